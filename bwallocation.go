@@ -0,0 +1,192 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+// SubscriberDemand is what an AllocationStrategy knows about a subscriber. Max 0 means unbounded.
+type SubscriberDemand struct {
+	ID       string
+	Priority mediasource.Priority
+	Min      int64
+	Max      int64
+}
+
+// AllocationStrategy splits the available bitrate between the subscribers of a BWEController. Bitrate
+// a subscriber cannot use because of its maximum goes to the others; subscribers of priority Level0 get
+// nothing.
+type AllocationStrategy = func(available int64, subscribers []SubscriberDemand) map[string]int64
+
+type AllocationStrategyName string
+
+const (
+	AllocationProportional   AllocationStrategyName = "proportional"
+	AllocationStrictPriority AllocationStrategyName = "strict_priority"
+	AllocationMaxMinFair     AllocationStrategyName = "max_min_fair"
+)
+
+// AllocationStrategyByName returns the built-in strategy with the given name.
+func AllocationStrategyByName(name AllocationStrategyName) (AllocationStrategy, error) {
+	switch name {
+	case AllocationProportional, "":
+		return ProportionalAllocation, nil
+	case AllocationStrictPriority:
+		return StrictPriorityAllocation, nil
+	case AllocationMaxMinFair:
+		return MaxMinFairAllocation, nil
+	default:
+		return nil, fmt.Errorf("unknown bandwidth allocation strategy '%s'", name)
+	}
+}
+
+type SubscriberOption = func(*subscriber) error
+
+// WithSubscriberMinBitrate guarantees the subscriber bps before anything is split, as far as the
+// available bitrate allows; minimums are served in the order of priority.
+func WithSubscriberMinBitrate(bps int64) SubscriberOption {
+	return func(sub *subscriber) error {
+		if bps < 0 {
+			return fmt.Errorf("invalid minimum bitrate %d", bps)
+		}
+		sub.min = bps
+		return nil
+	}
+}
+
+// WithSubscriberMaxBitrate caps the bitrate of the subscriber, e.g. at the maximum bitrate of its
+// encoder; what it cannot use goes to the other subscribers.
+func WithSubscriberMaxBitrate(bps int64) SubscriberOption {
+	return func(sub *subscriber) error {
+		if bps < 0 {
+			return fmt.Errorf("invalid maximum bitrate %d", bps)
+		}
+		sub.max = bps
+		return nil
+	}
+}
+
+// ProportionalAllocation splits the bitrate in proportion to the priorities of the subscribers.
+func ProportionalAllocation(available int64, subscribers []SubscriberDemand) map[string]int64 {
+	allocation, remaining := allocateMinimums(available, subscribers)
+	waterFill(remaining, active(subscribers), allocation, func(d SubscriberDemand) float64 {
+		return float64(d.Priority)
+	})
+
+	return allocation
+}
+
+// StrictPriorityAllocation serves the subscribers of the highest priority up to their maximum before
+// the next priority gets anything; subscribers of the same priority share equally.
+func StrictPriorityAllocation(available int64, subscribers []SubscriberDemand) map[string]int64 {
+	allocation, remaining := allocateMinimums(available, subscribers)
+
+	levels := make(map[mediasource.Priority][]SubscriberDemand)
+	for _, demand := range active(subscribers) {
+		levels[demand.Priority] = append(levels[demand.Priority], demand)
+	}
+
+	priorities := make([]mediasource.Priority, 0, len(levels))
+	for priority := range levels {
+		priorities = append(priorities, priority)
+	}
+	sort.Slice(priorities, func(i, j int) bool { return priorities[i] > priorities[j] })
+
+	for _, priority := range priorities {
+		remaining = waterFill(remaining, levels[priority], allocation, equalWeight)
+	}
+
+	return allocation
+}
+
+// MaxMinFairAllocation shares the bitrate equally, regardless of priority, so that no subscriber can
+// get more without taking from one that has less.
+func MaxMinFairAllocation(available int64, subscribers []SubscriberDemand) map[string]int64 {
+	allocation, remaining := allocateMinimums(available, subscribers)
+	waterFill(remaining, active(subscribers), allocation, equalWeight)
+
+	return allocation
+}
+
+func equalWeight(SubscriberDemand) float64 {
+	return 1
+}
+
+func active(subscribers []SubscriberDemand) []SubscriberDemand {
+	demands := make([]SubscriberDemand, 0, len(subscribers))
+	for _, demand := range subscribers {
+		if demand.Priority != mediasource.Level0 {
+			demands = append(demands, demand)
+		}
+	}
+
+	return demands
+}
+
+// allocateMinimums gives every active subscriber its minimum, highest priority first, and returns what
+// is left.
+func allocateMinimums(available int64, subscribers []SubscriberDemand) (map[string]int64, int64) {
+	allocation := make(map[string]int64, len(subscribers))
+
+	demands := active(subscribers)
+	sort.SliceStable(demands, func(i, j int) bool { return demands[i].Priority > demands[j].Priority })
+
+	for _, demand := range demands {
+		bps := min(demand.Min, available)
+		if demand.Max > 0 {
+			bps = min(bps, demand.Max)
+		}
+		allocation[demand.ID] = bps
+		available -= bps
+	}
+
+	return allocation, available
+}
+
+// waterFill splits available by weight between the demands below their maximum; whatever a demand
+// cannot take is split again between the rest. It returns what nobody could take.
+func waterFill(available int64, demands []SubscriberDemand, allocation map[string]int64, weight func(SubscriberDemand) float64) int64 {
+	open := make([]SubscriberDemand, 0, len(demands))
+	for _, demand := range demands {
+		if weight(demand) > 0 && (demand.Max == 0 || allocation[demand.ID] < demand.Max) {
+			open = append(open, demand)
+		}
+	}
+
+	for available > 0 && len(open) > 0 {
+		var total float64
+		for _, demand := range open {
+			total += weight(demand)
+		}
+
+		var (
+			given int64
+			next  = open[:0:0]
+		)
+		for _, demand := range open {
+			share := int64(math.Floor(float64(available) * weight(demand) / total))
+			if demand.Max > 0 {
+				share = min(share, demand.Max-allocation[demand.ID])
+			}
+
+			allocation[demand.ID] += share
+			given += share
+
+			if demand.Max == 0 || allocation[demand.ID] < demand.Max {
+				next = append(next, demand)
+			}
+		}
+
+		available -= given
+		if len(next) == len(open) {
+			// nobody hit its maximum; what is left is rounding
+			break
+		}
+		open = next
+	}
+
+	return available
+}
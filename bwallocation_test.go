@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"testing"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+func TestAllocationStrategies(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    AllocationStrategy
+		available   int64
+		subscribers []SubscriberDemand
+		expected    map[string]int64
+	}{
+		{
+			name:      "proportional to priority",
+			strategy:  ProportionalAllocation,
+			available: 400_000,
+			subscribers: []SubscriberDemand{
+				{ID: "video", Priority: mediasource.Level3},
+				{ID: "audio", Priority: mediasource.Level1},
+			},
+			expected: map[string]int64{"video": 300_000, "audio": 100_000},
+		},
+		{
+			name:      "proportional redistributes above the maximum",
+			strategy:  ProportionalAllocation,
+			available: 1_000_000,
+			subscribers: []SubscriberDemand{
+				{ID: "video", Priority: mediasource.Level1, Max: 200_000},
+				{ID: "screen", Priority: mediasource.Level1},
+			},
+			expected: map[string]int64{"video": 200_000, "screen": 800_000},
+		},
+		{
+			name:      "minimums first",
+			strategy:  ProportionalAllocation,
+			available: 100_000,
+			subscribers: []SubscriberDemand{
+				{ID: "video", Priority: mediasource.Level5},
+				{ID: "telemetry", Priority: mediasource.Level1, Min: 32_000},
+			},
+			expected: map[string]int64{"video": 56_666, "telemetry": 43_333},
+		},
+		{
+			name:      "minimums by priority when short",
+			strategy:  ProportionalAllocation,
+			available: 50_000,
+			subscribers: []SubscriberDemand{
+				{ID: "low", Priority: mediasource.Level1, Min: 40_000},
+				{ID: "high", Priority: mediasource.Level2, Min: 40_000},
+			},
+			expected: map[string]int64{"high": 40_000, "low": 10_000},
+		},
+		{
+			name:      "level 0 gets nothing",
+			strategy:  MaxMinFairAllocation,
+			available: 100_000,
+			subscribers: []SubscriberDemand{
+				{ID: "paused", Priority: mediasource.Level0, Min: 50_000},
+				{ID: "video", Priority: mediasource.Level1},
+			},
+			expected: map[string]int64{"video": 100_000},
+		},
+		{
+			name:      "strict priority serves the highest first",
+			strategy:  StrictPriorityAllocation,
+			available: 500_000,
+			subscribers: []SubscriberDemand{
+				{ID: "main", Priority: mediasource.Level3, Max: 300_000},
+				{ID: "backup", Priority: mediasource.Level1},
+				{ID: "other", Priority: mediasource.Level3, Max: 100_000},
+			},
+			expected: map[string]int64{"main": 300_000, "other": 100_000, "backup": 100_000},
+		},
+		{
+			name:      "max-min fair ignores priority",
+			strategy:  MaxMinFairAllocation,
+			available: 300_000,
+			subscribers: []SubscriberDemand{
+				{ID: "a", Priority: mediasource.Level5},
+				{ID: "b", Priority: mediasource.Level1, Max: 50_000},
+				{ID: "c", Priority: mediasource.Level1},
+			},
+			expected: map[string]int64{"a": 125_000, "b": 50_000, "c": 125_000},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			allocation := test.strategy(test.available, test.subscribers)
+			if !maps.Equal(allocation, test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, allocation)
+			}
+
+			var total int64
+			for _, bps := range allocation {
+				total += bps
+			}
+			if total > test.available {
+				t.Fatalf("allocated %d of %d", total, test.available)
+			}
+		})
+	}
+}
+
+func TestAllocationStrategyByName(t *testing.T) {
+	for _, name := range []AllocationStrategyName{"", AllocationProportional, AllocationStrictPriority, AllocationMaxMinFair} {
+		if _, err := AllocationStrategyByName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+
+	if _, err := AllocationStrategyByName("round_robin"); err == nil {
+		t.Error("expected an unknown strategy to be rejected")
+	}
+}
+
+// newAllocationTestController subscribes a telemetry stream fixed at 32 kbps and a video stream that
+// can take up to 2 Mbps, reserving reserved bps for media outside the controller.
+func newAllocationTestController(t *testing.T, reserved int64) *BWEController {
+	t.Helper()
+
+	bwc := createBWController(context.Background(), slog.Default())
+	t.Cleanup(bwc.Close)
+
+	if err := bwc.SetReservedBitrate(reserved); err != nil {
+		t.Fatal(err)
+	}
+	bwc.SetAllocationStrategy(MaxMinFairAllocation)
+
+	noop := func(int64) error { return nil }
+	if err := bwc.Subscribe("telemetry", mediasource.Level1, noop, WithSubscriberMinBitrate(32_000), WithSubscriberMaxBitrate(32_000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := bwc.Subscribe("video", mediasource.Level3, noop, WithSubscriberMaxBitrate(2_000_000)); err != nil {
+		t.Fatal(err)
+	}
+
+	return bwc
+}
+
+func TestBWEControllerAllocatesWithoutReservedBitrate(t *testing.T) {
+	bwc := newAllocationTestController(t, 0)
+
+	// what telemetry cannot take above its maximum goes to the video
+	_, allocation := bwc.allocate(1_000_000)
+	if expected := map[string]int64{"telemetry": 32_000, "video": 968_000}; !maps.Equal(allocation, expected) {
+		t.Fatalf("expected %v, got %v", expected, allocation)
+	}
+}
+
+func TestBWEControllerAllocatesWithReservedBitrate(t *testing.T) {
+	bwc := newAllocationTestController(t, 100_000)
+
+	_, allocation := bwc.allocate(1_000_000)
+	if expected := map[string]int64{"telemetry": 32_000, "video": 868_000}; !maps.Equal(allocation, expected) {
+		t.Fatalf("expected %v, got %v", expected, allocation)
+	}
+
+	noop := func(int64) error { return nil }
+	if err := bwc.Subscribe("invalid", mediasource.Level1, noop, WithSubscriberMinBitrate(2), WithSubscriberMaxBitrate(1)); err == nil {
+		t.Fatal("expected a minimum above the maximum to be rejected")
+	}
+}
@@ -3,8 +3,9 @@ package client
 import (
 	"context"
 	"errors"
-	"fmt"
 	"iter"
+	"log/slog"
+	"sort"
 	"sync"
 	"time"
 
@@ -18,26 +19,49 @@ type UpdateBitrateCallBack = func(bps int64) error
 type subscriber struct {
 	id       string // unique identifier
 	priority mediasource.Priority
+	min, max int64 // bounds of the allocated bitrate; max 0 is unbounded
 	callback UpdateBitrateCallBack
+
+	// current and lastUpdate are the last bitrate handed to the worker and when; only the loop of the
+	// controller touches them
+	current    int64
+	lastUpdate time.Time
+	delivered  bool
+	pending    chan int64
+	done       chan struct{}
 }
 
 type BWEController struct {
 	estimator cc.BandwidthEstimator
 	interval  time.Duration
 	subs      map[string]*subscriber
-	once      sync.Once
-	mux       sync.RWMutex
-	wg        sync.WaitGroup
-	ctx       context.Context
-	cancel    context.CancelFunc
+	strategy  AllocationStrategy
+	// reserved is kept out of the allocation, e.g. for data channels
+	reserved int64
+	update   BitrateUpdateConfig
+	// average is the smoothed estimate, see BitrateUpdateConfig.Smoothing
+	average  float64
+	smoothed bool
+	// history has its own lock, so that reading it does not hold up the loop
+	history    *bweHistory
+	historyMux sync.Mutex
+	logger     *slog.Logger
+	once       sync.Once
+	mux        sync.RWMutex
+	wg         sync.WaitGroup
+	ctx        context.Context
+	cancel     context.CancelFunc
 }
 
-func createBWController(ctx context.Context) *BWEController {
+func createBWController(ctx context.Context, logger *slog.Logger) *BWEController {
 	ctx2, cancel2 := context.WithCancel(ctx)
 
 	return &BWEController{
 		subs:      make(map[string]*subscriber),
+		strategy:  ProportionalAllocation,
+		history:   newBWEHistory(DefaultBWEHistorySize),
 		estimator: nil,
+		logger:    logger,
 		ctx:       ctx2,
 		cancel:    cancel2,
 	}
@@ -62,23 +86,97 @@ func (bwc *BWEController) Start() {
 	go bwc.loop()
 }
 
-func (bwc *BWEController) Subscribe(id string, priority mediasource.Priority, callback UpdateBitrateCallBack) error {
+func (bwc *BWEController) Subscribe(id string, priority mediasource.Priority, callback UpdateBitrateCallBack, options ...SubscriberOption) error {
+	sub := &subscriber{
+		id:       id,
+		priority: priority,
+		callback: callback,
+		pending:  make(chan int64, 1),
+		done:     make(chan struct{}),
+	}
+
+	for _, option := range options {
+		if err := option(sub); err != nil {
+			return err
+		}
+	}
+
+	if sub.max > 0 && sub.min > sub.max {
+		return errors.New("minimum bitrate of subscriber is higher than its maximum")
+	}
+
 	bwc.mux.Lock()
 	defer bwc.mux.Unlock()
 
+	if bwc.ctx.Err() != nil {
+		return errors.New("bandwidth controller is closed")
+	}
+
 	if _, exists := bwc.subs[id]; exists {
 		return errors.New("subscriber already exists")
 	}
 
-	bwc.subs[id] = &subscriber{
-		id:       id,
-		priority: priority,
-		callback: callback,
+	bwc.subs[id] = sub
+
+	bwc.wg.Add(1)
+	go bwc.worker(sub)
+
+	return nil
+}
+
+// SetSubscriberLimits changes the minimum and maximum bitrate of a subscriber; max 0 is unbounded.
+func (bwc *BWEController) SetSubscriberLimits(id string, min, max int64) error {
+	if min < 0 || max < 0 || (max > 0 && min > max) {
+		return errors.New("invalid subscriber bitrate limits")
+	}
+
+	bwc.mux.Lock()
+	defer bwc.mux.Unlock()
+
+	sub, exists := bwc.subs[id]
+	if !exists {
+		return errors.New("subscriber does not exist")
 	}
 
+	sub.min, sub.max = min, max
+
 	return nil
 }
 
+// SetAllocationStrategy changes how the estimated bitrate is split between the subscribers, from the
+// next tick on; nil restores ProportionalAllocation.
+func (bwc *BWEController) SetAllocationStrategy(strategy AllocationStrategy) {
+	bwc.mux.Lock()
+	defer bwc.mux.Unlock()
+
+	if strategy == nil {
+		strategy = ProportionalAllocation
+	}
+	bwc.strategy = strategy
+}
+
+// SetReservedBitrate keeps bps of the estimated bitrate out of the allocation, for traffic that does not
+// subscribe, like data channels.
+func (bwc *BWEController) SetReservedBitrate(bps int64) error {
+	if bps < 0 {
+		return errors.New("reserved bitrate cannot be negative")
+	}
+
+	bwc.mux.Lock()
+	defer bwc.mux.Unlock()
+
+	bwc.reserved = bps
+
+	return nil
+}
+
+func (bwc *BWEController) reservedBitrate() int64 {
+	bwc.mux.RLock()
+	defer bwc.mux.RUnlock()
+
+	return bwc.reserved
+}
+
 func (bwc *BWEController) subscribers() iter.Seq2[string, *subscriber] {
 	return func(yield func(string, *subscriber) bool) {
 		bwc.mux.RLock()
@@ -92,21 +190,33 @@ func (bwc *BWEController) subscribers() iter.Seq2[string, *subscriber] {
 	}
 }
 
-func (bwc *BWEController) calculateTotalPriority() mediasource.Priority {
-	var totalPriority = mediasource.Level0
+// allocate splits the bitrate with the current strategy and returns the subscribers with their share.
+func (bwc *BWEController) allocate(total int64) ([]*subscriber, map[string]int64) {
+	bwc.mux.RLock()
+	strategy, reserved := bwc.strategy, bwc.reserved
+	bwc.mux.RUnlock()
 
+	var (
+		subs    []*subscriber
+		demands []SubscriberDemand
+	)
 	for _, sub := range bwc.subscribers() {
-		totalPriority += sub.priority
+		subs = append(subs, sub)
+		demands = append(demands, SubscriberDemand{ID: sub.id, Priority: sub.priority, Min: sub.min, Max: sub.max})
 	}
 
-	return totalPriority
+	return subs, strategy(max(total-reserved, 0), demands)
 }
 
 func (bwc *BWEController) loop() {
 	bwc.wg.Add(1)
 	defer bwc.wg.Done()
 
-	ticker := time.NewTicker(bwc.interval)
+	bwc.mux.RLock()
+	interval := bwc.interval
+	bwc.mux.RUnlock()
+
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -118,68 +228,69 @@ func (bwc *BWEController) loop() {
 				continue
 			}
 
-			totalPriority := bwc.calculateTotalPriority()
-			if totalPriority == mediasource.Level0 {
-				continue // No active priorities
-			}
-
 			totalBitrate, err := bwc.getBitrate()
 			if err != nil {
 				continue
 			}
 
-			for _, sub := range bwc.subscribers() {
-				if sub.priority == mediasource.Level0 {
+			config := bwc.updateConfig()
+			now := time.Now()
+
+			sample := BWESample{Time: now, TargetBitrate: int64(totalBitrate), ReservedBitrate: bwc.reservedBitrate()}
+			sample.setEstimatorStats(bwc.get().GetStats())
+			sample.SmoothedBitrate = bwc.smooth(config, sample.TargetBitrate)
+
+			subs, allocation := bwc.allocate(sample.SmoothedBitrate)
+			for _, sub := range subs {
+				bitrate, ok := allocation[sub.id]
+				if !ok || sub.priority == mediasource.Level0 {
 					continue
 				}
-				bitrate := int64(float64(totalBitrate) * float64(sub.priority) / float64(totalPriority))
-				go bwc.sendBitrateUpdate(sub.id, sub.callback, bitrate)
+				entry := BWEAllocation{Subscriber: sub.id, Allocated: bitrate}
+				if bitrate, ok = sub.shape(config, bitrate, now); ok {
+					sub.deliver(bitrate, now)
+				}
+				entry.Delivered, entry.Updated = sub.current, ok
+				sample.Allocations = append(sample.Allocations, entry)
 			}
-		}
-	}
-}
-
-func (bwc *BWEController) sendBitrateUpdate(id string, callback UpdateBitrateCallBack, bitrate int64) {
-	done := make(chan error, 1)
 
-	go func() {
-		done <- callback(bitrate)
-	}()
-
-	select {
-	case err := <-done:
-		if err != nil {
-			fmt.Printf("bitrate update callback (id=%s) failed: %v. Unsubscribing...\n", id, err)
-			bwc.Unsubscribe(id)
+			sort.Slice(sample.Allocations, func(i, j int) bool {
+				return sample.Allocations[i].Subscriber < sample.Allocations[j].Subscriber
+			})
+			bwc.record(sample)
 		}
-	case <-bwc.ctx.Done():
-		return
 	}
 }
 
 func (bwc *BWEController) getBitrate() (int, error) {
-	if bwc.get() == nil {
+	estimator := bwc.get()
+	if estimator == nil {
 		return 0, errors.New("estimator is nil")
 	}
-	return bwc.estimator.GetTargetBitrate(), nil
+	return estimator.GetTargetBitrate(), nil
 }
 
 func (bwc *BWEController) Unsubscribe(id string) {
 	bwc.mux.Lock()
 	defer bwc.mux.Unlock()
 
-	if _, exists := bwc.subs[id]; !exists {
+	sub, exists := bwc.subs[id]
+	if !exists {
 		return
 	}
 
+	close(sub.done)
 	delete(bwc.subs, id)
 }
 
 func (bwc *BWEController) Close() {
 	bwc.once.Do(func() {
+		// NOTE: CANCELLED UNDER THE LOCK SO THAT SUBSCRIBE DOES NOT START A WORKER WHILE WAITING BELOW
+		bwc.mux.Lock()
 		if bwc.cancel != nil {
 			bwc.cancel()
 		}
+		bwc.mux.Unlock()
 
 		bwc.wg.Wait()
 
@@ -0,0 +1,170 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/interceptor/pkg/gcc"
+	"github.com/pion/rtcp"
+)
+
+type BandwidthEstimatorName string
+
+const (
+	BandwidthEstimatorGCC    BandwidthEstimatorName = "gcc"
+	BandwidthEstimatorStatic BandwidthEstimatorName = "static"
+	BandwidthEstimatorREMB   BandwidthEstimatorName = "remb"
+)
+
+// BandwidthEstimatorFactoryByName returns a factory of the built-in estimator with the given name.
+// The static estimator holds initialBitrate.
+func BandwidthEstimatorFactoryByName(name BandwidthEstimatorName, initialBitrate, minimumBitrate, maximumBitrate int64) (cc.BandwidthEstimatorFactory, error) {
+	switch name {
+	case BandwidthEstimatorGCC, "":
+		return func() (cc.BandwidthEstimator, error) {
+			return gcc.NewSendSideBWE(gcc.SendSideBWEInitialBitrate(int(initialBitrate)), gcc.SendSideBWEMinBitrate(int(minimumBitrate)), gcc.SendSideBWEMaxBitrate(int(maximumBitrate)))
+		}, nil
+	case BandwidthEstimatorStatic:
+		return func() (cc.BandwidthEstimator, error) {
+			return NewStaticBandwidthEstimator(initialBitrate), nil
+		}, nil
+	case BandwidthEstimatorREMB:
+		return func() (cc.BandwidthEstimator, error) {
+			return NewREMBBandwidthEstimator(initialBitrate, minimumBitrate, maximumBitrate), nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown bandwidth estimator '%s'", name)
+	}
+}
+
+// StaticBandwidthEstimator estimates a fixed bitrate, for links whose capacity is known; it ignores all
+// feedback.
+type StaticBandwidthEstimator struct {
+	bitrate int
+}
+
+func NewStaticBandwidthEstimator(bps int64) *StaticBandwidthEstimator {
+	return &StaticBandwidthEstimator{bitrate: int(bps)}
+}
+
+func (e *StaticBandwidthEstimator) AddStream(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	return writer
+}
+
+func (e *StaticBandwidthEstimator) WriteRTCP([]rtcp.Packet, interceptor.Attributes) error {
+	return nil
+}
+
+func (e *StaticBandwidthEstimator) GetTargetBitrate() int {
+	return e.bitrate
+}
+
+// OnTargetBitrateChange is a no-op; the bitrate never changes.
+func (e *StaticBandwidthEstimator) OnTargetBitrateChange(func(int)) {}
+
+func (e *StaticBandwidthEstimator) GetStats() map[string]interface{} {
+	return map[string]interface{}{
+		"state": "static",
+	}
+}
+
+func (e *StaticBandwidthEstimator) Close() error {
+	return nil
+}
+
+// REMBBandwidthEstimator follows the REMB (receiver estimated maximum bitrate) feedback of the remote,
+// clamped to [min, max]. It holds the initial bitrate until the first REMB arrives, so the remote needs
+// to send REMB; TypeRTCPFBGoogREMB is advertised for all codecs registered by this package.
+type REMBBandwidthEstimator struct {
+	bitrate  int
+	min, max int
+	received uint64
+	last     time.Time
+	onChange func(int)
+	mux      sync.Mutex
+}
+
+func NewREMBBandwidthEstimator(initialBitrate, minimumBitrate, maximumBitrate int64) *REMBBandwidthEstimator {
+	return &REMBBandwidthEstimator{
+		bitrate: int(initialBitrate),
+		min:     int(minimumBitrate),
+		max:     int(maximumBitrate),
+	}
+}
+
+func (e *REMBBandwidthEstimator) AddStream(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	return writer
+}
+
+// WriteRTCP takes the feedback of the remote. Errors would break the RTCP reader of the peer connection,
+// so there are none.
+func (e *REMBBandwidthEstimator) WriteRTCP(packets []rtcp.Packet, _ interceptor.Attributes) error {
+	for _, packet := range packets {
+		remb, ok := packet.(*rtcp.ReceiverEstimatedMaximumBitrate)
+		if !ok {
+			continue
+		}
+
+		e.update(int(remb.Bitrate))
+	}
+
+	return nil
+}
+
+func (e *REMBBandwidthEstimator) update(bitrate int) {
+	if e.min > 0 {
+		bitrate = max(bitrate, e.min)
+	}
+	if e.max > 0 {
+		bitrate = min(bitrate, e.max)
+	}
+
+	e.mux.Lock()
+	changed := bitrate != e.bitrate
+	e.bitrate, e.last = bitrate, time.Now()
+	e.received++
+	onChange := e.onChange
+	e.mux.Unlock()
+
+	if changed && onChange != nil {
+		onChange(bitrate)
+	}
+}
+
+func (e *REMBBandwidthEstimator) GetTargetBitrate() int {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	return e.bitrate
+}
+
+func (e *REMBBandwidthEstimator) OnTargetBitrateChange(f func(int)) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	e.onChange = f
+}
+
+func (e *REMBBandwidthEstimator) GetStats() map[string]interface{} {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+
+	stats := map[string]interface{}{
+		"rembReceived": e.received,
+		"state":        "remb",
+	}
+	if e.received > 0 {
+		stats["rembAge"] = float64(time.Since(e.last).Microseconds()) / 1000.0
+	} else {
+		stats["state"] = "initial"
+	}
+
+	return stats
+}
+
+func (e *REMBBandwidthEstimator) Close() error {
+	return nil
+}
@@ -0,0 +1,320 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+func TestBandwidthEstimatorFactoryByName(t *testing.T) {
+	tests := []struct {
+		test     string
+		name     BandwidthEstimatorName
+		expected string
+	}{
+		{"default", "", "*gcc.SendSideBWE"},
+		{"gcc", BandwidthEstimatorGCC, "*gcc.SendSideBWE"},
+		{"static", BandwidthEstimatorStatic, "*client.StaticBandwidthEstimator"},
+		{"remb", BandwidthEstimatorREMB, "*client.REMBBandwidthEstimator"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.test, func(t *testing.T) {
+			factory, err := BandwidthEstimatorFactoryByName(test.name, 1_000_000, 100_000, 5_000_000)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e, err := factory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer e.Close()
+
+			if kind := fmt.Sprintf("%T", e); kind != test.expected {
+				t.Fatalf("expected a %s, got a %s", test.expected, kind)
+			}
+			if bitrate := e.GetTargetBitrate(); bitrate != 1_000_000 {
+				t.Fatalf("expected to start at the initial bitrate, got %d", bitrate)
+			}
+		})
+	}
+
+	if _, err := BandwidthEstimatorFactoryByName("bbr", 1, 1, 1); err == nil {
+		t.Fatal("expected an unknown estimator to fail")
+	}
+}
+
+func TestStaticBandwidthEstimatorIgnoresFeedback(t *testing.T) {
+	e := NewStaticBandwidthEstimator(2_000_000)
+
+	if err := e.WriteRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 100_000}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if bitrate := e.GetTargetBitrate(); bitrate != 2_000_000 {
+		t.Fatalf("expected the bitrate to stay at 2000000, got %d", bitrate)
+	}
+}
+
+func TestREMBBandwidthEstimator(t *testing.T) {
+	e := NewREMBBandwidthEstimator(1_000_000, 200_000, 3_000_000)
+
+	var changes []int
+	e.OnTargetBitrateChange(func(bitrate int) {
+		changes = append(changes, bitrate)
+	})
+
+	if state := e.GetStats()["state"]; state != "initial" {
+		t.Fatalf("expected the initial state before any remb, got %v", state)
+	}
+
+	steps := []struct {
+		name     string
+		packet   rtcp.Packet
+		expected int
+	}{
+		{"other feedback", &rtcp.PictureLossIndication{}, 1_000_000},
+		{"remb", &rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 1_500_000}, 1_500_000},
+		{"same remb", &rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 1_500_000}, 1_500_000},
+		{"remb above maximum", &rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 10_000_000}, 3_000_000},
+		{"remb below minimum", &rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 50_000}, 200_000},
+	}
+
+	for _, step := range steps {
+		if err := e.WriteRTCP([]rtcp.Packet{step.packet}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if bitrate := e.GetTargetBitrate(); bitrate != step.expected {
+			t.Fatalf("%s: expected a bitrate of %d, got %d", step.name, step.expected, bitrate)
+		}
+	}
+
+	// NOTE: ONLY CHANGES ARE REPORTED
+	if len(changes) != 3 || changes[0] != 1_500_000 || changes[1] != 3_000_000 || changes[2] != 200_000 {
+		t.Fatalf("expected three changes, got %v", changes)
+	}
+
+	stats := e.GetStats()
+	if stats["state"] != "remb" || stats["rembReceived"] != uint64(4) {
+		t.Fatalf("expected four rembs to be received, got %v", stats)
+	}
+	if _, ok := stats["rembAge"]; !ok {
+		t.Fatal("expected the age of the last remb")
+	}
+}
+
+// recordingBandwidthEstimator is an estimator of the application's own, which estimates a fixed bitrate
+// and records the streams and feedback it sees.
+type recordingBandwidthEstimator struct {
+	StaticBandwidthEstimator
+	streams chan uint32
+	rembs   chan uint64
+}
+
+func newRecordingBandwidthEstimator(bps int64) *recordingBandwidthEstimator {
+	return &recordingBandwidthEstimator{
+		StaticBandwidthEstimator: StaticBandwidthEstimator{bitrate: int(bps)},
+		streams:                  make(chan uint32, 16),
+		rembs:                    make(chan uint64, 64),
+	}
+}
+
+func (e *recordingBandwidthEstimator) AddStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	select {
+	case e.streams <- info.SSRC:
+	default:
+	}
+	return writer
+}
+
+func (e *recordingBandwidthEstimator) WriteRTCP(packets []rtcp.Packet, _ interceptor.Attributes) error {
+	for _, packet := range packets {
+		if remb, ok := packet.(*rtcp.ReceiverEstimatedMaximumBitrate); ok {
+			select {
+			case e.rembs <- uint64(remb.Bitrate):
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// estimatedPeerConnection connects a peer connection, whose bandwidth is estimated as option has it,
+// sending video to a sink of a plain peer connection. It returns both with the SSRC of the video.
+func estimatedPeerConnection(ctx context.Context, t *testing.T, option ClientOption) (*PeerConnection, *PeerConnection, uint32) {
+	t.Helper()
+
+	offer, err := NewClient(ctx, nil, nil, loopbackSettings(), WithDefaultMediaEngine(), option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(offer.Close)
+
+	answer, err := NewClient(ctx, nil, nil, loopbackSettings(), WithDefaultMediaEngine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(answer.Close)
+
+	offerPC, err := offer.CreatePeerConnectionWithBWEstimator("media", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	answerPC, err := answer.CreatePeerConnection("media", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := offerPC.CreateMediaSource("video", mediasource.WithVP8Track(90000)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := answerPC.CreateMediaSink("video", mediasink.WithVP8Track(90000)); err != nil {
+		t.Fatal(err)
+	}
+
+	offerSignal, answerSignal := NewLoopbackSignals(ctx)
+	t.Cleanup(func() {
+		_ = offerSignal.Close()
+		_ = answerSignal.Close()
+	})
+
+	connectSignals(t, offer, answer, offerSignal, answerSignal)
+
+	for _, pc := range []*PeerConnection{offerPC, answerPC} {
+		if err := pc.WaitTill(ctx, func(state webrtc.PeerConnectionState, _ webrtc.ICEConnectionState) bool {
+			return state != webrtc.PeerConnectionStateConnected
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return offerPC, answerPC, uint32(offerPC.GetPeerConnection().GetSenders()[0].GetParameters().Encodings[0].SSRC)
+}
+
+// awaitTargetBitrate waits till the controller of pc ticks with the expected target bitrate, sending
+// the remote's feedback, if any, before every look.
+func awaitTargetBitrate(ctx context.Context, t *testing.T, pc *PeerConnection, expected int64, feedback func()) {
+	t.Helper()
+
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if feedback != nil {
+			feedback()
+		}
+
+		if sample, ok := pc.bwc.Latest(); ok && sample.TargetBitrate == expected {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			sample, _ := pc.bwc.Latest()
+			t.Fatalf("expected a target bitrate of %d, got %d", expected, sample.TargetBitrate)
+		case <-ticker.C:
+		}
+	}
+}
+
+func TestStaticBandwidthEstimatorDrivesController(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pc, _, _ := estimatedPeerConnection(ctx, t, WithStaticBandwidthEstimator(1_200_000, 20*time.Millisecond))
+
+	if _, ok := pc.bwc.get().(*StaticBandwidthEstimator); !ok {
+		t.Fatalf("expected a static estimator, got %T", pc.bwc.get())
+	}
+	awaitTargetBitrate(ctx, t, pc, 1_200_000, nil)
+}
+
+func TestREMBBandwidthEstimatorFollowsRemote(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pc, remote, ssrc := estimatedPeerConnection(ctx, t, WithREMBBandwidthEstimator(1_000_000, 200_000, 3_000_000, 20*time.Millisecond))
+
+	awaitTargetBitrate(ctx, t, pc, 1_000_000, nil)
+
+	remb := func(bitrate float32) func() {
+		return func() {
+			_ = remote.GetPeerConnection().WriteRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: bitrate, SSRCs: []uint32{ssrc}}})
+		}
+	}
+
+	awaitTargetBitrate(ctx, t, pc, 1_800_000, remb(1_800_000))
+	// NOTE: THE REMOTE CANNOT PUSH THE BITRATE PAST THE MAXIMUM
+	awaitTargetBitrate(ctx, t, pc, 3_000_000, remb(20_000_000))
+
+	if sample, _ := pc.bwc.Latest(); sample.State != "remb" {
+		t.Fatalf("expected the sample to carry the state of the estimator, got %q", sample.State)
+	}
+}
+
+func TestExternalBandwidthEstimator(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var (
+		created []*recordingBandwidthEstimator
+		mux     sync.Mutex
+	)
+	factory := func() (cc.BandwidthEstimator, error) {
+		mux.Lock()
+		defer mux.Unlock()
+
+		e := newRecordingBandwidthEstimator(700_000)
+		created = append(created, e)
+		return e, nil
+	}
+
+	pc, remote, ssrc := estimatedPeerConnection(ctx, t, WithBandwidthEstimatorInterceptor(factory, 20*time.Millisecond))
+
+	mux.Lock()
+	if len(created) != 1 || pc.bwc.get() != created[0] {
+		mux.Unlock()
+		t.Fatalf("expected the peer connection to get the one estimator of the factory, got %d", len(created))
+	}
+	e := created[0]
+	mux.Unlock()
+
+	awaitTargetBitrate(ctx, t, pc, 700_000, nil)
+
+	select {
+	case stream := <-e.streams:
+		if stream != ssrc {
+			t.Fatalf("expected the estimator to see the video stream %d, got %d", ssrc, stream)
+		}
+	case <-ctx.Done():
+		t.Fatal("expected the estimator to see the video stream")
+	}
+
+	// NOTE: THE ESTIMATOR SEES THE RTCP OF THE REMOTE
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		_ = remote.GetPeerConnection().WriteRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 900_000, SSRCs: []uint32{ssrc}}})
+
+		select {
+		case bitrate := <-e.rembs:
+			if bitrate != 900_000 {
+				t.Fatalf("expected the remb of the remote, got %d", bitrate)
+			}
+			return
+		case <-ticker.C:
+		case <-ctx.Done():
+			t.Fatal("expected the estimator to see the feedback of the remote")
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// DefaultBWEHistorySize is the number of ticks a BWEController remembers by default.
+const DefaultBWEHistorySize = 600
+
+// BWESample is what a BWEController saw and did in one tick. The loss and delay based components are
+// filled in from the estimator's GetStats, as gcc.SendSideBWE reports them; other estimators leave
+// them zero. Delays are in milliseconds.
+type BWESample struct {
+	Time            time.Time `json:"time"`
+	TargetBitrate   int64     `json:"target_bitrate"`
+	SmoothedBitrate int64     `json:"smoothed_bitrate"`
+	ReservedBitrate int64     `json:"reserved_bitrate"`
+
+	LossTargetBitrate  int64   `json:"loss_target_bitrate"`
+	AverageLoss        float64 `json:"average_loss"`
+	DelayTargetBitrate int64   `json:"delay_target_bitrate"`
+	DelayMeasurement   float64 `json:"delay_measurement"`
+	DelayEstimate      float64 `json:"delay_estimate"`
+	DelayThreshold     float64 `json:"delay_threshold"`
+	Usage              string  `json:"usage,omitempty"`
+	State              string  `json:"state,omitempty"`
+
+	Allocations []BWEAllocation `json:"allocations"`
+}
+
+// BWEAllocation is the share of one subscriber in a tick. Allocated is what the AllocationStrategy gave
+// it and Delivered what was handed to its callback after smoothing and rate limiting; Updated is false
+// when no update was delivered in the tick.
+type BWEAllocation struct {
+	Subscriber string `json:"subscriber"`
+	Allocated  int64  `json:"allocated"`
+	Delivered  int64  `json:"delivered"`
+	Updated    bool   `json:"updated"`
+}
+
+func (sample *BWESample) setEstimatorStats(stats map[string]interface{}) {
+	number := func(key string) float64 {
+		switch v := stats[key].(type) {
+		case int:
+			return float64(v)
+		case int64:
+			return float64(v)
+		case float64:
+			return v
+		default:
+			return 0
+		}
+	}
+	text := func(key string) string {
+		v, _ := stats[key].(string)
+		return v
+	}
+
+	sample.LossTargetBitrate = int64(number("lossTargetBitrate"))
+	sample.AverageLoss = number("averageLoss")
+	sample.DelayTargetBitrate = int64(number("delayTargetBitrate"))
+	sample.DelayMeasurement = number("delayMeasurement")
+	sample.DelayEstimate = number("delayEstimate")
+	sample.DelayThreshold = number("delayThreshold")
+	sample.Usage = text("usage")
+	sample.State = text("state")
+}
+
+// bweHistory is a ring buffer of samples.
+type bweHistory struct {
+	samples []BWESample
+	next    int
+	full    bool
+}
+
+func newBWEHistory(size int) *bweHistory {
+	return &bweHistory{samples: make([]BWESample, size)}
+}
+
+func (h *bweHistory) add(sample BWESample) {
+	if len(h.samples) == 0 {
+		return
+	}
+
+	h.samples[h.next] = sample
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// all returns the samples, oldest first.
+func (h *bweHistory) all() []BWESample {
+	if !h.full {
+		return append([]BWESample(nil), h.samples[:h.next]...)
+	}
+
+	return append(append([]BWESample(nil), h.samples[h.next:]...), h.samples[:h.next]...)
+}
+
+func (h *bweHistory) latest() (BWESample, bool) {
+	if !h.full && h.next == 0 {
+		return BWESample{}, false
+	}
+
+	return h.samples[(h.next-1+len(h.samples))%len(h.samples)], true
+}
+
+// SetHistorySize changes how many ticks the controller remembers; the history starts over. 0 disables
+// it.
+func (bwc *BWEController) SetHistorySize(size int) error {
+	if size < 0 {
+		return errors.New("history size cannot be negative")
+	}
+
+	bwc.historyMux.Lock()
+	defer bwc.historyMux.Unlock()
+
+	bwc.history = newBWEHistory(size)
+
+	return nil
+}
+
+func (bwc *BWEController) record(sample BWESample) {
+	bwc.historyMux.Lock()
+	defer bwc.historyMux.Unlock()
+
+	bwc.history.add(sample)
+}
+
+// History returns the remembered ticks, oldest first.
+func (bwc *BWEController) History() []BWESample {
+	bwc.historyMux.Lock()
+	defer bwc.historyMux.Unlock()
+
+	return bwc.history.all()
+}
+
+// Latest returns the last tick, and false if there was none yet.
+func (bwc *BWEController) Latest() (BWESample, bool) {
+	bwc.historyMux.Lock()
+	defer bwc.historyMux.Unlock()
+
+	return bwc.history.latest()
+}
+
+// WriteHistoryJSON writes the remembered ticks, oldest first, as a JSON array.
+func (bwc *BWEController) WriteHistoryJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(bwc.History())
+}
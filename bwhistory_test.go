@@ -0,0 +1,96 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestBWEHistoryWrapsAround(t *testing.T) {
+	history := newBWEHistory(3)
+
+	if _, ok := history.latest(); ok {
+		t.Fatal("expected no latest sample in an empty history")
+	}
+
+	for bitrate := int64(1); bitrate <= 5; bitrate++ {
+		history.add(BWESample{TargetBitrate: bitrate})
+	}
+
+	samples := history.all()
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(samples))
+	}
+	for i, expected := range []int64{3, 4, 5} {
+		if samples[i].TargetBitrate != expected {
+			t.Fatalf("expected sample %d to be %d, got %d", i, expected, samples[i].TargetBitrate)
+		}
+	}
+
+	latest, ok := history.latest()
+	if !ok || latest.TargetBitrate != 5 {
+		t.Fatalf("expected the latest sample to be 5, got (%d, %t)", latest.TargetBitrate, ok)
+	}
+}
+
+func TestBWEHistoryDisabled(t *testing.T) {
+	history := newBWEHistory(0)
+	history.add(BWESample{TargetBitrate: 1})
+
+	if samples := history.all(); len(samples) != 0 {
+		t.Fatalf("expected no samples, got %d", len(samples))
+	}
+	if _, ok := history.latest(); ok {
+		t.Fatal("expected no latest sample")
+	}
+}
+
+func TestBWEControllerWriteHistoryJSON(t *testing.T) {
+	bwc := createBWController(context.Background(), slog.Default())
+	defer bwc.Close()
+
+	if err := bwc.SetHistorySize(-1); err == nil {
+		t.Fatal("expected a negative history size to be rejected")
+	}
+	if err := bwc.SetHistorySize(2); err != nil {
+		t.Fatal(err)
+	}
+
+	bwc.record(BWESample{TargetBitrate: 1_000_000, Allocations: []BWEAllocation{{Subscriber: "video", Allocated: 800_000, Delivered: 700_000, Updated: true}}})
+	bwc.record(BWESample{TargetBitrate: 2_000_000, Usage: "normal"})
+
+	var buffer bytes.Buffer
+	if err := bwc.WriteHistoryJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	var samples []BWESample
+	if err := json.Unmarshal(buffer.Bytes(), &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 || samples[0].TargetBitrate != 1_000_000 || samples[1].Usage != "normal" {
+		t.Fatalf("unexpected history %+v", samples)
+	}
+	if allocations := samples[0].Allocations; len(allocations) != 1 || allocations[0] != (BWEAllocation{Subscriber: "video", Allocated: 800_000, Delivered: 700_000, Updated: true}) {
+		t.Fatalf("unexpected allocations %+v", allocations)
+	}
+}
+
+func TestBWESampleEstimatorStats(t *testing.T) {
+	var sample BWESample
+	sample.setEstimatorStats(map[string]interface{}{
+		"lossTargetBitrate":  int(900_000),
+		"averageLoss":        0.02,
+		"delayTargetBitrate": int64(1_100_000),
+		"delayEstimate":      1.5,
+		"usage":              "overuse",
+		"state":              "decrease",
+	})
+
+	if sample.LossTargetBitrate != 900_000 || sample.AverageLoss != 0.02 || sample.DelayTargetBitrate != 1_100_000 ||
+		sample.DelayEstimate != 1.5 || sample.Usage != "overuse" || sample.State != "decrease" {
+		t.Fatalf("unexpected sample %+v", sample)
+	}
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"errors"
+	"time"
+)
+
+// BitrateUpdateConfig shapes the bitrate updates a BWEController delivers, so that subscribers like
+// transcode.UpdateEncoder, which rebuild their encoder on every update, are not driven by every swing
+// of the estimate. The zero value delivers every allocation as is.
+type BitrateUpdateConfig struct {
+	// Smoothing is the weight of a new estimate in the exponentially weighted moving average of the
+	// estimates, in (0, 1]; 0 and 1 disable smoothing.
+	Smoothing float64 `yaml:"smoothing" json:"smoothing"`
+	// MaxIncrease and MaxDecrease limit how far the bitrate of a subscriber moves per update, as a
+	// fraction of its current bitrate; 0 is unlimited. A small MaxIncrease with a larger MaxDecrease ramps
+	// up carefully and backs off quickly.
+	MaxIncrease float64 `yaml:"max_increase" json:"max_increase"`
+	MaxDecrease float64 `yaml:"max_decrease" json:"max_decrease"`
+	// Deadband skips updates that change the bitrate of a subscriber by less than this fraction; keep it
+	// below the ramp limits, or the bitrate never moves.
+	Deadband float64 `yaml:"deadband" json:"deadband"`
+	// MinInterval is the minimum time between two updates of a subscriber.
+	MinInterval time.Duration `yaml:"min_interval" json:"min_interval"`
+}
+
+func (c BitrateUpdateConfig) validate() error {
+	if c.Smoothing < 0 || c.Smoothing > 1 {
+		return errors.New("smoothing needs to be within [0, 1]")
+	}
+	if c.MaxIncrease < 0 || c.MaxDecrease < 0 || c.MaxDecrease > 1 || c.Deadband < 0 {
+		return errors.New("ramp limits and deadband cannot be negative and max decrease cannot exceed 1")
+	}
+	if c.MinInterval < 0 {
+		return errors.New("minimum interval cannot be negative")
+	}
+
+	return nil
+}
+
+// SetUpdateConfig changes how bitrate updates are shaped, from the next tick on.
+func (bwc *BWEController) SetUpdateConfig(config BitrateUpdateConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+
+	bwc.mux.Lock()
+	defer bwc.mux.Unlock()
+
+	bwc.update = config
+
+	return nil
+}
+
+func (bwc *BWEController) updateConfig() BitrateUpdateConfig {
+	bwc.mux.RLock()
+	defer bwc.mux.RUnlock()
+
+	return bwc.update
+}
+
+// smooth feeds the estimate into the moving average and returns the average.
+func (bwc *BWEController) smooth(config BitrateUpdateConfig, estimate int64) int64 {
+	if config.Smoothing <= 0 || config.Smoothing >= 1 || !bwc.smoothed {
+		bwc.average, bwc.smoothed = float64(estimate), true
+		return estimate
+	}
+
+	bwc.average += config.Smoothing * (float64(estimate) - bwc.average)
+	return int64(bwc.average)
+}
+
+// shape applies the ramp limits, deadband and minimum interval to the allocation of the subscriber and
+// returns what to deliver, if anything.
+func (sub *subscriber) shape(config BitrateUpdateConfig, bitrate int64, now time.Time) (int64, bool) {
+	if !sub.delivered {
+		return bitrate, true
+	}
+
+	if config.MinInterval > 0 && now.Sub(sub.lastUpdate) < config.MinInterval {
+		return 0, false
+	}
+
+	current := float64(sub.current)
+	if config.MaxIncrease > 0 && current > 0 {
+		bitrate = min(bitrate, int64(current*(1+config.MaxIncrease)))
+	}
+	if config.MaxDecrease > 0 {
+		bitrate = max(bitrate, int64(current*(1-config.MaxDecrease)))
+	}
+
+	if bitrate == sub.current {
+		return 0, false
+	}
+	if config.Deadband > 0 && current > 0 {
+		change := float64(bitrate) - current
+		if change < 0 {
+			change = -change
+		}
+		if change/current < config.Deadband {
+			return 0, false
+		}
+	}
+
+	return bitrate, true
+}
+
+// deliver hands the bitrate to the worker of the subscriber, replacing an update it has not picked up
+// yet; the worker only ever sees the latest target.
+func (sub *subscriber) deliver(bitrate int64, now time.Time) {
+	sub.current, sub.lastUpdate, sub.delivered = bitrate, now, true
+
+	select {
+	case <-sub.pending:
+	default:
+	}
+
+	select {
+	case sub.pending <- bitrate:
+	default:
+	}
+}
+
+// worker calls the callback of the subscriber with the latest target, one call at a time, till the
+// subscriber is removed or the controller is closed.
+func (bwc *BWEController) worker(sub *subscriber) {
+	defer bwc.wg.Done()
+
+	for {
+		select {
+		case <-bwc.ctx.Done():
+			return
+		case <-sub.done:
+			return
+		case bitrate := <-sub.pending:
+			if err := sub.callback(bitrate); err != nil {
+				bwc.logger.Warn("bitrate update callback failed; unsubscribing", "subscriber", sub.id, "err", err)
+				bwc.Unsubscribe(sub.id)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+func TestSubscriberShape(t *testing.T) {
+	start := time.Now()
+
+	tests := []struct {
+		name      string
+		config    BitrateUpdateConfig
+		current   int64
+		allocated int64
+		after     time.Duration
+		expected  int64
+		ok        bool
+	}{
+		{"zero config delivers as is", BitrateUpdateConfig{}, 1_000_000, 1_500_000, 0, 1_500_000, true},
+		{"unchanged is skipped", BitrateUpdateConfig{}, 1_000_000, 1_000_000, 0, 0, false},
+		{"increase is limited", BitrateUpdateConfig{MaxIncrease: 0.1}, 1_000_000, 2_000_000, 0, 1_100_000, true},
+		{"decrease is limited", BitrateUpdateConfig{MaxDecrease: 0.5}, 1_000_000, 100_000, 0, 500_000, true},
+		{"within the deadband", BitrateUpdateConfig{Deadband: 0.05}, 1_000_000, 1_040_000, 0, 0, false},
+		{"outside the deadband", BitrateUpdateConfig{Deadband: 0.05}, 1_000_000, 1_060_000, 0, 1_060_000, true},
+		{"before the minimum interval", BitrateUpdateConfig{MinInterval: time.Second}, 1_000_000, 500_000, 500 * time.Millisecond, 0, false},
+		{"after the minimum interval", BitrateUpdateConfig{MinInterval: time.Second}, 1_000_000, 500_000, time.Second, 500_000, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sub := &subscriber{current: test.current, lastUpdate: start, delivered: true}
+
+			bitrate, ok := sub.shape(test.config, test.allocated, start.Add(test.after))
+			if ok != test.ok || bitrate != test.expected {
+				t.Fatalf("expected (%d, %t), got (%d, %t)", test.expected, test.ok, bitrate, ok)
+			}
+		})
+	}
+}
+
+func TestSubscriberShapeDeliversFirstAllocation(t *testing.T) {
+	sub := &subscriber{}
+
+	config := BitrateUpdateConfig{MaxIncrease: 0.1, Deadband: 0.5, MinInterval: time.Hour}
+	if bitrate, ok := sub.shape(config, 1_000_000, time.Now()); !ok || bitrate != 1_000_000 {
+		t.Fatalf("expected the first allocation to be delivered as is, got (%d, %t)", bitrate, ok)
+	}
+}
+
+func TestBWEControllerSmooth(t *testing.T) {
+	bwc := createBWController(context.Background(), slog.Default())
+	defer bwc.Close()
+
+	config := BitrateUpdateConfig{Smoothing: 0.25}
+	if average := bwc.smooth(config, 1_000_000); average != 1_000_000 {
+		t.Fatalf("expected the first estimate to start the average, got %d", average)
+	}
+	if average := bwc.smooth(config, 2_000_000); average != 1_250_000 {
+		t.Fatalf("expected 1250000, got %d", average)
+	}
+	if average := bwc.smooth(BitrateUpdateConfig{}, 3_000_000); average != 3_000_000 {
+		t.Fatalf("expected no smoothing with the zero config, got %d", average)
+	}
+}
+
+func TestBitrateUpdateConfigValidate(t *testing.T) {
+	invalid := []BitrateUpdateConfig{
+		{Smoothing: -0.1},
+		{Smoothing: 1.1},
+		{MaxIncrease: -1},
+		{MaxDecrease: 1.5},
+		{Deadband: -0.1},
+		{MinInterval: -time.Second},
+	}
+
+	for _, config := range invalid {
+		if err := config.validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", config)
+		}
+	}
+}
+
+func TestSubscriberWorkerSeesLatestUpdateOnly(t *testing.T) {
+	bwc := createBWController(context.Background(), slog.Default())
+	defer bwc.Close()
+
+	release := make(chan struct{})
+	updates := make(chan int64, 8)
+	if err := bwc.Subscribe("video", mediasource.Level1, func(bps int64) error {
+		updates <- bps
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	bwc.mux.RLock()
+	sub := bwc.subs["video"]
+	bwc.mux.RUnlock()
+
+	now := time.Now()
+	sub.deliver(100, now)
+	if bps := <-updates; bps != 100 {
+		t.Fatalf("expected 100, got %d", bps)
+	}
+
+	// NOTE: THE CALLBACK IS STILL RUNNING; ONLY THE LAST OF THESE MAY REACH IT
+	sub.deliver(200, now)
+	sub.deliver(300, now)
+	close(release)
+
+	select {
+	case bps := <-updates:
+		if bps != 300 {
+			t.Fatalf("expected 300, got %d", bps)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no update after the callback returned")
+	}
+
+	select {
+	case bps := <-updates:
+		t.Fatalf("expected no further update, got %d", bps)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -3,24 +3,19 @@ package client
 import (
 	"context"
 	"errors"
-	"fmt"
 	"iter"
+	"log/slog"
+	"maps"
+	"slices"
 	"sync"
-	"time"
 
 	"github.com/pion/interceptor"
-	"github.com/pion/interceptor/pkg/cc"
 	"github.com/pion/interceptor/pkg/stats"
 	"github.com/pion/webrtc/v4"
 
 	"github.com/harshabose/tools/pkg/multierr"
 )
 
-type estimator struct {
-	e        cc.BandwidthEstimator
-	interval time.Duration
-}
-
 type Client struct {
 	pcs                 map[string]*PeerConnection
 	mediaEngine         *webrtc.MediaEngine
@@ -28,9 +23,15 @@ type Client struct {
 	interceptorRegistry *interceptor.Registry
 	api                 *webrtc.API
 
-	estimator  chan estimator
+	estimators *estimatorHandoff
 	getterChan chan stats.Getter
 
+	reconnect   *ReconnectConfig
+	onReconnect OnReconnectEvent
+
+	logger *slog.Logger
+	events *eventBus
+
 	mux sync.RWMutex
 	ctx context.Context
 }
@@ -57,7 +58,9 @@ func NewClient(
 		interceptorRegistry: interceptorRegistry,
 		settingsEngine:      settings,
 		pcs:                 make(map[string]*PeerConnection),
-		estimator:           make(chan estimator, 10),
+		estimators:          newEstimatorHandoff(),
+		logger:              slog.Default(),
+		events:              newEventBus(),
 		ctx:                 ctx,
 	}
 
@@ -73,38 +76,53 @@ func NewClient(
 }
 
 func (c *Client) CreatePeerConnection(label string, config webrtc.Configuration) (*PeerConnection, error) {
-	c.mux.Lock()
-	defer c.mux.Unlock()
+	return c.createPeerConnection(c.ctx, label, config, false)
+}
 
-	if _, exists := c.pcs[label]; exists {
-		return nil, errors.New("peer connection already exists")
-	}
+// CreatePeerConnectionWithBWEstimator creates a peer connection whose BWEController is driven by the
+// bandwidth estimator of WithBandwidthControlInterceptor; see CreatePeerConnectionWithBWEstimatorContext.
+func (c *Client) CreatePeerConnectionWithBWEstimator(label string, config webrtc.Configuration) (*PeerConnection, error) {
+	return c.CreatePeerConnectionWithBWEstimatorContext(c.ctx, label, config)
+}
 
-	pc, err := CreatePeerConnection(c.ctx, label, c.api, config)
+// CreatePeerConnectionWithBWEstimatorContext is CreatePeerConnectionWithBWEstimator, waiting for the peer
+// connections being built concurrently till ctx is done. Every peer connection gets the estimator that
+// was created for it; without WithBandwidthControlInterceptor, or another estimator option, it fails
+// with ErrNoBandwidthEstimator.
+func (c *Client) CreatePeerConnectionWithBWEstimatorContext(ctx context.Context, label string, config webrtc.Configuration) (*PeerConnection, error) {
+	pc, err := c.createPeerConnection(ctx, label, config, true)
 	if err != nil {
 		return nil, err
 	}
 
-	c.pcs[label] = pc
+	// NOTE: PION'S CC INTERCEPTOR ONLY HANDS OUT ESTIMATORS THROUGH A CALLBACK, SEE "https://github.com/pion/webrtc/issues/3053"
+	e, err := c.estimators.take(pc.estimatorID)
+	if err != nil {
+		return nil, multierr.Append(err, c.ClosePeerConnection(label))
+	}
+
+	pc.bwc.set(e.e, e.interval)
+	pc.bwc.Start()
 
 	return pc, nil
 }
 
-func (c *Client) CreatePeerConnectionWithBWEstimator(label string, config webrtc.Configuration) (*PeerConnection, error) {
-	pc, err := c.CreatePeerConnection(label, config)
+// createPeerConnection creates a peer connection living as long as the client; buildCtx bounds the wait
+// for peer connections being built concurrently.
+func (c *Client) createPeerConnection(buildCtx context.Context, label string, config webrtc.Configuration, withEstimator bool) (*PeerConnection, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if _, exists := c.pcs[label]; exists {
+		return nil, errors.New("peer connection already exists")
+	}
+
+	pc, err := createPeerConnection(c.ctx, buildCtx, label, c.api, config, c.logger, c.events, c.estimators, withEstimator)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: THIS WEIRD CHANNEL BASED APPROACH OF SETTING BW CONTROLLER IS REQUIRED BECAUSE OF THE
-	// TODO: THE WEIRD DESIGN OF CC INTERCEPTOR IN PION. TRACK THE ISSUE WITH "https://github.com/pion/webrtc/issues/3053"
-	if pc.bwc != nil {
-		select {
-		case e := <-c.estimator:
-			pc.bwc.set(e.e, e.interval)
-			pc.bwc.Start()
-		}
-	}
+	c.pcs[label] = pc
 
 	return pc, nil
 }
@@ -119,13 +137,15 @@ func (c *Client) GetPeerConnection(label string) (*PeerConnection, error) {
 	return c.pcs[label], nil
 }
 
+// PeerConnections yields the peer connections in label order. Connect relies on the order: signals
+// which connect one label at a time only work when both sides walk the labels alike.
 func (c *Client) PeerConnections() iter.Seq2[string, *PeerConnection] {
 	return func(yield func(string, *PeerConnection) bool) {
 		c.mux.RLock()
 		defer c.mux.RUnlock()
 
-		for label, pc := range c.pcs {
-			if !yield(label, pc) {
+		for _, label := range slices.Sorted(maps.Keys(c.pcs)) {
+			if !yield(label, c.pcs[label]) {
 				return
 			}
 		}
@@ -136,14 +156,41 @@ func (c *Client) Connect(category string, signal BaseSignal) error {
 	var merr error
 
 	for _, pc := range c.PeerConnections() {
+		pc.setSignal(category, signal)
 		if err := signal.Connect(category, pc); err != nil {
 			merr = multierr.Append(merr, err)
+			continue
+		}
+		pc.markNegotiated()
+
+		if c.reconnect != nil {
+			pc.supervised.Do(func() {
+				go newSupervisor(c, pc, *c.reconnect, c.onReconnect).loop()
+			})
 		}
 	}
 
 	return merr
 }
 
+// recreatePeerConnection rebuilds the underlying peer connection of pc and, when bandwidth estimation
+// was enabled on it, hands it the estimator of the new connection.
+func (c *Client) recreatePeerConnection(pc *PeerConnection) error {
+	if err := pc.recreate(); err != nil {
+		return err
+	}
+
+	if pc.bwc != nil && pc.bwc.get() != nil {
+		e, err := c.estimators.take(pc.estimatorID)
+		if err != nil {
+			return err
+		}
+		pc.bwc.set(e.e, e.interval)
+	}
+
+	return nil
+}
+
 func (c *Client) ClosePeerConnection(label string) error {
 	pc, err := c.GetPeerConnection(label)
 	if err != nil {
@@ -181,7 +228,7 @@ func (c *Client) ClosePeerConnectionIfExists(label string) error {
 func (c *Client) Close() {
 	for _, pc := range c.PeerConnections() {
 		if err := pc.Close(); err != nil {
-			fmt.Printf("Client: error while closing err=%v\n", err)
+			pc.logger.Error("error while closing peer connection", "err", err)
 		}
 	}
 
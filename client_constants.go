@@ -5,14 +5,50 @@ import (
 
 	"github.com/pion/interceptor/pkg/nack"
 	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
 )
 
+// NOTE: THE PAYLOAD TYPES AND FMTP LINES ARE DEFINED IN MEDIASOURCE, SO THAT THE SINKS AND SOURCES USE THE SAME ONES
+const (
+	H264PayloadType           = mediasource.H264PayloadType
+	H264RTXPayloadType        = mediasource.H264RTXPayloadType
+	VP8PayloadType            = mediasource.VP8PayloadType
+	VP8RTXPayloadType         = mediasource.VP8RTXPayloadType
+	VP9Profile0PayloadType    = mediasource.VP9Profile0PayloadType
+	VP9Profile0RTXPayloadType = mediasource.VP9Profile0RTXPayloadType
+	VP9Profile2PayloadType    = mediasource.VP9Profile2PayloadType
+	VP9Profile2RTXPayloadType = mediasource.VP9Profile2RTXPayloadType
+	AV1PayloadType            = mediasource.AV1PayloadType
+	AV1RTXPayloadType         = mediasource.AV1RTXPayloadType
+	H265PayloadType           = mediasource.H265PayloadType
+	H265RTXPayloadType        = mediasource.H265RTXPayloadType
+	OpusPayloadType           = mediasource.OpusPayloadType
+)
+
+// NOTE: DUPLICATE CONSTANT KEYS DO NOT COMPILE, SO THIS KEEPS THE PAYLOAD TYPES ABOVE FROM COLLIDING. EVERY NEW
+// NOTE: PAYLOAD TYPE NEEDS TO BE ADDED HERE.
+var _ = map[webrtc.PayloadType]struct{}{
+	H264PayloadType:           {},
+	H264RTXPayloadType:        {},
+	VP8PayloadType:            {},
+	VP8RTXPayloadType:         {},
+	VP9Profile0PayloadType:    {},
+	VP9Profile0RTXPayloadType: {},
+	VP9Profile2PayloadType:    {},
+	VP9Profile2RTXPayloadType: {},
+	AV1PayloadType:            {},
+	AV1RTXPayloadType:         {},
+	H265PayloadType:           {},
+	H265RTXPayloadType:        {},
+	OpusPayloadType:           {},
+}
+
 const (
-	H264PayloadType    webrtc.PayloadType = 102
-	H264RTXPayloadType webrtc.PayloadType = 103
-	VP8PayloadType     webrtc.PayloadType = 96
-	VP8RTXPayloadType  webrtc.PayloadType = 97
-	OpusPayloadType    webrtc.PayloadType = 111
+	VP9FmtpProfile0 = mediasource.VP9FmtpProfile0
+	VP9FmtpProfile2 = mediasource.VP9FmtpProfile2
+	AV1Fmtp         = mediasource.AV1Fmtp
+	H265Fmtp        = mediasource.H265Fmtp
 )
 
 type NACKGeneratorOptions []nack.GeneratorOption
@@ -1,12 +1,12 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/pion/interceptor/pkg/cc"
 	"github.com/pion/interceptor/pkg/flexfec"
-	"github.com/pion/interceptor/pkg/gcc"
 	"github.com/pion/interceptor/pkg/jitterbuffer"
 	"github.com/pion/interceptor/pkg/nack"
 	"github.com/pion/interceptor/pkg/report"
@@ -14,71 +14,81 @@ import (
 	"github.com/pion/interceptor/pkg/twcc"
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
 )
 
 type ClientOption = func(*Client) error
 
-func WithH264MediaEngine(clockrate uint32) ClientOption {
-	return func(client *Client) error {
-		RTCPFeedback := []webrtc.RTCPFeedback{{Type: webrtc.TypeRTCPFBGoogREMB}, {Type: webrtc.TypeRTCPFBCCM, Parameter: "fir"}, {Type: webrtc.TypeRTCPFBNACK}, {Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"}}
-		if err := client.mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
-			RTPCodecCapability: webrtc.RTPCodecCapability{
-				MimeType:     webrtc.MimeTypeH264,
-				ClockRate:    clockrate,
-				Channels:     0,
-				SDPFmtpLine:  fmt.Sprintf("level-asymmetry-allowed=1"),
-				RTCPFeedback: RTCPFeedback,
-			},
-			PayloadType: H264PayloadType,
-		}, webrtc.RTPCodecTypeVideo); err != nil {
-			return err
-		}
+// registerVideoCodec registers a video codec with the RTCP feedback every video codec of the client asks for,
+// and the RTX codec that retransmits it.
+func registerVideoCodec(mediaEngine *webrtc.MediaEngine, mimeType string, clockrate uint32, fmtpLine string, payloadType, rtxPayloadType webrtc.PayloadType) error {
+	RTCPFeedback := []webrtc.RTCPFeedback{{Type: webrtc.TypeRTCPFBGoogREMB}, {Type: webrtc.TypeRTCPFBCCM, Parameter: "fir"}, {Type: webrtc.TypeRTCPFBNACK}, {Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"}}
+	if err := mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{
+			MimeType:     mimeType,
+			ClockRate:    clockrate,
+			Channels:     0,
+			SDPFmtpLine:  fmtpLine,
+			RTCPFeedback: RTCPFeedback,
+		},
+		PayloadType: payloadType,
+	}, webrtc.RTPCodecTypeVideo); err != nil {
+		return err
+	}
 
-		if err := client.mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
-			RTPCodecCapability: webrtc.RTPCodecCapability{
-				MimeType:     webrtc.MimeTypeRTX,
-				ClockRate:    clockrate,
-				Channels:     0,
-				SDPFmtpLine:  fmt.Sprintf("apt=%d", H264PayloadType),
-				RTCPFeedback: nil,
-			},
-			PayloadType: H264RTXPayloadType,
-		}, webrtc.RTPCodecTypeVideo); err != nil {
-			return err
-		}
+	if err := mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{
+			MimeType:     webrtc.MimeTypeRTX,
+			ClockRate:    clockrate,
+			Channels:     0,
+			SDPFmtpLine:  fmt.Sprintf("apt=%d", payloadType),
+			RTCPFeedback: nil,
+		},
+		PayloadType: rtxPayloadType,
+	}, webrtc.RTPCodecTypeVideo); err != nil {
+		return err
+	}
 
-		return nil
+	return nil
+}
+
+func WithH264MediaEngine(clockrate uint32) ClientOption {
+	return func(client *Client) error {
+		return registerVideoCodec(client.mediaEngine, webrtc.MimeTypeH264, clockrate, "level-asymmetry-allowed=1", H264PayloadType, H264RTXPayloadType)
 	}
 }
 
 func WithVP8MediaEngine(clockrate uint32) ClientOption {
 	return func(client *Client) error {
-		RTCPFeedback := []webrtc.RTCPFeedback{{Type: webrtc.TypeRTCPFBGoogREMB}, {Type: webrtc.TypeRTCPFBCCM, Parameter: "fir"}, {Type: webrtc.TypeRTCPFBNACK}, {Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"}}
-		if err := client.mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
-			RTPCodecCapability: webrtc.RTPCodecCapability{
-				MimeType:     webrtc.MimeTypeVP8,
-				ClockRate:    clockrate,
-				RTCPFeedback: RTCPFeedback,
-				SDPFmtpLine:  fmt.Sprintf(""),
-			},
-			PayloadType: VP8PayloadType,
-		}, webrtc.RTPCodecTypeVideo); err != nil {
-			return err
-		}
+		return registerVideoCodec(client.mediaEngine, webrtc.MimeTypeVP8, clockrate, "", VP8PayloadType, VP8RTXPayloadType)
+	}
+}
 
-		if err := client.mediaEngine.RegisterCodec(webrtc.RTPCodecParameters{
-			RTPCodecCapability: webrtc.RTPCodecCapability{
-				MimeType:     webrtc.MimeTypeRTX,
-				ClockRate:    clockrate,
-				RTCPFeedback: nil,
-				SDPFmtpLine:  fmt.Sprintf("apt=%d", VP8PayloadType),
-			},
-			PayloadType: VP8RTXPayloadType,
-		}, webrtc.RTPCodecTypeVideo); err != nil {
+// WithVP9MediaEngine registers VP9 with the given profile, which has to be 0 or 2. Both profiles can be
+// registered by using the option twice; they get different payload types.
+func WithVP9MediaEngine(clockrate uint32, profile uint8) ClientOption {
+	return func(client *Client) error {
+		payloadType, rtxPayloadType, fmtpLine, err := mediasource.VP9Codec(profile)
+		if err != nil {
 			return err
 		}
 
-		return nil
+		return registerVideoCodec(client.mediaEngine, webrtc.MimeTypeVP9, clockrate, fmtpLine, payloadType, rtxPayloadType)
+	}
+}
+
+// WithAV1MediaEngine registers AV1 main profile at level 3.1 (level-idx=5), with RTX.
+func WithAV1MediaEngine(clockrate uint32) ClientOption {
+	return func(client *Client) error {
+		return registerVideoCodec(client.mediaEngine, webrtc.MimeTypeAV1, clockrate, AV1Fmtp, AV1PayloadType, AV1RTXPayloadType)
+	}
+}
+
+// WithH265MediaEngine registers H.265 main profile at level 3.1 (level-id=93), with RTX.
+func WithH265MediaEngine(clockrate uint32) ClientOption {
+	return func(client *Client) error {
+		return registerVideoCodec(client.mediaEngine, webrtc.MimeTypeH265, clockrate, H265Fmtp, H265PayloadType, H265RTXPayloadType)
 	}
 }
 
@@ -161,7 +171,8 @@ func WithTWCCSenderInterceptor(interval TWCCSenderInterval) ClientOption {
 	}
 }
 
-// WARN: DO NOT USE THIS, PION HAS SOME ISSUE WITH THIS WHICH MAKES THE ONTRACK CALLBACK NOT FIRE
+// WARN: DO NOT USE THIS, PION HAS SOME ISSUE WITH THIS WHICH MAKES THE ONTRACK CALLBACK NOT FIRE. USE
+// mediasink.WithJitterBuffer ON THE SINKS INSTEAD
 
 func WithJitterBufferInterceptor() ClientOption {
 	return func(client *Client) error {
@@ -221,17 +232,45 @@ func WithSimulcastExtensionHeaders() ClientOption {
 	}
 }
 
+// WithBandwidthControlInterceptor estimates the bandwidth with Google Congestion Control, from the
+// TWCC feedback of the remote; see WithTWCCHeaderExtensionSender.
 func WithBandwidthControlInterceptor(initialBitrate, minimumBitrate, maximumBitrate int64, interval time.Duration) ClientOption {
+	factory, _ := BandwidthEstimatorFactoryByName(BandwidthEstimatorGCC, initialBitrate, minimumBitrate, maximumBitrate)
+	return WithBandwidthEstimatorInterceptor(factory, interval)
+}
+
+// WithStaticBandwidthEstimator has every peer connection with bandwidth estimation assume a fixed
+// bitrate.
+func WithStaticBandwidthEstimator(bitrate int64, interval time.Duration) ClientOption {
+	factory, _ := BandwidthEstimatorFactoryByName(BandwidthEstimatorStatic, bitrate, bitrate, bitrate)
+	return WithBandwidthEstimatorInterceptor(factory, interval)
+}
+
+// WithREMBBandwidthEstimator follows the REMB feedback of the remote; see REMBBandwidthEstimator.
+func WithREMBBandwidthEstimator(initialBitrate, minimumBitrate, maximumBitrate int64, interval time.Duration) ClientOption {
+	factory, _ := BandwidthEstimatorFactoryByName(BandwidthEstimatorREMB, initialBitrate, minimumBitrate, maximumBitrate)
+	return WithBandwidthEstimatorInterceptor(factory, interval)
+}
+
+// WithBandwidthEstimatorInterceptor creates an estimator with factory for every peer connection and
+// hands it to its BWEController, which reads it every interval. Estimators see the RTP streams sent and
+// the RTCP received by the peer connection; only one of these options can be used.
+func WithBandwidthEstimatorInterceptor(factory cc.BandwidthEstimatorFactory, interval time.Duration) ClientOption {
 	return func(client *Client) error {
-		congestionController, err := cc.NewInterceptor(func() (cc.BandwidthEstimator, error) {
-			return gcc.NewSendSideBWE(gcc.SendSideBWEInitialBitrate(int(initialBitrate)), gcc.SendSideBWEMinBitrate(int(minimumBitrate)), gcc.SendSideBWEMaxBitrate(int(maximumBitrate)))
-		})
+		if factory == nil {
+			return errors.New("bandwidth estimator factory is nil")
+		}
+		if interval <= 0 {
+			return errors.New("bandwidth estimator interval needs to be positive")
+		}
+
+		congestionController, err := cc.NewInterceptor(factory)
 		if err != nil {
 			return err
 		}
 
 		congestionController.OnNewPeerConnection(func(id string, e cc.BandwidthEstimator) {
-			client.estimator <- estimator{e: e, interval: interval}
+			client.estimators.add(id, estimator{e: e, interval: interval})
 		})
 
 		client.interceptorRegistry.Add(congestionController)
@@ -262,3 +301,18 @@ func WithStatsCollector() ClientOption {
 		return nil
 	}
 }
+
+// WithReconnectSupervisor watches every peer connection connected through Client.Connect and, when it
+// fails or stays disconnected, restarts ICE through the same signal, falling back to recreating the
+// peer connection. onEvent (optional) is called after every attempt.
+func WithReconnectSupervisor(config ReconnectConfig, onEvent OnReconnectEvent) ClientOption {
+	return func(client *Client) error {
+		if err := config.validate(); err != nil {
+			return err
+		}
+
+		client.reconnect = &config
+		client.onReconnect = onEvent
+		return nil
+	}
+}
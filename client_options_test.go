@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+)
+
+func TestMediaEnginesRegisterTogether(t *testing.T) {
+	client, err := NewClient(context.Background(), nil, nil, nil,
+		WithH264MediaEngine(90000),
+		WithVP8MediaEngine(90000),
+		WithVP9MediaEngine(90000, 0),
+		WithVP9MediaEngine(90000, 2),
+		WithAV1MediaEngine(90000),
+		WithH265MediaEngine(90000),
+		WithOpusMediaEngine(48000, 2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	pc, err := client.api.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	codecs := make(map[webrtc.PayloadType]webrtc.RTPCodecParameters)
+	for _, kind := range []webrtc.RTPCodecType{webrtc.RTPCodecTypeVideo, webrtc.RTPCodecTypeAudio} {
+		transceiver, err := pc.AddTransceiverFromKind(kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, codec := range transceiver.Sender().GetParameters().Codecs {
+			codecs[codec.PayloadType] = codec
+		}
+	}
+
+	tests := []struct {
+		mimeType       string
+		fmtpLine       string
+		payloadType    webrtc.PayloadType
+		rtxPayloadType webrtc.PayloadType
+	}{
+		{webrtc.MimeTypeH264, "level-asymmetry-allowed=1", H264PayloadType, H264RTXPayloadType},
+		{webrtc.MimeTypeVP8, "", VP8PayloadType, VP8RTXPayloadType},
+		{webrtc.MimeTypeVP9, VP9FmtpProfile0, VP9Profile0PayloadType, VP9Profile0RTXPayloadType},
+		{webrtc.MimeTypeVP9, VP9FmtpProfile2, VP9Profile2PayloadType, VP9Profile2RTXPayloadType},
+		{webrtc.MimeTypeAV1, AV1Fmtp, AV1PayloadType, AV1RTXPayloadType},
+		{webrtc.MimeTypeH265, H265Fmtp, H265PayloadType, H265RTXPayloadType},
+		{webrtc.MimeTypeOpus, "minptime=10;useinbandfec=1", OpusPayloadType, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s/%d", test.mimeType, test.payloadType), func(t *testing.T) {
+			codec, ok := codecs[test.payloadType]
+			if !ok {
+				t.Fatalf("payload type %d is not registered", test.payloadType)
+			}
+			if codec.MimeType != test.mimeType || codec.SDPFmtpLine != test.fmtpLine {
+				t.Fatalf("expected %s '%s' at payload type %d, got %s '%s'", test.mimeType, test.fmtpLine, test.payloadType, codec.MimeType, codec.SDPFmtpLine)
+			}
+
+			if test.rtxPayloadType == 0 {
+				return
+			}
+			if len(codec.RTCPFeedback) != 4 {
+				t.Fatalf("expected REMB, FIR, NACK and PLI feedback, got %v", codec.RTCPFeedback)
+			}
+
+			rtx, ok := codecs[test.rtxPayloadType]
+			if !ok {
+				t.Fatalf("rtx payload type %d is not registered", test.rtxPayloadType)
+			}
+			if rtx.MimeType != webrtc.MimeTypeRTX || rtx.SDPFmtpLine != fmt.Sprintf("apt=%d", test.payloadType) {
+				t.Fatalf("expected rtx of payload type %d, got %s '%s'", test.payloadType, rtx.MimeType, rtx.SDPFmtpLine)
+			}
+		})
+	}
+}
@@ -0,0 +1,833 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"gopkg.in/yaml.v3"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/datachannel"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+	"github.com/harshabose/tools/pkg/multierr"
+)
+
+// Config describes a Client declaratively: its codecs, interceptors, ICE servers and peer connections
+// with their data channels, media sources and media sinks, plus the signal to connect them with. See
+// LoadConfig and NewClientFromConfig. A YAML example:
+//
+//	codecs:
+//	  - name: h264
+//	  - name: opus
+//	interceptors:
+//	  preset: low-latency
+//	  nack: true
+//	  rtcp_reports: true
+//	  bandwidth: {initial: 1000000, min: 100000, max: 5000000, interval: 100ms}
+//	ice_servers:
+//	  - urls: ["${STUN_SERVER_URL}"]
+//	peer_connections:
+//	  - label: drone
+//	    bandwidth_estimation: true
+//	    data_channels: [{label: mavlink}]
+//	    sources: [{label: camera, codec: h264, priority: 5}]
+//	signal:
+//	  type: websocket
+//	  role: offer
+//	  category: fleet
+//	  url: ws://localhost:8080/signal
+//	  trickle: true
+type Config struct {
+	// DefaultCodecs registers pion's default codecs in addition to Codecs
+	DefaultCodecs   bool                   `yaml:"default_codecs" json:"default_codecs"`
+	Codecs          []CodecConfig          `yaml:"codecs" json:"codecs"`
+	Interceptors    InterceptorConfig      `yaml:"interceptors" json:"interceptors"`
+	ICEServers      []ICEServerConfig      `yaml:"ice_servers" json:"ice_servers"`
+	PeerConnections []PeerConnectionConfig `yaml:"peer_connections" json:"peer_connections"`
+	Signal          SignalConfig           `yaml:"signal" json:"signal"`
+	// Reconnect enables the reconnect supervisor with DefaultReconnectConfig
+	Reconnect bool `yaml:"reconnect" json:"reconnect"`
+}
+
+type CodecConfig struct {
+	Name mediasource.CodecID `yaml:"name" json:"name"`
+	// ClockRate defaults to 90000 for video and 48000 for audio
+	ClockRate uint32 `yaml:"clock_rate" json:"clock_rate"`
+	// Channels defaults to 2 for opus
+	Channels uint16 `yaml:"channels" json:"channels"`
+	// Profile is the vp9 profile, 0 or 2; both can be registered by listing vp9 twice
+	Profile uint8 `yaml:"profile" json:"profile"`
+}
+
+type InterceptorPreset string
+
+const (
+	InterceptorPresetLowLatency   InterceptorPreset = "low-latency"
+	InterceptorPresetDefault      InterceptorPreset = "default"
+	InterceptorPresetHighQuality  InterceptorPreset = "high-quality"
+	InterceptorPresetLowBandwidth InterceptorPreset = "low-bandwidth"
+)
+
+type interceptorPreset struct {
+	generator  NACKGeneratorOptions
+	responder  NACKResponderOptions
+	twcc       TWCCSenderInterval
+	rtcpReport RTCPReportInterval
+}
+
+var interceptorPresets = map[InterceptorPreset]interceptorPreset{
+	InterceptorPresetLowLatency:   {NACKGeneratorLowLatency, NACKResponderLowLatency, TWCCIntervalLowLatency, RTCPReportIntervalLowLatency},
+	InterceptorPresetDefault:      {NACKGeneratorDefault, NACKResponderDefault, TWCCIntervalDefault, RTCPReportIntervalDefault},
+	InterceptorPresetHighQuality:  {NACKGeneratorHighQuality, NACKResponderHighQuality, TWCCIntervalHighQuality, RTCPReportIntervalHighQuality},
+	InterceptorPresetLowBandwidth: {NACKGeneratorLowBandwidth, NACKResponderLowBandwidth, TWCCIntervalLowBandwidth, RTCPReportIntervalLowBandwidth},
+}
+
+type InterceptorConfig struct {
+	// Preset picks the NACK, TWCC and RTCP report settings; defaults to InterceptorPresetDefault
+	Preset InterceptorPreset `yaml:"preset" json:"preset"`
+	// Default registers pion's default interceptors, which already include NACK, RTCP reports and TWCC
+	Default     bool             `yaml:"default" json:"default"`
+	NACK        bool             `yaml:"nack" json:"nack"`
+	TWCC        bool             `yaml:"twcc" json:"twcc"`
+	RTCPReports bool             `yaml:"rtcp_reports" json:"rtcp_reports"`
+	Simulcast   bool             `yaml:"simulcast" json:"simulcast"`
+	Bandwidth   *BandwidthConfig `yaml:"bandwidth" json:"bandwidth"`
+}
+
+// BandwidthConfig enables send side bandwidth estimation; bitrates are in bits per second. Estimator is
+// gcc (default), remb, or static, which holds Initial.
+type BandwidthConfig struct {
+	Estimator BandwidthEstimatorName `yaml:"estimator" json:"estimator"`
+	Initial   int64                  `yaml:"initial" json:"initial"`
+	Min       int64                  `yaml:"min" json:"min"`
+	Max       int64                  `yaml:"max" json:"max"`
+	Interval  time.Duration          `yaml:"interval" json:"interval"`
+}
+
+// ICEServerConfig is a STUN or TURN server. Environment variables in its values are expanded, so that
+// credentials do not need to be part of the config file.
+type ICEServerConfig struct {
+	URLs       []string `yaml:"urls" json:"urls"`
+	Username   string   `yaml:"username" json:"username"`
+	Credential string   `yaml:"credential" json:"credential"`
+}
+
+type PeerConnectionConfig struct {
+	Label string `yaml:"label" json:"label"`
+	// BandwidthEstimation creates the peer connection with a bandwidth estimator; needs interceptors.bandwidth
+	BandwidthEstimation bool `yaml:"bandwidth_estimation" json:"bandwidth_estimation"`
+	// BandwidthAllocation is how the estimate is split between the subscribers, see
+	// AllocationStrategyByName; ReservedBitrate (bps) is kept for the data channels
+	BandwidthAllocation AllocationStrategyName `yaml:"bandwidth_allocation" json:"bandwidth_allocation"`
+	ReservedBitrate     int64                  `yaml:"reserved_bitrate" json:"reserved_bitrate"`
+	// BitrateUpdate smooths and rate limits the bitrate updates of the subscribers
+	BitrateUpdate *BitrateUpdateConfig `yaml:"bitrate_update" json:"bitrate_update"`
+	// BandwidthHistory is how many ticks of the estimator are remembered; nil keeps DefaultBWEHistorySize
+	BandwidthHistory *int `yaml:"bandwidth_history" json:"bandwidth_history"`
+	// ICEServers replaces the client wide ICE servers for this peer connection, if set
+	ICEServers   []ICEServerConfig   `yaml:"ice_servers" json:"ice_servers"`
+	DataChannels []DataChannelConfig `yaml:"data_channels" json:"data_channels"`
+	Sources      []SourceConfig      `yaml:"sources" json:"sources"`
+	Sinks        []SinkConfig        `yaml:"sinks" json:"sinks"`
+	// AutoSinks creates a sink, labelled with the track ID, for every remote track no sink is given for
+	AutoSinks bool `yaml:"auto_sinks" json:"auto_sinks"`
+}
+
+type DataChannelConfig struct {
+	Label string `yaml:"label" json:"label"`
+	// Ordered defaults to true
+	Ordered           *bool   `yaml:"ordered" json:"ordered"`
+	MaxRetransmits    *uint16 `yaml:"max_retransmits" json:"max_retransmits"`
+	MaxPacketLifeTime *uint16 `yaml:"max_packet_lifetime" json:"max_packet_lifetime"`
+	Protocol          string  `yaml:"protocol" json:"protocol"`
+	// Negotiated channels are created by both sides with the same ID instead of being announced
+	Negotiated bool    `yaml:"negotiated" json:"negotiated"`
+	ID         *uint16 `yaml:"id" json:"id"`
+}
+
+type SourceConfig struct {
+	Label     string               `yaml:"label" json:"label"`
+	Codec     mediasource.CodecID  `yaml:"codec" json:"codec"`
+	ClockRate uint32               `yaml:"clock_rate" json:"clock_rate"`
+	Channels  uint16               `yaml:"channels" json:"channels"`
+	Profile   uint8                `yaml:"profile" json:"profile"`
+	Priority  mediasource.Priority `yaml:"priority" json:"priority"`
+	// RTP creates a source written with RTP packets instead of samples
+	RTP bool `yaml:"rtp" json:"rtp"`
+}
+
+type SinkConfig struct {
+	Label     string              `yaml:"label" json:"label"`
+	Codec     mediasource.CodecID `yaml:"codec" json:"codec"`
+	ClockRate uint32              `yaml:"clock_rate" json:"clock_rate"`
+	Channels  uint16              `yaml:"channels" json:"channels"`
+	Profile   uint8               `yaml:"profile" json:"profile"`
+	// TrackID, StreamID, MID and RID route remote tracks to the sink; without them the sink receives the
+	// track whose ID is the sink's label
+	TrackID  string `yaml:"track_id" json:"track_id"`
+	StreamID string `yaml:"stream_id" json:"stream_id"`
+	MID      string `yaml:"mid" json:"mid"`
+	RID      string `yaml:"rid" json:"rid"`
+	// JitterBuffer reorders and smooths the packets of the sink, see mediasink.WithJitterBuffer
+	JitterBuffer *JitterBufferConfig `yaml:"jitter_buffer" json:"jitter_buffer"`
+	// AutoKeyframe requests keyframes when a track is attached and after loss, see
+	// mediasink.WithAutoKeyframeRequest; KeyframeInterval limits how often
+	AutoKeyframe     bool          `yaml:"auto_keyframe" json:"auto_keyframe"`
+	KeyframeInterval time.Duration `yaml:"keyframe_interval" json:"keyframe_interval"`
+}
+
+// JitterBufferConfig is the playout delay of a sink's jitter buffer; unset values are taken from
+// mediasink.DefaultJitterBufferConfig.
+type JitterBufferConfig struct {
+	Target time.Duration `yaml:"target" json:"target"`
+	Min    time.Duration `yaml:"min" json:"min"`
+	Max    time.Duration `yaml:"max" json:"max"`
+}
+
+type SignalType string
+
+const (
+	SignalTypeWebSocket SignalType = "websocket"
+	SignalTypeFile      SignalType = "file"
+	SignalTypeWHIP      SignalType = "whip"
+	SignalTypeWHEP      SignalType = "whep"
+	SignalTypeFirebase  SignalType = "firebase"
+)
+
+// SignalConfig describes the signal built by NewSignalFromConfig. Role is "offer" or "answer" and is not
+// used by WHIP and WHEP, which always offer. Environment variables in URL, Endpoint and Token are
+// expanded.
+type SignalConfig struct {
+	Type     SignalType `yaml:"type" json:"type"`
+	Role     string     `yaml:"role" json:"role"`
+	Category string     `yaml:"category" json:"category"`
+	Trickle  bool       `yaml:"trickle" json:"trickle"`
+
+	// URL of the websocket signal server
+	URL string `yaml:"url" json:"url"`
+
+	// Endpoint and Token of WHIP and WHEP
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	Token    string `yaml:"token" json:"token"`
+
+	// OfferDir, AnswerDir and Encoding of the file signals
+	OfferDir  string       `yaml:"offer_dir" json:"offer_dir"`
+	AnswerDir string       `yaml:"answer_dir" json:"answer_dir"`
+	Encoding  FileEncoding `yaml:"encoding" json:"encoding"`
+}
+
+// ErrEmptyConfig is returned for a config without any document, which would otherwise decode to the zero
+// config.
+var ErrEmptyConfig = errors.New("empty config")
+
+// LoadConfig reads and validates a config file. JSON is a subset of YAML, so both are accepted.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading config; err: %w", err)
+	}
+
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("error in config %s; err: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// ParseConfig decodes and validates a YAML or JSON config. Unknown fields are rejected, so that typos do
+// not silently fall back to defaults.
+func ParseConfig(data []byte) (*Config, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	config := &Config{}
+	if err := decoder.Decode(config); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrEmptyConfig
+		}
+		return nil, fmt.Errorf("error while decoding config; err: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// Validate reports every problem of the config at once.
+func (config *Config) Validate() error {
+	var merr error
+	fail := func(format string, args ...any) {
+		merr = multierr.Append(merr, fmt.Errorf(format, args...))
+	}
+
+	codecs := make(map[codecKey]struct{})
+	for _, codec := range config.Codecs {
+		if _, ok := codecDefaults[codec.Name]; !ok {
+			fail("codecs: unknown codec '%s'", codec.Name)
+			continue
+		}
+		if err := validateProfile(codec.Name, codec.Profile); err != nil {
+			fail("codecs: %v", err)
+			continue
+		}
+		key := codecKey{name: codec.Name, profile: codec.Profile}
+		if _, exists := codecs[key]; exists {
+			fail("codecs: codec '%s' (profile %d) given more than once", codec.Name, codec.Profile)
+		}
+		codecs[key] = struct{}{}
+	}
+
+	interceptors := config.Interceptors
+	if _, ok := interceptorPresets[interceptors.preset()]; !ok {
+		fail("interceptors: unknown preset '%s'", interceptors.Preset)
+	}
+	if interceptors.Default && (interceptors.NACK || interceptors.TWCC || interceptors.RTCPReports) {
+		fail("interceptors: default already registers nack, twcc and rtcp_reports")
+	}
+	if b := interceptors.Bandwidth; b != nil {
+		if b.Min <= 0 || b.Min > b.Initial || b.Initial > b.Max {
+			fail("interceptors.bandwidth: needs 0 < min <= initial <= max")
+		}
+		if b.Interval <= 0 {
+			fail("interceptors.bandwidth: interval needs to be positive")
+		}
+		if _, err := BandwidthEstimatorFactoryByName(b.Estimator, b.Initial, b.Min, b.Max); err != nil {
+			fail("interceptors.bandwidth: %v", err)
+		}
+	}
+
+	if len(config.PeerConnections) == 0 {
+		fail("peer_connections: at least one peer connection is needed")
+	}
+
+	labels := make(map[string]struct{})
+	for i, pc := range config.PeerConnections {
+		if pc.Label == "" {
+			fail("peer_connections[%d]: label is missing", i)
+		} else if _, exists := labels[pc.Label]; exists {
+			fail("peer_connections[%d]: label '%s' used more than once", i, pc.Label)
+		}
+		labels[pc.Label] = struct{}{}
+
+		if pc.BandwidthEstimation && interceptors.Bandwidth == nil {
+			fail("peer_connections[%s]: bandwidth_estimation needs interceptors.bandwidth", pc.Label)
+		}
+		if _, err := AllocationStrategyByName(pc.BandwidthAllocation); err != nil {
+			fail("peer_connections[%s]: %v", pc.Label, err)
+		}
+		if pc.ReservedBitrate < 0 {
+			fail("peer_connections[%s]: reserved_bitrate cannot be negative", pc.Label)
+		}
+		if pc.BitrateUpdate != nil {
+			if err := pc.BitrateUpdate.validate(); err != nil {
+				fail("peer_connections[%s].bitrate_update: %v", pc.Label, err)
+			}
+		}
+		if pc.BandwidthHistory != nil && *pc.BandwidthHistory < 0 {
+			fail("peer_connections[%s]: bandwidth_history cannot be negative", pc.Label)
+		}
+
+		channels := make(map[string]struct{})
+		for _, dc := range pc.DataChannels {
+			if _, exists := channels[dc.Label]; exists || dc.Label == "" {
+				fail("peer_connections[%s].data_channels: label '%s' is missing or used more than once", pc.Label, dc.Label)
+			}
+			channels[dc.Label] = struct{}{}
+
+			if dc.MaxRetransmits != nil && dc.MaxPacketLifeTime != nil {
+				fail("peer_connections[%s].data_channels[%s]: only one of max_retransmits and max_packet_lifetime can be set", pc.Label, dc.Label)
+			}
+			if dc.Negotiated && dc.ID == nil {
+				fail("peer_connections[%s].data_channels[%s]: negotiated channels need an id", pc.Label, dc.Label)
+			}
+		}
+
+		sources := make(map[string]struct{})
+		for _, source := range pc.Sources {
+			if _, exists := sources[source.Label]; exists || source.Label == "" {
+				fail("peer_connections[%s].sources: label '%s' is missing or used more than once", pc.Label, source.Label)
+			}
+			sources[source.Label] = struct{}{}
+
+			if err := config.validateCodec(codecs, source.Codec, source.Profile); err != nil {
+				fail("peer_connections[%s].sources[%s]: %v", pc.Label, source.Label, err)
+			}
+			if source.Priority > mediasource.Level5 {
+				fail("peer_connections[%s].sources[%s]: priority needs to be between 0 and 5", pc.Label, source.Label)
+			}
+		}
+
+		sinks := make(map[string]struct{})
+		for _, sink := range pc.Sinks {
+			if _, exists := sinks[sink.Label]; exists || sink.Label == "" {
+				fail("peer_connections[%s].sinks: label '%s' is missing or used more than once", pc.Label, sink.Label)
+			}
+			sinks[sink.Label] = struct{}{}
+
+			if err := config.validateCodec(codecs, sink.Codec, sink.Profile); err != nil {
+				fail("peer_connections[%s].sinks[%s]: %v", pc.Label, sink.Label, err)
+			}
+			if jitter := sink.JitterBuffer; jitter != nil {
+				if c := jitter.config(); c.Min > c.Max {
+					fail("peer_connections[%s].sinks[%s].jitter_buffer: needs min <= max", pc.Label, sink.Label)
+				}
+			}
+		}
+	}
+
+	if err := config.Signal.validate(); err != nil {
+		merr = multierr.Append(merr, err)
+	}
+
+	return merr
+}
+
+// codecKey tells registered codecs apart; only vp9 can be registered more than once, with different
+// profiles.
+type codecKey struct {
+	name    mediasource.CodecID
+	profile uint8
+}
+
+func validateProfile(codec mediasource.CodecID, profile uint8) error {
+	if codec == mediasource.VP9CodecID {
+		if profile != 0 && profile != 2 {
+			return fmt.Errorf("vp9 profile needs to be 0 or 2, got %d", profile)
+		}
+		return nil
+	}
+
+	if profile != 0 {
+		return fmt.Errorf("codec '%s' does not take a profile", codec)
+	}
+
+	return nil
+}
+
+func (config *Config) validateCodec(registered map[codecKey]struct{}, codec mediasource.CodecID, profile uint8) error {
+	if _, ok := codecDefaults[codec]; !ok {
+		return fmt.Errorf("unknown codec '%s'", codec)
+	}
+
+	if err := validateProfile(codec, profile); err != nil {
+		return err
+	}
+
+	if _, ok := registered[codecKey{name: codec, profile: profile}]; !ok && !config.DefaultCodecs {
+		return fmt.Errorf("codec '%s' (profile %d) is not registered in codecs", codec, profile)
+	}
+
+	return nil
+}
+
+func (signal SignalConfig) validate() error {
+	if signal.Type == "" {
+		return nil
+	}
+
+	switch signal.Type {
+	case SignalTypeWebSocket:
+		if signal.URL == "" {
+			return errors.New("signal: websocket needs url")
+		}
+	case SignalTypeFile:
+		if signal.OfferDir == "" || signal.AnswerDir == "" {
+			return errors.New("signal: file needs offer_dir and answer_dir")
+		}
+		if signal.Encoding != "" && signal.Encoding != FileEncodingBase64JSON && signal.Encoding != FileEncodingPlainSDP {
+			return fmt.Errorf("signal: unknown file encoding '%s'", signal.Encoding)
+		}
+	case SignalTypeWHIP, SignalTypeWHEP:
+		if signal.Endpoint == "" {
+			return fmt.Errorf("signal: %s needs endpoint", signal.Type)
+		}
+		return nil
+	case SignalTypeFirebase:
+	default:
+		return fmt.Errorf("signal: unknown type '%s'", signal.Type)
+	}
+
+	if signal.Role != WebSocketRoleOffer && signal.Role != WebSocketRoleAnswer {
+		return fmt.Errorf("signal: role needs to be '%s' or '%s'", WebSocketRoleOffer, WebSocketRoleAnswer)
+	}
+
+	return nil
+}
+
+func (interceptors InterceptorConfig) preset() InterceptorPreset {
+	if interceptors.Preset == "" {
+		return InterceptorPresetDefault
+	}
+
+	return interceptors.Preset
+}
+
+type codecDefault struct {
+	clockRate uint32
+	channels  uint16
+}
+
+var codecDefaults = map[mediasource.CodecID]codecDefault{
+	mediasource.H264CodecID: {clockRate: 90000},
+	mediasource.VP8CodecID:  {clockRate: 90000},
+	mediasource.VP9CodecID:  {clockRate: 90000},
+	mediasource.AV1CodecID:  {clockRate: 90000},
+	mediasource.H265CodecID: {clockRate: 90000},
+	mediasource.OpusCodecID: {clockRate: 48000, channels: 2},
+}
+
+func codecParameters(codec mediasource.CodecID, clockRate uint32, channels uint16) (uint32, uint16) {
+	defaults := codecDefaults[codec]
+	if clockRate == 0 {
+		clockRate = defaults.clockRate
+	}
+	if channels == 0 {
+		channels = defaults.channels
+	}
+
+	return clockRate, channels
+}
+
+// clientOptions translates codecs and interceptors to client options, in the order they need to be
+// applied in.
+func (config *Config) clientOptions() []ClientOption {
+	var options []ClientOption
+
+	if config.DefaultCodecs {
+		options = append(options, WithDefaultMediaEngine())
+	}
+
+	for _, codec := range config.Codecs {
+		clockRate, channels := codecParameters(codec.Name, codec.ClockRate, codec.Channels)
+		switch codec.Name {
+		case mediasource.H264CodecID:
+			options = append(options, WithH264MediaEngine(clockRate))
+		case mediasource.VP8CodecID:
+			options = append(options, WithVP8MediaEngine(clockRate))
+		case mediasource.VP9CodecID:
+			options = append(options, WithVP9MediaEngine(clockRate, codec.Profile))
+		case mediasource.AV1CodecID:
+			options = append(options, WithAV1MediaEngine(clockRate))
+		case mediasource.H265CodecID:
+			options = append(options, WithH265MediaEngine(clockRate))
+		case mediasource.OpusCodecID:
+			options = append(options, WithOpusMediaEngine(clockRate, channels))
+		}
+	}
+
+	interceptors := config.Interceptors
+	preset := interceptorPresets[interceptors.preset()]
+
+	if interceptors.Default {
+		options = append(options, WithDefaultInterceptorRegistry())
+	}
+	if interceptors.NACK {
+		options = append(options, WithNACKInterceptor(preset.generator, preset.responder))
+	}
+	if interceptors.RTCPReports {
+		options = append(options, WithRTCPReportsInterceptor(preset.rtcpReport))
+	}
+	if interceptors.TWCC {
+		options = append(options, WithTWCCSenderInterceptor(preset.twcc))
+	}
+	if b := interceptors.Bandwidth; b != nil {
+		switch b.Estimator {
+		case BandwidthEstimatorStatic:
+			options = append(options, WithStaticBandwidthEstimator(b.Initial, b.Interval))
+		case BandwidthEstimatorREMB:
+			options = append(options, WithREMBBandwidthEstimator(b.Initial, b.Min, b.Max, b.Interval))
+		default:
+			// NOTE: THE CONGESTION CONTROLLER NEEDS TO BE REGISTERED BEFORE THE TWCC HEADER EXTENSION SENDER
+			options = append(options, WithBandwidthControlInterceptor(b.Initial, b.Min, b.Max, b.Interval), WithTWCCHeaderExtensionSender())
+		}
+	}
+	if interceptors.Simulcast {
+		options = append(options, WithSimulcastExtensionHeaders())
+	}
+	if config.Reconnect {
+		options = append(options, WithReconnectSupervisor(DefaultReconnectConfig, nil))
+	}
+
+	return options
+}
+
+func iceServers(servers []ICEServerConfig) []webrtc.ICEServer {
+	result := make([]webrtc.ICEServer, 0, len(servers))
+	for _, server := range servers {
+		urls := make([]string, 0, len(server.URLs))
+		for _, url := range server.URLs {
+			urls = append(urls, os.ExpandEnv(url))
+		}
+
+		iceServer := webrtc.ICEServer{URLs: urls}
+		if server.Username != "" || server.Credential != "" {
+			iceServer.Username = os.ExpandEnv(server.Username)
+			iceServer.Credential = os.ExpandEnv(server.Credential)
+			iceServer.CredentialType = webrtc.ICECredentialTypePassword
+		}
+		result = append(result, iceServer)
+	}
+
+	return result
+}
+
+// NewClientFromConfig validates the config and builds the client with all its peer connections, data
+// channels, media sources and media sinks. options are applied after the ones derived from the config.
+// The peer connections still need to be connected, e.g. with NewSignalFromConfig:
+//
+//	signal, err := NewSignalFromConfig(ctx, config.Signal)
+//	...
+//	err = client.Connect(config.Signal.Category, signal)
+func NewClientFromConfig(ctx context.Context, config *Config, options ...ClientOption) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	client, err := NewClient(ctx, nil, nil, nil, append(config.clientOptions(), options...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pcConfig := range config.PeerConnections {
+		if err := client.createPeerConnectionFromConfig(config, pcConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("error while creating peer connection '%s'; err: %w", pcConfig.Label, err)
+		}
+	}
+
+	return client, nil
+}
+
+func (c *Client) createPeerConnectionFromConfig(config *Config, pcConfig PeerConnectionConfig) error {
+	servers := config.ICEServers
+	if len(pcConfig.ICEServers) > 0 {
+		servers = pcConfig.ICEServers
+	}
+	rtcConfig := webrtc.Configuration{ICEServers: iceServers(servers)}
+
+	var (
+		pc  *PeerConnection
+		err error
+	)
+	if pcConfig.BandwidthEstimation {
+		pc, err = c.CreatePeerConnectionWithBWEstimator(pcConfig.Label, rtcConfig)
+	} else {
+		pc, err = c.CreatePeerConnection(pcConfig.Label, rtcConfig)
+	}
+	if err != nil {
+		return err
+	}
+
+	if pcConfig.BandwidthEstimation {
+		strategy, err := AllocationStrategyByName(pcConfig.BandwidthAllocation)
+		if err != nil {
+			return err
+		}
+		pc.bwc.SetAllocationStrategy(strategy)
+		if err := pc.bwc.SetReservedBitrate(pcConfig.ReservedBitrate); err != nil {
+			return err
+		}
+		if pcConfig.BitrateUpdate != nil {
+			if err := pc.bwc.SetUpdateConfig(*pcConfig.BitrateUpdate); err != nil {
+				return err
+			}
+		}
+		if pcConfig.BandwidthHistory != nil {
+			if err := pc.bwc.SetHistorySize(*pcConfig.BandwidthHistory); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, dc := range pcConfig.DataChannels {
+		if _, err := pc.CreateDataChannel(dc.Label, datachannel.WithDataChannelInit(dc.dataChannelInit())); err != nil {
+			return fmt.Errorf("data channel '%s'; err: %w", dc.Label, err)
+		}
+	}
+
+	for _, source := range pcConfig.Sources {
+		options := []mediasource.TrackOption{sourceCodecOption(source), mediasource.WithPriority(source.Priority)}
+		if source.RTP {
+			_, err = pc.CreateRTPMediaSource(source.Label, options...)
+		} else {
+			_, err = pc.CreateMediaSource(source.Label, options...)
+		}
+		if err != nil {
+			return fmt.Errorf("media source '%s'; err: %w", source.Label, err)
+		}
+	}
+
+	for _, sink := range pcConfig.Sinks {
+		if _, err := pc.CreateMediaSink(sink.Label, append([]mediasink.SinkOption{sinkCodecOption(sink)}, sink.options()...)...); err != nil {
+			return fmt.Errorf("media sink '%s'; err: %w", sink.Label, err)
+		}
+	}
+
+	if pcConfig.AutoSinks {
+		if err := pc.SetAutoSinkFactory(mediasink.AutoSinkByTrackID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (sink SinkConfig) options() []mediasink.SinkOption {
+	var options []mediasink.SinkOption
+
+	if sink.TrackID != "" {
+		options = append(options, mediasink.WithTrackID(sink.TrackID))
+	}
+	if sink.StreamID != "" {
+		options = append(options, mediasink.WithStreamID(sink.StreamID))
+	}
+	if sink.MID != "" {
+		options = append(options, mediasink.WithMID(sink.MID))
+	}
+	if sink.RID != "" {
+		options = append(options, mediasink.WithRID(sink.RID))
+	}
+	if sink.JitterBuffer != nil {
+		options = append(options, mediasink.WithJitterBuffer(sink.JitterBuffer.config()))
+	}
+	if sink.AutoKeyframe {
+		options = append(options, mediasink.WithAutoKeyframeRequest())
+	}
+	if sink.KeyframeInterval > 0 {
+		options = append(options, mediasink.WithKeyframeRequestInterval(sink.KeyframeInterval))
+	}
+
+	return options
+}
+
+func (jitter JitterBufferConfig) config() mediasink.JitterBufferConfig {
+	config := mediasink.DefaultJitterBufferConfig
+	if jitter.Target > 0 {
+		config.Target = jitter.Target
+	}
+	if jitter.Min > 0 {
+		config.Min = jitter.Min
+	}
+	if jitter.Max > 0 {
+		config.Max = jitter.Max
+	}
+
+	return config
+}
+
+func (dc DataChannelConfig) dataChannelInit() *webrtc.DataChannelInit {
+	ordered := true
+	if dc.Ordered != nil {
+		ordered = *dc.Ordered
+	}
+
+	init := &webrtc.DataChannelInit{
+		Ordered:           &ordered,
+		MaxRetransmits:    dc.MaxRetransmits,
+		MaxPacketLifeTime: dc.MaxPacketLifeTime,
+		ID:                dc.ID,
+	}
+	if dc.Protocol != "" {
+		init.Protocol = &dc.Protocol
+	}
+	if dc.Negotiated {
+		init.Negotiated = &dc.Negotiated
+	}
+
+	return init
+}
+
+func sourceCodecOption(source SourceConfig) mediasource.TrackOption {
+	clockRate, channels := codecParameters(source.Codec, source.ClockRate, source.Channels)
+	switch source.Codec {
+	case mediasource.H264CodecID:
+		return mediasource.WithH264Track(clockRate)
+	case mediasource.VP8CodecID:
+		return mediasource.WithVP8Track(clockRate)
+	case mediasource.VP9CodecID:
+		return mediasource.WithVP9Track(clockRate, source.Profile)
+	case mediasource.AV1CodecID:
+		return mediasource.WithAV1Track(clockRate)
+	case mediasource.H265CodecID:
+		return mediasource.WithH265Track(clockRate)
+	default:
+		return mediasource.WithOpusTrack(clockRate, channels)
+	}
+}
+
+func sinkCodecOption(sink SinkConfig) mediasink.SinkOption {
+	clockRate, channels := codecParameters(sink.Codec, sink.ClockRate, sink.Channels)
+	switch sink.Codec {
+	case mediasource.H264CodecID:
+		return mediasink.WithH264Track(clockRate)
+	case mediasource.VP8CodecID:
+		return mediasink.WithVP8Track(clockRate)
+	case mediasource.VP9CodecID:
+		return mediasink.WithVP9Track(clockRate, sink.Profile)
+	case mediasource.AV1CodecID:
+		return mediasink.WithAV1Track(clockRate)
+	case mediasource.H265CodecID:
+		return mediasink.WithH265Track(clockRate)
+	default:
+		return mediasink.WithOpusTrack(clockRate, channels)
+	}
+}
+
+// NewSignalFromConfig builds the signal described by the config.
+func NewSignalFromConfig(ctx context.Context, config SignalConfig) (BaseSignal, error) {
+	if config.Type == "" {
+		return nil, errors.New("signal: type is missing")
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	var options []SignalOption
+	if config.Trickle {
+		options = append(options, WithTrickleICE())
+	}
+	if config.Encoding != "" {
+		options = append(options, WithFileEncoding(config.Encoding))
+	}
+
+	offer := config.Role == WebSocketRoleOffer
+
+	switch config.Type {
+	case SignalTypeWebSocket:
+		if offer {
+			return CreateWebSocketOfferSignal(ctx, os.ExpandEnv(config.URL), options...), nil
+		}
+		return CreateWebSocketAnswerSignal(ctx, os.ExpandEnv(config.URL), options...), nil
+	case SignalTypeFile:
+		if offer {
+			return CreateFileOfferSignal(ctx, config.OfferDir, config.AnswerDir, options...), nil
+		}
+		return CreateFileAnswerSignal(ctx, config.OfferDir, config.AnswerDir, options...), nil
+	case SignalTypeWHIP:
+		return CreateWHIPSignal(ctx, os.ExpandEnv(config.Endpoint), os.ExpandEnv(config.Token), options...), nil
+	case SignalTypeWHEP:
+		return CreateWHEPSignal(ctx, os.ExpandEnv(config.Endpoint), os.ExpandEnv(config.Token), options...), nil
+	default:
+		if offer {
+			signal, err := CreateFirebaseOfferSignal(ctx, options...)
+			if err != nil {
+				return nil, err
+			}
+			return signal, nil
+		}
+		signal, err := CreateFirebaseAnswerSignal(ctx, options...)
+		if err != nil {
+			return nil, err
+		}
+		return signal, nil
+	}
+}
@@ -0,0 +1,202 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4/pkg/media"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected string
+	}{
+		{"minimal", "peer_connections: [{label: a}]", ""},
+		{"json", `{"codecs": [{"name": "vp8"}], "peer_connections": [{"label": "a", "sources": [{"label": "cam", "codec": "vp8"}]}]}`, ""},
+		{"unknown field", "peer_connections: [{label: a, lable: b}]", "field lable not found"},
+		{"unknown top level field", "peer_connection: [{label: a}]", "field peer_connection not found"},
+		{"missing peer connections", "codecs: [{name: vp8}]", "at least one peer connection is needed"},
+		{"missing peer connection label", "peer_connections: [{auto_sinks: true}]", "peer_connections[0]: label is missing"},
+		{"duplicate peer connection label", "peer_connections: [{label: a}, {label: a}]", "label 'a' used more than once"},
+		{"missing data channel label", "peer_connections: [{label: a, data_channels: [{protocol: x}]}]", "label '' is missing or used more than once"},
+		{"missing source label", "codecs: [{name: vp8}]\npeer_connections: [{label: a, sources: [{codec: vp8}]}]", "sources: label '' is missing"},
+		{"missing websocket url", "peer_connections: [{label: a}]\nsignal: {type: websocket, role: offer}", "websocket needs url"},
+		{"missing file directories", "peer_connections: [{label: a}]\nsignal: {type: file, role: offer, offer_dir: x}", "file needs offer_dir and answer_dir"},
+		{"missing whip endpoint", "peer_connections: [{label: a}]\nsignal: {type: whip}", "whip needs endpoint"},
+		{"missing signal role", "peer_connections: [{label: a}]\nsignal: {type: websocket, url: ws://localhost}", "role needs to be"},
+		{"unknown signal type", "peer_connections: [{label: a}]\nsignal: {type: carrier-pigeon}", "unknown type 'carrier-pigeon'"},
+		{"unknown codec", "codecs: [{name: mpeg2}]\npeer_connections: [{label: a}]", "unknown codec 'mpeg2'"},
+		{"duplicate codec", "codecs: [{name: vp8}, {name: vp8}]\npeer_connections: [{label: a}]", "given more than once"},
+		{"invalid vp9 profile", "codecs: [{name: vp9, profile: 1}]\npeer_connections: [{label: a}]", "vp9 profile needs to be 0 or 2"},
+		{"profile of another codec", "codecs: [{name: h264, profile: 2}]\npeer_connections: [{label: a}]", "does not take a profile"},
+		{"unregistered source codec", "codecs: [{name: vp8}]\npeer_connections: [{label: a, sources: [{label: cam, codec: h264}]}]", "is not registered in codecs"},
+		{"unregistered codec with default codecs", "default_codecs: true\npeer_connections: [{label: a, sources: [{label: cam, codec: h264}]}]", ""},
+		{"source priority", "codecs: [{name: vp8}]\npeer_connections: [{label: a, sources: [{label: cam, codec: vp8, priority: 6}]}]", "priority needs to be between 0 and 5"},
+		{"unknown preset", "interceptors: {preset: fastest}\npeer_connections: [{label: a}]", "unknown preset 'fastest'"},
+		{"default with nack", "interceptors: {default: true, nack: true}\npeer_connections: [{label: a}]", "default already registers"},
+		{"bandwidth bounds", "interceptors: {bandwidth: {initial: 10, min: 100, max: 1000, interval: 100ms}}\npeer_connections: [{label: a}]", "needs 0 < min <= initial <= max"},
+		{"bandwidth interval", "interceptors: {bandwidth: {initial: 100, min: 10, max: 1000}}\npeer_connections: [{label: a}]", "interval needs to be positive"},
+		{"estimation without bandwidth", "peer_connections: [{label: a, bandwidth_estimation: true}]", "bandwidth_estimation needs interceptors.bandwidth"},
+		{"negative reserved bitrate", "peer_connections: [{label: a, reserved_bitrate: -1}]", "reserved_bitrate cannot be negative"},
+		{"negotiated without id", "peer_connections: [{label: a, data_channels: [{label: dc, negotiated: true}]}]", "negotiated channels need an id"},
+		{"retransmits and lifetime", "peer_connections: [{label: a, data_channels: [{label: dc, max_retransmits: 1, max_packet_lifetime: 1}]}]", "only one of max_retransmits and max_packet_lifetime"},
+		{"jitter buffer bounds", "codecs: [{name: vp8}]\npeer_connections: [{label: a, sinks: [{label: s, codec: vp8, jitter_buffer: {min: 2s, max: 1s}}]}]", "needs min <= max"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := ParseConfig([]byte(test.config))
+			if test.expected == "" {
+				if err != nil {
+					t.Fatalf("expected the config to be valid, got %v", err)
+				}
+				if config == nil {
+					t.Fatal("expected a config")
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected an error containing '%s'", test.expected)
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Fatalf("expected an error containing '%s', got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseConfigEmpty(t *testing.T) {
+	for _, config := range []string{"", "\n", "# nothing configured yet\n"} {
+		if _, err := ParseConfig([]byte(config)); !errors.Is(err, ErrEmptyConfig) {
+			t.Fatalf("expected ErrEmptyConfig for %q, got %v", config, err)
+		}
+	}
+}
+
+func TestConfigValidateReportsEveryProblem(t *testing.T) {
+	config := &Config{
+		Codecs:          []CodecConfig{{Name: "mpeg2"}},
+		PeerConnections: []PeerConnectionConfig{{Label: "a", ReservedBitrate: -1}, {}},
+		Signal:          SignalConfig{Type: SignalTypeWebSocket, Role: WebSocketRoleOffer},
+	}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("expected the config to be invalid")
+	}
+
+	for _, expected := range []string{"unknown codec 'mpeg2'", "reserved_bitrate cannot be negative", "peer_connections[1]: label is missing", "websocket needs url"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected the error to contain '%s', got %v", expected, err)
+		}
+	}
+}
+
+func TestNewClientFromConfigRejectsInvalidConfig(t *testing.T) {
+	if _, err := NewClientFromConfig(context.Background(), &Config{}); err == nil {
+		t.Fatal("expected a config without peer connections to be rejected")
+	}
+}
+
+// withLoopbackSettings replaces the setting engine of a client built from a config, which has no setting
+// engine of its own.
+func withLoopbackSettings() ClientOption {
+	return func(c *Client) error {
+		c.settingsEngine = loopbackSettings()
+		c.settingsEngine.DetachDataChannels()
+		return nil
+	}
+}
+
+func TestNewClientFromConfigConnects(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	offerConfig, err := ParseConfig([]byte(`
+codecs: [{name: vp8}]
+interceptors: {nack: true, rtcp_reports: true}
+peer_connections:
+  - label: drone
+    data_channels: [{label: mavlink}]
+    sources: [{label: camera, codec: vp8, priority: 5}]
+signal: {type: websocket, role: offer, category: fleet, url: ws://localhost:8080/signal}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	answerConfig, err := ParseConfig([]byte(`
+codecs: [{name: vp8}]
+interceptors: {nack: true, rtcp_reports: true}
+peer_connections:
+  - label: drone
+    sinks: [{label: camera, codec: vp8}]
+signal: {type: websocket, role: answer, category: fleet, url: ws://localhost:8080/signal}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := NewClientFromConfig(ctx, offerConfig, withLoopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offer.Close()
+
+	answer, err := NewClientFromConfig(ctx, answerConfig, withLoopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer answer.Close()
+
+	offerPC, err := offer.GetPeerConnection("drone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := offerPC.GetDataChannel("mavlink"); err != nil {
+		t.Fatal(err)
+	}
+	source, err := offerPC.GetMediaSource("camera")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	answerPC, err := answer.GetPeerConnection("drone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := answerPC.GetMediaSink("camera")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offerSignal, answerSignal := NewLoopbackSignals(ctx)
+	defer offerSignal.Close()
+	defer answerSignal.Close()
+
+	connectSignals(t, offer, answer, offerSignal, answerSignal)
+
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = source.WriteSample(media.Sample{Data: []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a}, Duration: 20 * time.Millisecond})
+			}
+		}
+	}()
+
+	if _, _, err := sink.ReadRTP(ctx); err != nil {
+		t.Fatalf("no media reached the sink built from the config: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/webrtc/v4"
+)
+
+var ErrNoBandwidthEstimator = errors.New("no bandwidth estimator was created for the peer connection; is WithBandwidthControlInterceptor set?")
+
+type estimator struct {
+	e        cc.BandwidthEstimator
+	interval time.Duration
+}
+
+// estimatorHandoff hands the bandwidth estimator, which pion's cc interceptor creates while
+// api.NewPeerConnection builds the interceptors, to the peer connection it was created for. Pion builds
+// interceptors with an empty ID, so every peer connection is built in the one build slot with an ID of
+// its own in building, and the estimator is filed under that ID. Pion calls back before
+// api.NewPeerConnection returns, so once build returned the estimator is either filed or never comes.
+type estimatorHandoff struct {
+	// slot is taken by the peer connection being built; a channel, so that waiting for it can be cancelled
+	slot     chan struct{}
+	building string
+	sequence uint64
+	// expected holds an entry for every peer connection that takes its estimator; nil till it arrives
+	expected map[string]*estimator
+	mux      sync.Mutex
+}
+
+func newEstimatorHandoff() *estimatorHandoff {
+	return &estimatorHandoff{
+		slot:     make(chan struct{}, 1),
+		expected: make(map[string]*estimator),
+	}
+}
+
+// build creates a peer connection with api and returns it with the ID its estimator is filed under. If
+// expect is false, an estimator created for it is dropped. It waits for other peer connections being
+// built till ctx is done.
+func (h *estimatorHandoff) build(ctx context.Context, label string, expect bool, api *webrtc.API, config webrtc.Configuration) (string, *webrtc.PeerConnection, error) {
+	if h == nil {
+		peerConnection, err := api.NewPeerConnection(config)
+		return "", peerConnection, err
+	}
+
+	select {
+	case h.slot <- struct{}{}:
+	case <-ctx.Done():
+		return "", nil, fmt.Errorf("error while waiting to build peer connection (pc=%s); err: %w", label, ctx.Err())
+	}
+	defer func() {
+		<-h.slot
+	}()
+
+	h.mux.Lock()
+	h.sequence++
+	id := fmt.Sprintf("%s-%d", label, h.sequence)
+	h.building = id
+	if expect {
+		h.expected[id] = nil
+	}
+	h.mux.Unlock()
+
+	peerConnection, err := api.NewPeerConnection(config)
+
+	h.mux.Lock()
+	h.building = ""
+	if err != nil {
+		delete(h.expected, id)
+	}
+	h.mux.Unlock()
+
+	return id, peerConnection, err
+}
+
+// add files the estimator of the peer connection being built. Pion passes an empty id; a non-empty one
+// is used as is.
+func (h *estimatorHandoff) add(id string, e estimator) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if id == "" {
+		id = h.building
+	}
+
+	if _, ok := h.expected[id]; !ok {
+		// NOTE: THE PEER CONNECTION WAS CREATED WITHOUT BANDWIDTH ESTIMATION
+		return
+	}
+
+	h.expected[id] = &e
+}
+
+// take returns the estimator filed under id and forgets it. It does not wait: build returns only after
+// pion created the estimator, if it creates one at all.
+func (h *estimatorHandoff) take(id string) (estimator, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	e, ok := h.expected[id]
+	delete(h.expected, id)
+
+	if !ok || e == nil {
+		return estimator{}, ErrNoBandwidthEstimator
+	}
+
+	return *e, nil
+}
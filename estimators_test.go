@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/interceptor/pkg/cc"
+	"github.com/pion/webrtc/v4"
+)
+
+func TestEstimatorHandoffFilesEstimatorsByID(t *testing.T) {
+	h := newEstimatorHandoff()
+	h.expected["first-1"] = nil
+	h.expected["second-2"] = nil
+
+	first, second := NewStaticBandwidthEstimator(1), NewStaticBandwidthEstimator(2)
+	h.add("second-2", estimator{e: second, interval: time.Second})
+	h.add("first-1", estimator{e: first, interval: time.Second})
+	// NOTE: NOBODY EXPECTS AN ESTIMATOR FOR A PEER CONNECTION CREATED WITHOUT BANDWIDTH ESTIMATION
+	h.add("third-3", estimator{e: NewStaticBandwidthEstimator(3)})
+
+	for id, expected := range map[string]cc.BandwidthEstimator{"first-1": first, "second-2": second} {
+		e, err := h.take(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.e != expected || e.interval != time.Second {
+			t.Fatalf("expected %s to get its own estimator, got the one of %d bps", id, e.e.GetTargetBitrate())
+		}
+	}
+
+	for _, id := range []string{"first-1", "third-3"} {
+		if _, err := h.take(id); !errors.Is(err, ErrNoBandwidthEstimator) {
+			t.Fatalf("expected ErrNoBandwidthEstimator for %s, got %v", id, err)
+		}
+	}
+	if len(h.expected) != 0 {
+		t.Fatalf("expected every estimator to be forgotten, got %d left", len(h.expected))
+	}
+}
+
+func TestEstimatorHandoffBuildStopsWithItsContext(t *testing.T) {
+	h := newEstimatorHandoff()
+
+	// NOTE: ANOTHER PEER CONNECTION IS BEING BUILT
+	h.slot <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := h.build(ctx, "pc", true, webrtc.NewAPI(), webrtc.Configuration{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the build to stop waiting with its context, got %v", err)
+	}
+	if len(h.expected) != 0 {
+		t.Fatal("expected the peer connection that was never built not to expect an estimator")
+	}
+}
+
+func TestConcurrentPeerConnectionsGetTheirOwnEstimator(t *testing.T) {
+	const count = 8
+
+	var created atomic.Int64
+	factory := func() (cc.BandwidthEstimator, error) {
+		return NewStaticBandwidthEstimator(created.Add(1)), nil
+	}
+
+	c, err := NewClient(context.Background(), nil, nil, loopbackSettings(), WithBandwidthEstimatorInterceptor(factory, time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var (
+		wg  sync.WaitGroup
+		pcs = make([]*PeerConnection, count)
+	)
+	for i := range pcs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			pc, err := c.CreatePeerConnectionWithBWEstimator(fmt.Sprintf("pc-%d", i), webrtc.Configuration{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			pcs[i] = pc
+		}()
+	}
+
+	// NOTE: A PEER CONNECTION WITHOUT BANDWIDTH ESTIMATION DROPS THE ESTIMATOR CREATED FOR IT
+	if _, err := c.CreatePeerConnection("plain", webrtc.Configuration{}); err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	seen := make(map[cc.BandwidthEstimator]string)
+	for _, pc := range pcs {
+		e := pc.bwc.get()
+		if e == nil {
+			t.Fatalf("expected %s to have an estimator", pc.GetLabel())
+		}
+		if other, exists := seen[e]; exists {
+			t.Fatalf("expected %s and %s to have estimators of their own", pc.GetLabel(), other)
+		}
+		seen[e] = pc.GetLabel()
+	}
+
+	// NOTE: A RECREATED PEER CONNECTION GETS THE ESTIMATOR OF ITS NEW CONNECTION
+	previous := pcs[0].bwc.get()
+	if err := c.recreatePeerConnection(pcs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if e := pcs[0].bwc.get(); e == previous || e == nil {
+		t.Fatal("expected the recreated peer connection to get a new estimator")
+	}
+
+	c.estimators.mux.Lock()
+	defer c.estimators.mux.Unlock()
+	if len(c.estimators.expected) != 0 {
+		t.Fatalf("expected no peer connection to wait for an estimator, got %d", len(c.estimators.expected))
+	}
+}
+
+func TestCreatePeerConnectionWithBWEstimatorWithoutInterceptor(t *testing.T) {
+	c, err := NewClient(context.Background(), nil, nil, loopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// NOTE: PION CREATES ESTIMATORS WHILE BUILDING THE PEER CONNECTION, SO THERE IS NOTHING TO WAIT FOR
+	if _, err := c.CreatePeerConnectionWithBWEstimatorContext(ctx, "pc", webrtc.Configuration{}); !errors.Is(err, ErrNoBandwidthEstimator) {
+		t.Fatalf("expected ErrNoBandwidthEstimator, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("expected the missing estimator to be reported right away")
+	}
+
+	// NOTE: THE PEER CONNECTION IS CLOSED AND ITS LABEL FREED
+	if _, err := c.GetPeerConnection("pc"); err == nil {
+		t.Fatal("expected the peer connection without an estimator to be removed")
+	}
+}
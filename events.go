@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+)
+
+type EventType string
+
+const (
+	EventConnectionState       EventType = "connection-state"
+	EventICEConnectionState    EventType = "ice-connection-state"
+	EventICEGatheringState     EventType = "ice-gathering-state"
+	EventLocalCandidate        EventType = "local-candidate"
+	EventSelectedCandidatePair EventType = "selected-candidate-pair"
+	EventDataChannelOpen       EventType = "datachannel-open"
+	EventDataChannelClose      EventType = "datachannel-close"
+	EventTrack                 EventType = "track"         // a remote track got attached to its sink
+	EventTrackIgnored          EventType = "track-ignored" // a remote track arrived without a sink for it
+	EventCodecMismatch         EventType = "codec-mismatch"
+)
+
+// EventBufferSize is how many events a subscriber can fall behind before further events are dropped
+// for it.
+const EventBufferSize = 64
+
+// Event is something that happened to a peer connection. Type tells which of the other fields are set:
+//   - EventConnectionState: ConnectionState
+//   - EventICEConnectionState: ICEConnectionState
+//   - EventICEGatheringState: ICEGatheringState
+//   - EventLocalCandidate: Candidate
+//   - EventSelectedCandidatePair: CandidatePair
+//   - EventDataChannelOpen, EventDataChannelClose: DataChannel
+//   - EventTrack: Track, Sink and SinkCodec
+//   - EventCodecMismatch: Track, Sink, SinkCodec and Err, telling why the codecs did not match
+//   - EventTrackIgnored: Track
+type Event struct {
+	Type           EventType
+	PeerConnection string
+	Time           time.Time
+
+	ConnectionState    webrtc.PeerConnectionState
+	ICEConnectionState webrtc.ICEConnectionState
+	ICEGatheringState  webrtc.ICEGatheringState
+	Candidate          *webrtc.ICECandidate
+	CandidatePair      *webrtc.ICECandidatePair
+	DataChannel        string
+	Track              *webrtc.TrackRemote
+	Sink               string
+	SinkCodec          *webrtc.RTPCodecParameters
+	Err                error
+}
+
+// eventBus fans every event out to all subscribers without ever blocking the publisher; a subscriber
+// which does not keep up misses events instead of stalling pion's callbacks.
+type eventBus struct {
+	subs map[chan Event]struct{}
+	mux  sync.RWMutex
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subs: make(map[chan Event]struct{})}
+}
+
+// subscribe returns a channel receiving every event published from now on. It is closed once ctx or
+// done is done.
+func (b *eventBus) subscribe(ctx context.Context, done <-chan struct{}) <-chan Event {
+	events := make(chan Event, EventBufferSize)
+
+	b.mux.Lock()
+	b.subs[events] = struct{}{}
+	b.mux.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+
+		b.mux.Lock()
+		delete(b.subs, events)
+		close(events)
+		b.mux.Unlock()
+	}()
+
+	return events
+}
+
+func (b *eventBus) publish(event Event) {
+	b.mux.RLock()
+	defer b.mux.RUnlock()
+
+	for events := range b.subs {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// handleEvents calls handler with every event of the subscription, one at a time, on its own goroutine.
+func handleEvents(events <-chan Event, handler func(Event)) {
+	go func() {
+		for event := range events {
+			handler(event)
+		}
+	}()
+}
+
+// Events returns a channel receiving the events of this peer connection. The channel is closed when ctx
+// is done or the peer connection is closed.
+func (pc *PeerConnection) Events(ctx context.Context) <-chan Event {
+	return pc.events.subscribe(ctx, pc.ctx.Done())
+}
+
+// OnEvent calls handler with every event of this peer connection till ctx is done or the peer connection
+// is closed. Events are delivered in order on a separate goroutine.
+func (pc *PeerConnection) OnEvent(ctx context.Context, handler func(Event)) {
+	handleEvents(pc.Events(ctx), handler)
+}
+
+// Events returns a channel receiving the events of every peer connection of the client, including ones
+// created later. The channel is closed when ctx or the client's context is done.
+func (c *Client) Events(ctx context.Context) <-chan Event {
+	return c.events.subscribe(ctx, c.ctx.Done())
+}
+
+// OnEvent calls handler with the events of every peer connection of the client; see Events.
+func (c *Client) OnEvent(ctx context.Context, handler func(Event)) {
+	handleEvents(c.Events(ctx), handler)
+}
+
+func (pc *PeerConnection) emit(event Event) {
+	event.PeerConnection = pc.label
+	event.Time = time.Now()
+
+	pc.events.publish(event)
+	if pc.clientEvents != nil {
+		pc.clientEvents.publish(event)
+	}
+}
+
+func (pc *PeerConnection) onSelectedCandidatePairChange() *PeerConnection {
+	pc.GetPeerConnection().SCTP().Transport().ICETransport().OnSelectedCandidatePairChange(func(pair *webrtc.ICECandidatePair) {
+		pc.logger.Info("selected candidate pair changed", "pair", pair.String())
+		pc.emit(Event{Type: EventSelectedCandidatePair, CandidatePair: pair})
+	})
+	return pc
+}
+
+func (pc *PeerConnection) onDataChannelState(label string, state webrtc.DataChannelState) {
+	switch state {
+	case webrtc.DataChannelStateOpen:
+		pc.emit(Event{Type: EventDataChannelOpen, DataChannel: label})
+	case webrtc.DataChannelStateClosed:
+		pc.emit(Event{Type: EventDataChannelClose, DataChannel: label})
+	}
+}
+
+func (pc *PeerConnection) onSinkTrack(event mediasink.TrackEvent) {
+	switch {
+	case event.Matched:
+		pc.emit(Event{Type: EventTrack, Track: event.Track, Sink: event.Sink, SinkCodec: event.Codec})
+	case event.Sink == "":
+		pc.emit(Event{Type: EventTrackIgnored, Track: event.Track})
+	default:
+		pc.emit(Event{Type: EventCodecMismatch, Track: event.Track, Sink: event.Sink, SinkCodec: event.Codec, Err: event.Err})
+	}
+}
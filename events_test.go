@@ -0,0 +1,240 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+// assertClosed drains events till the channel is closed.
+func assertClosed(t *testing.T, events <-chan Event) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected the event channel to be closed")
+		}
+	}
+}
+
+// awaitEvent reads events till one matches.
+func awaitEvent(ctx context.Context, t *testing.T, events <-chan Event, match func(Event) bool) Event {
+	t.Helper()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("the event channel closed before the event arrived")
+			}
+			if match(event) {
+				return event
+			}
+		case <-ctx.Done():
+			t.Fatal("the event did not arrive")
+		}
+	}
+}
+
+func TestEventBusFansOutToEverySubscriber(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := newEventBus()
+	first := bus.subscribe(ctx, nil)
+	second := bus.subscribe(ctx, nil)
+
+	bus.publish(Event{Type: EventDataChannelOpen, DataChannel: "control"})
+
+	for _, events := range []<-chan Event{first, second} {
+		select {
+		case event := <-events:
+			if event.Type != EventDataChannelOpen || event.DataChannel != "control" {
+				t.Fatalf("expected the published event, got %+v", event)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected every subscriber to receive the event")
+		}
+	}
+}
+
+func TestEventBusDropsEventsOfSlowSubscribers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := newEventBus()
+	slow := bus.subscribe(ctx, nil)
+
+	// NOTE: NOBODY READS, SO PUBLISHING WOULD HANG IF IT BLOCKED
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < 2*EventBufferSize; i++ {
+			bus.publish(Event{Type: EventLocalCandidate})
+		}
+		close(published)
+	}()
+
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("expected publishing to never block on a slow subscriber")
+	}
+
+	if len(slow) != EventBufferSize {
+		t.Fatalf("expected the slow subscriber to keep %d events, got %d", EventBufferSize, len(slow))
+	}
+
+	fast := bus.subscribe(ctx, nil)
+	bus.publish(Event{Type: EventConnectionState})
+	if event := <-fast; event.Type != EventConnectionState {
+		t.Fatalf("expected a later subscriber to receive later events, got %+v", event)
+	}
+}
+
+func TestEventBusClosesSubscriptions(t *testing.T) {
+	bus := newEventBus()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	byContext := bus.subscribe(ctx, nil)
+
+	done := make(chan struct{})
+	byDone := bus.subscribe(context.Background(), done)
+
+	cancel()
+	assertClosed(t, byContext)
+
+	close(done)
+	assertClosed(t, byDone)
+
+	// NOTE: PUBLISHING AFTER THE SUBSCRIBERS LEFT MUST NOT SEND ON THEIR CLOSED CHANNELS
+	bus.publish(Event{Type: EventConnectionState})
+
+	bus.mux.RLock()
+	defer bus.mux.RUnlock()
+	if len(bus.subs) != 0 {
+		t.Fatalf("expected no subscribers to be left, got %d", len(bus.subs))
+	}
+}
+
+func TestPeerConnectionAndClientEvents(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	offer, err := NewClient(ctx, nil, nil, loopbackSettings(), WithDefaultMediaEngine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offer.Close()
+
+	answer, err := NewClient(ctx, nil, nil, loopbackSettings(), WithDefaultMediaEngine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer answer.Close()
+
+	// NOTE: THE CLIENT STREAM INCLUDES PEER CONNECTIONS CREATED AFTER SUBSCRIBING
+	clientEvents := answer.Events(ctx)
+
+	offerPC, err := offer.CreatePeerConnection("media", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	answerPC, err := answer.CreatePeerConnection("media", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcEvents := answerPC.Events(ctx)
+	handled := make(chan Event, EventBufferSize)
+	answerPC.OnEvent(ctx, func(event Event) {
+		select {
+		case handled <- event:
+		default:
+		}
+	})
+
+	var sources []*mediasource.Track
+	for _, label := range []string{"video", "unrouted"} {
+		source, err := offerPC.CreateMediaSource(label, mediasource.WithVP8Track(90000))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources = append(sources, source)
+	}
+	if _, err := answerPC.CreateMediaSink("video", mediasink.WithVP8Track(90000)); err != nil {
+		t.Fatal(err)
+	}
+
+	offerSignal, answerSignal := NewLoopbackSignals(ctx)
+	defer offerSignal.Close()
+	defer answerSignal.Close()
+
+	connectSignals(t, offer, answer, offerSignal, answerSignal)
+
+	// NOTE: A REMOTE TRACK ONLY ARRIVES WITH ITS FIRST PACKET
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, source := range sources {
+					_ = source.WriteSample(media.Sample{Data: []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a}, Duration: 20 * time.Millisecond})
+				}
+			}
+		}
+	}()
+
+	connected := func(event Event) bool {
+		return event.Type == EventConnectionState && event.ConnectionState == webrtc.PeerConnectionStateConnected
+	}
+	for _, events := range []<-chan Event{clientEvents, pcEvents, handled} {
+		if event := awaitEvent(ctx, t, events, connected); event.PeerConnection != "media" || event.Time.IsZero() {
+			t.Fatalf("expected the event to name its peer connection and time, got %+v", event)
+		}
+	}
+
+	// NOTE: THE TRACK EVENTS MAY COME IN EITHER ORDER
+	var track, ignored *Event
+	for track == nil || ignored == nil {
+		event := awaitEvent(ctx, t, clientEvents, func(event Event) bool {
+			return event.Type == EventTrack || event.Type == EventTrackIgnored || event.Type == EventCodecMismatch
+		})
+
+		switch event.Type {
+		case EventTrack:
+			track = &event
+		case EventTrackIgnored:
+			ignored = &event
+		default:
+			t.Fatalf("expected no codec mismatch, got %v", event.Err)
+		}
+	}
+
+	if track.Sink != "video" || track.Track.ID() != "video" || track.SinkCodec == nil || track.SinkCodec.MimeType != webrtc.MimeTypeVP8 {
+		t.Fatalf("expected the video track to be attached to its sink, got %+v", track)
+	}
+	if ignored.Track.ID() != "unrouted" || ignored.Sink != "" {
+		t.Fatalf("expected the track without a sink to be ignored, got %+v", ignored)
+	}
+
+	if err := answerPC.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertClosed(t, pcEvents)
+}
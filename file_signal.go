@@ -0,0 +1,325 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pion/webrtc/v4"
+)
+
+// FileEncoding is how the file signals write SDP files. Reading detects the encoding, so both sides do
+// not need to agree on it.
+type FileEncoding string
+
+const (
+	// FileEncodingBase64JSON writes the webrtc.SessionDescription as base64 encoded JSON (default)
+	FileEncodingBase64JSON FileEncoding = "base64-json"
+	// FileEncodingPlainSDP writes the bare SDP, which is easier to inspect and to produce by other tools
+	FileEncodingPlainSDP FileEncoding = "sdp"
+)
+
+const (
+	fileOfferName           = "offer.txt"
+	fileAnswerName          = "answer.txt"
+	fileOfferCandidatesDir  = "offer-candidates"
+	fileAnswerCandidatesDir = "answer-candidates"
+)
+
+// WithFileEncoding sets the encoding the file signals write SDP files with.
+func WithFileEncoding(encoding FileEncoding) SignalOption {
+	return func(config *signalConfig) {
+		config.encoding = encoding
+	}
+}
+
+// filePaths is where a single peer connection's files live: <dir>/<category>/<label>/...
+type filePaths struct {
+	offer            string
+	answer           string
+	offerCandidates  string
+	answerCandidates string
+}
+
+func newFilePaths(offerDir, answerDir, category, label string) filePaths {
+	offerDir = filepath.Join(offerDir, category, label)
+	answerDir = filepath.Join(answerDir, category, label)
+
+	return filePaths{
+		offer:            filepath.Join(offerDir, fileOfferName),
+		answer:           filepath.Join(answerDir, fileAnswerName),
+		offerCandidates:  filepath.Join(offerDir, fileOfferCandidatesDir),
+		answerCandidates: filepath.Join(answerDir, fileAnswerCandidatesDir),
+	}
+}
+
+// writeFileAtomic writes data to a hidden temporary file in the same directory and renames it in place,
+// so that readers never see a partially written file.
+func writeFileAtomic(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", dir, err)
+	}
+
+	file, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(file.Name())
+	}()
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filename)
+}
+
+func encodeSDP(sdp *webrtc.SessionDescription, encoding FileEncoding) ([]byte, error) {
+	switch encoding {
+	case FileEncodingPlainSDP:
+		return []byte(sdp.SDP), nil
+	case FileEncodingBase64JSON, "":
+		b, err := json.Marshal(sdp)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(base64.StdEncoding.EncodeToString(b)), nil
+	default:
+		return nil, fmt.Errorf("unknown file encoding '%s'", encoding)
+	}
+}
+
+// decodeSDP reads either encoding; a plain SDP always starts with its version line.
+func decodeSDP(data []byte, sdpType webrtc.SDPType) (webrtc.SessionDescription, error) {
+	var sdp webrtc.SessionDescription
+
+	text := strings.TrimSpace(string(data))
+	if strings.HasPrefix(text, "v=0") {
+		return webrtc.SessionDescription{Type: sdpType, SDP: string(data)}, nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return sdp, fmt.Errorf("base64 decode error: %w", err)
+	}
+
+	if err = json.Unmarshal(b, &sdp); err != nil {
+		return sdp, fmt.Errorf("JSON unmarshal error: %w", err)
+	}
+
+	if sdp.Type != sdpType {
+		return sdp, fmt.Errorf("expected SDP of type '%s' but got '%s'", sdpType, sdp.Type)
+	}
+
+	return sdp, nil
+}
+
+func saveSDPToFile(sdp *webrtc.SessionDescription, filename string, encoding FileEncoding, logger *slog.Logger) error {
+	data, err := encodeSDP(sdp, encoding)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(filename, data); err != nil {
+		return fmt.Errorf("error while writing %s: %w", filename, err)
+	}
+
+	logger.Debug("sdp saved", "file", filename, "bytes", len(data))
+	return nil
+}
+
+// waitForSDPFile blocks till the file exists, then reads, decodes and removes it. The removal makes sure
+// a later Connect never picks up an SDP of an earlier session.
+func waitForSDPFile(ctx context.Context, filename string, sdpType webrtc.SDPType, logger *slog.Logger) (webrtc.SessionDescription, error) {
+	if err := waitForFile(ctx, filename, logger); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return webrtc.SessionDescription{}, fmt.Errorf("error reading %s: %w", filename, err)
+	}
+
+	sdp, err := decodeSDP(data, sdpType)
+	if err != nil {
+		return sdp, fmt.Errorf("error decoding %s: %w", filename, err)
+	}
+
+	if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return sdp, err
+	}
+
+	logger.Debug("sdp loaded", "file", filename, "bytes", len(data))
+	return sdp, nil
+}
+
+// waitForFile blocks till the file exists or the context is done. The directory is watched before the
+// file is looked for, so a file created in between is not missed.
+func waitForFile(ctx context.Context, filename string, logger *slog.Logger) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating directory %s: %w", dir, err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = watcher.Close()
+	}()
+
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("error watching %s: %w", dir, err)
+	}
+
+	if _, err := os.Stat(filename); err == nil {
+		return nil
+	}
+
+	logger.Info("waiting for file", "file", filename)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for %s; err: %w", filename, ctx.Err())
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return fmt.Errorf("watcher closed while waiting for %s", filename)
+			}
+			if event.Name == filename && event.Has(fsnotify.Create) {
+				return nil
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return fmt.Errorf("watcher closed while waiting for %s", filename)
+			}
+			logger.Warn("error while watching directory", "dir", dir, "err", err)
+		}
+	}
+}
+
+// writeCandidatesToDir returns a local candidate handler which writes every candidate into its own file.
+func writeCandidatesToDir(dir string, logger *slog.Logger) func(webrtc.ICECandidateInit) {
+	var count atomic.Int64
+
+	return func(candidate webrtc.ICECandidateInit) {
+		b, err := json.Marshal(candidate)
+		if err != nil {
+			logger.Error("error while encoding candidate", "err", err)
+			return
+		}
+
+		if err := writeFileAtomic(filepath.Join(dir, fmt.Sprintf("%06d.json", count.Add(1))), b); err != nil {
+			logger.Error("error while saving candidate to file", "dir", dir, "err", err)
+		}
+	}
+}
+
+// receiveCandidatesFromDir applies the candidate files already in dir and every one added later, until
+// the context is done.
+func receiveCandidatesFromDir(ctx context.Context, dir string, pc *PeerConnection) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		pc.logger.Error("error creating directory", "dir", dir, "err", err)
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		pc.logger.Error("error while creating watcher", "err", err)
+		return
+	}
+	defer func() {
+		_ = watcher.Close()
+	}()
+
+	if err := watcher.Add(dir); err != nil {
+		pc.logger.Error("error watching directory", "dir", dir, "err", err)
+		return
+	}
+
+	applied := make(map[string]struct{})
+	apply := func(filename string) {
+		if _, exists := applied[filename]; exists || !isCandidateFile(filename) {
+			return
+		}
+		applied[filename] = struct{}{}
+
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			pc.logger.Error("error reading candidate file", "file", filename, "err", err)
+			return
+		}
+
+		var candidate webrtc.ICECandidateInit
+		if err := json.Unmarshal(data, &candidate); err != nil {
+			pc.logger.Warn("skipping malformed candidate", "file", filename, "err", err)
+			return
+		}
+
+		if err := pc.addRemoteCandidate(candidate); err != nil {
+			pc.logger.Warn("error while adding remote candidate", "err", err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err == nil {
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			apply(filepath.Join(dir, name))
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pc.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				apply(event.Name)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			pc.logger.Warn("error while watching directory", "dir", dir, "err", err)
+		}
+	}
+}
+
+// isCandidateFile skips the hidden temporary files of writeFileAtomic.
+func isCandidateFile(filename string) bool {
+	name := filepath.Base(filename)
+	return !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".json")
+}
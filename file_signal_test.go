@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/datachannel"
+)
+
+const testSDP = "v=0\r\no=- 0 0 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n"
+
+func TestSDPFileEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding FileEncoding
+	}{
+		{"default", ""},
+		{"base64 json", FileEncodingBase64JSON},
+		{"plain sdp", FileEncodingPlainSDP},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := encodeSDP(&webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: testSDP}, test.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// NOTE: READING DETECTS THE ENCODING
+			sdp, err := decodeSDP(data, webrtc.SDPTypeOffer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sdp.Type != webrtc.SDPTypeOffer || sdp.SDP != testSDP {
+				t.Fatalf("expected the offer back, got %+v", sdp)
+			}
+		})
+	}
+}
+
+func TestSDPFileEncodingErrors(t *testing.T) {
+	if _, err := encodeSDP(&webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: testSDP}, "xml"); err == nil {
+		t.Fatal("expected an unknown encoding to fail")
+	}
+
+	data, err := encodeSDP(&webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: testSDP}, FileEncodingBase64JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeSDP(data, webrtc.SDPTypeAnswer); err == nil {
+		t.Fatal("expected an offer to be rejected where an answer is expected")
+	}
+
+	if _, err := decodeSDP([]byte("not an sdp"), webrtc.SDPTypeOffer); err == nil {
+		t.Fatal("expected garbage to be rejected")
+	}
+}
+
+func TestWriteFileAtomicLeavesNoTemporaryFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "category", "pc")
+	filename := filepath.Join(dir, fileOfferName)
+
+	for _, data := range []string{"first", "second"} {
+		if err := writeFileAtomic(filename, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "second" {
+		t.Fatalf("expected the file to be replaced, got %q", data)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only %s in the directory, got %d entries", fileOfferName, len(entries))
+	}
+}
+
+func TestIsCandidateFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"000001.json", true},
+		{".000001.json.tmp-1234", false},
+		{".hidden.json", false},
+		{"offer.txt", false},
+	}
+
+	for _, test := range tests {
+		if isCandidateFile(filepath.Join("dir", test.name)) != test.expected {
+			t.Errorf("expected isCandidateFile(%s) to be %t", test.name, test.expected)
+		}
+	}
+}
+
+func TestWaitForSDPFileSeesLaterFileAndRemovesIt(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filename := filepath.Join(t.TempDir(), "category", "pc", fileAnswerName)
+	logger := slog.New(slog.DiscardHandler)
+
+	result := make(chan error, 1)
+	go func() {
+		sdp, err := waitForSDPFile(ctx, filename, webrtc.SDPTypeAnswer, logger)
+		if err == nil && sdp.SDP != testSDP {
+			err = errors.New("unexpected sdp")
+		}
+		result <- err
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if err := saveSDPToFile(&webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: testSDP}, filename, FileEncodingPlainSDP, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: A LATER SESSION MUST NOT PICK UP THIS ANSWER
+	if _, err := os.Stat(filename); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the answer file to be removed after reading, got %v", err)
+	}
+}
+
+func TestWaitForFileStopsWithItsContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := waitForFile(ctx, filepath.Join(t.TempDir(), fileOfferName), slog.New(slog.DiscardHandler))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to end with its context, got %v", err)
+	}
+}
+
+// newFileSignalClient creates a client with a peer connection per label, each with a negotiated data
+// channel so that there is something to negotiate.
+func newFileSignalClient(ctx context.Context, t *testing.T, labels ...string) (*Client, []*PeerConnection) {
+	t.Helper()
+
+	c, err := NewClient(ctx, nil, nil, loopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+
+	pcs := make([]*PeerConnection, 0, len(labels))
+	for _, label := range labels {
+		pc, err := c.CreatePeerConnection(label, webrtc.Configuration{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		negotiated := datachannel.WithDataChannelInit(&webrtc.DataChannelInit{Negotiated: &datachannel.NegotiatedTrue, ID: &datachannel.IDOne})
+		if _, err := pc.CreateDataChannel("control", negotiated); err != nil {
+			t.Fatal(err)
+		}
+
+		pcs = append(pcs, pc)
+	}
+
+	return c, pcs
+}
+
+func TestFileSignalsConnectPeerConnections(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []SignalOption
+	}{
+		{"gathered", nil},
+		{"trickled", []SignalOption{WithTrickleICE()}},
+		{"plain sdp", []SignalOption{WithFileEncoding(FileEncodingPlainSDP)}},
+		{"trickled plain sdp", []SignalOption{WithTrickleICE(), WithFileEncoding(FileEncodingPlainSDP)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			offer, offerPCs := newFileSignalClient(ctx, t, "first", "second")
+			answer, answerPCs := newFileSignalClient(ctx, t, "first", "second")
+
+			// NOTE: BOTH SIDES SHARE ONE DIRECTORY, LIKE A MOUNTED VOLUME WOULD BE SHARED
+			dir := t.TempDir()
+			offerSignal := CreateFileOfferSignal(ctx, dir, dir, test.options...)
+			defer offerSignal.Close()
+			answerSignal := CreateFileAnswerSignal(ctx, dir, dir, test.options...)
+			defer answerSignal.Close()
+
+			connectSignals(t, offer, answer, offerSignal, answerSignal)
+
+			for _, pc := range append(offerPCs, answerPCs...) {
+				if err := pc.WaitTill(ctx, func(state webrtc.PeerConnectionState, _ webrtc.ICEConnectionState) bool {
+					return state != webrtc.PeerConnectionStateConnected
+				}); err != nil {
+					t.Fatalf("expected %s to connect, got %s; err: %v", pc.GetLabel(), pc.GetState(), err)
+				}
+			}
+
+			// NOTE: EVERY PEER CONNECTION HAS ITS OWN FILES, AND THE SDP FILES ARE GONE ONCE READ
+			for _, label := range []string{"first", "second"} {
+				paths := newFilePaths(dir, dir, "reconnect", label)
+				for _, filename := range []string{paths.offer, paths.answer} {
+					if _, err := os.Stat(filename); !errors.Is(err, os.ErrNotExist) {
+						t.Fatalf("expected %s to be removed, got %v", filename, err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -15,13 +15,15 @@ import (
 )
 
 type AnswerSignal struct {
-	app    *firebase.App
-	client *firestore.Client
-	docRef *firestore.DocumentRef
-	ctx    context.Context
+	app      *firebase.App
+	client   *firestore.Client
+	docRef   *firestore.DocumentRef
+	sessions candidateSessions
+	ctx      context.Context
+	signalConfig
 }
 
-func CreateFirebaseAnswerSignal(ctx context.Context) (*AnswerSignal, error) {
+func CreateFirebaseAnswerSignal(ctx context.Context, options ...SignalOption) (*AnswerSignal, error) {
 	var (
 		configuration option.ClientOption
 		app           *firebase.App
@@ -40,13 +42,23 @@ func CreateFirebaseAnswerSignal(ctx context.Context) (*AnswerSignal, error) {
 	}
 
 	return &AnswerSignal{
-		app:    app,
-		client: client,
-		ctx:    ctx,
+		app:          app,
+		client:       client,
+		ctx:          ctx,
+		signalConfig: newSignalConfig(options...),
 	}, nil
 }
 
 func (signal *AnswerSignal) Connect(category string, pc *PeerConnection) error {
+	return signal.ConnectContext(signal.ctx, category, pc)
+}
+
+// ConnectContext implements ContextSignal; ctx bounds the exchange, the candidate listener lives as long
+// as the session.
+func (signal *AnswerSignal) ConnectContext(ctx context.Context, category string, pc *PeerConnection) error {
+	ctx, cancel := exchangeContext(ctx, signal.ctx)
+	defer cancel()
+
 	signal.docRef = signal.client.Collection(category).Doc(pc.label)
 
 	ticker := time.NewTicker(1 * time.Second)
@@ -56,10 +68,10 @@ func (signal *AnswerSignal) Connect(category string, pc *PeerConnection) error {
 loop:
 	for {
 		select {
-		case <-signal.ctx.Done():
-			return fmt.Errorf("failed to get offer within context deadline; err: %w", signal.ctx.Err())
+		case <-ctx.Done():
+			return fmt.Errorf("failed to get offer within context deadline; err: %w", ctx.Err())
 		case <-ticker.C:
-			snapshot, err := signal.docRef.Get(signal.ctx)
+			snapshot, err := signal.docRef.Get(ctx)
 			if err != nil || snapshot == nil {
 				if status.Code(err) == codes.NotFound {
 					continue loop
@@ -74,42 +86,51 @@ loop:
 			break loop
 		}
 	}
-	fmt.Println("Found Offer. Creating answer...")
+	pc.logger.Info("found offer in firestore; creating answer")
+
+	if err := pc.lockNegotiation(ctx); err != nil {
+		return err
+	}
+	defer pc.unlockNegotiation()
 
-	return signal.answer(data[FieldOffer].(map[string]interface{}), pc)
+	return signal.answer(ctx, signal.sessions.start(signal.ctx, category, pc), data[FieldOffer].(map[string]interface{}), pc)
 }
 
-func (signal *AnswerSignal) answer(offer map[string]interface{}, pc *PeerConnection) error {
+// answer answers the offer within ctx; session bounds the candidate goroutines, see candidateSessions.
+func (signal *AnswerSignal) answer(ctx context.Context, session context.Context, offer map[string]interface{}, pc *PeerConnection) error {
 	sdp, ok := offer[FieldSDP].(string)
 	if !ok {
 		return fmt.Errorf("invalid SDP format in offer")
 	}
 
-	if err := pc.peerConnection.SetRemoteDescription(webrtc.SessionDescription{
+	if signal.trickle {
+		go receiveFirestoreCandidates(session, signal.docRef.Collection(FieldOfferCandidates), pc)
+		pc.setLocalCandidateHandler(sendFirestoreCandidates(session, signal.docRef.Collection(FieldAnswerCandidates), pc.logger))
+	}
+
+	if err := pc.setRemoteDescription(webrtc.SessionDescription{
 		Type: webrtc.SDPTypeOffer,
 		SDP:  sdp,
 	}); err != nil {
 		return err
 	}
 
-	answer, err := pc.peerConnection.CreateAnswer(nil)
+	answer, err := pc.GetPeerConnection().CreateAnswer(nil)
 	if err != nil {
 		return fmt.Errorf("error while creating answer: %w", err)
 	}
 
-	if err := pc.peerConnection.SetLocalDescription(answer); err != nil {
+	if err := pc.GetPeerConnection().SetLocalDescription(answer); err != nil {
 		return fmt.Errorf("error while setting local sdp: %w", err)
 	}
 
-	select {
-	case <-webrtc.GatheringCompletePromise(pc.peerConnection):
-	case <-signal.ctx.Done():
-		return fmt.Errorf("failed to gather ICE candidates within context deadline; err: %w", signal.ctx.Err())
+	if err := pc.waitForICEGathering(ctx, signal.trickle); err != nil {
+		return err
 	}
 
-	if _, err = signal.docRef.Set(signal.ctx, map[string]interface{}{
+	if _, err = signal.docRef.Set(ctx, map[string]interface{}{
 		FieldAnswer: map[string]interface{}{
-			FieldSDP:       pc.peerConnection.LocalDescription().SDP,
+			FieldSDP:       pc.GetPeerConnection().LocalDescription().SDP,
 			FieldUpdatedAt: firestore.ServerTimestamp,
 		},
 		FieldStatus: FieldStatusConnected,
@@ -121,5 +142,7 @@ func (signal *AnswerSignal) answer(offer map[string]interface{}, pc *PeerConnect
 }
 
 func (signal *AnswerSignal) Close() error {
+	signal.sessions.close()
+
 	return signal.client.Close()
 }
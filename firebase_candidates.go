@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"cloud.google.com/go/firestore"
+	"github.com/pion/webrtc/v4"
+	"google.golang.org/api/iterator"
+)
+
+func candidateToFirestore(candidate webrtc.ICECandidateInit) map[string]interface{} {
+	data := map[string]interface{}{
+		FieldCandidate: candidate.Candidate,
+		FieldCreatedAt: firestore.ServerTimestamp,
+	}
+
+	if candidate.SDPMid != nil {
+		data[FieldSDPMid] = *candidate.SDPMid
+	}
+	if candidate.SDPMLineIndex != nil {
+		data[FieldSDPMLineIndex] = int64(*candidate.SDPMLineIndex)
+	}
+	if candidate.UsernameFragment != nil {
+		data[FieldUsernameFragment] = *candidate.UsernameFragment
+	}
+
+	return data
+}
+
+func candidateFromFirestore(data map[string]interface{}) (webrtc.ICECandidateInit, error) {
+	var candidate webrtc.ICECandidateInit
+
+	c, ok := data[FieldCandidate].(string)
+	if !ok {
+		return candidate, errors.New("invalid candidate format in firestore")
+	}
+	candidate.Candidate = c
+
+	if mid, ok := data[FieldSDPMid].(string); ok {
+		candidate.SDPMid = &mid
+	}
+	if index, ok := data[FieldSDPMLineIndex].(int64); ok {
+		i := uint16(index)
+		candidate.SDPMLineIndex = &i
+	}
+	if ufrag, ok := data[FieldUsernameFragment].(string); ok {
+		candidate.UsernameFragment = &ufrag
+	}
+
+	return candidate, nil
+}
+
+// sendFirestoreCandidates returns a local candidate handler which adds each candidate to the given subcollection.
+func sendFirestoreCandidates(ctx context.Context, collection *firestore.CollectionRef, logger *slog.Logger) func(webrtc.ICECandidateInit) {
+	return func(candidate webrtc.ICECandidateInit) {
+		if _, _, err := collection.Add(ctx, candidateToFirestore(candidate)); err != nil {
+			logger.Warn("error while adding candidate to firestore", "err", err)
+		}
+	}
+}
+
+// receiveFirestoreCandidates listens on the given subcollection and applies every added candidate to the
+// PeerConnection until the context is done.
+func receiveFirestoreCandidates(ctx context.Context, collection *firestore.CollectionRef, pc *PeerConnection) {
+	snapshots := collection.Snapshots(ctx)
+	defer snapshots.Stop()
+
+	for {
+		snapshot, err := snapshots.Next()
+		if err != nil {
+			return
+		}
+
+		for _, change := range snapshot.Changes {
+			if change.Kind != firestore.DocumentAdded {
+				continue
+			}
+
+			candidate, err := candidateFromFirestore(change.Doc.Data())
+			if err != nil {
+				pc.logger.Warn("skipping malformed candidate", "err", err)
+				continue
+			}
+
+			if err := pc.addRemoteCandidate(candidate); err != nil {
+				pc.logger.Warn("error while adding remote candidate", "err", err)
+			}
+		}
+	}
+}
+
+func deleteFirestoreCollection(ctx context.Context, collection *firestore.CollectionRef) error {
+	documents := collection.Documents(ctx)
+	defer documents.Stop()
+
+	for {
+		doc, err := documents.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := doc.Ref.Delete(ctx); err != nil {
+			return err
+		}
+	}
+}
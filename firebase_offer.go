@@ -17,10 +17,12 @@ type FirebaseOfferSignal struct {
 	app            *firebase.App
 	firebaseClient *firestore.Client
 	docRef         *firestore.DocumentRef
+	sessions       candidateSessions
 	ctx            context.Context
+	signalConfig
 }
 
-func CreateFirebaseOfferSignal(ctx context.Context) (*FirebaseOfferSignal, error) {
+func CreateFirebaseOfferSignal(ctx context.Context, options ...SignalOption) (*FirebaseOfferSignal, error) {
 	var (
 		configuration  option.ClientOption
 		app            *firebase.App
@@ -42,43 +44,69 @@ func CreateFirebaseOfferSignal(ctx context.Context) (*FirebaseOfferSignal, error
 		app:            app,
 		firebaseClient: firebaseClient,
 		ctx:            ctx,
+		signalConfig:   newSignalConfig(options...),
 	}, nil
 }
 
 func (signal *FirebaseOfferSignal) Connect(category string, pc *PeerConnection) error {
+	return signal.ConnectContext(signal.ctx, category, pc)
+}
+
+// ConnectContext implements ContextSignal; ctx bounds the exchange, the candidate listener lives as long
+// as the session.
+func (signal *FirebaseOfferSignal) ConnectContext(ctx context.Context, category string, pc *PeerConnection) error {
+	ctx, cancel := exchangeContext(ctx, signal.ctx)
+	defer cancel()
+
 	signal.docRef = signal.firebaseClient.Collection(category).Doc(pc.label)
-	_, err := signal.docRef.Get(signal.ctx)
+	_, err := signal.docRef.Get(ctx)
 
 	if err != nil && status.Code(err) != codes.NotFound {
-		fmt.Println(status.Code(err))
+		pc.logger.Error("error while reading firestore document", "code", status.Code(err).String(), "err", err)
 		return err
 	}
 
 	if err == nil {
-		if _, err := signal.docRef.Delete(signal.ctx); err != nil {
+		if _, err := signal.docRef.Delete(ctx); err != nil {
 			return err
 		}
 	}
 
-	offer, err := pc.peerConnection.CreateOffer(nil)
+	// NOTE: DELETING A DOCUMENT DOES NOT DELETE ITS SUBCOLLECTIONS
+	for _, name := range []string{FieldOfferCandidates, FieldAnswerCandidates} {
+		if err := deleteFirestoreCollection(ctx, signal.docRef.Collection(name)); err != nil {
+			return fmt.Errorf("error while deleting old candidates: %w", err)
+		}
+	}
+
+	session := signal.sessions.start(signal.ctx, category, pc)
+
+	if err := pc.lockNegotiation(ctx); err != nil {
+		return err
+	}
+	defer pc.unlockNegotiation()
+
+	if signal.trickle {
+		pc.setLocalCandidateHandler(sendFirestoreCandidates(session, signal.docRef.Collection(FieldOfferCandidates), pc.logger))
+	}
+
+	offer, err := pc.createOffer()
 	if err != nil {
 		return fmt.Errorf("error while creating offer: %w", err)
 	}
 
-	if err := pc.peerConnection.SetLocalDescription(offer); err != nil {
+	if err := pc.GetPeerConnection().SetLocalDescription(offer); err != nil {
 		return fmt.Errorf("error while setting local sdp: %w", err)
 	}
 
-	select {
-	case <-webrtc.GatheringCompletePromise(pc.peerConnection):
-	case <-signal.ctx.Done():
-		return fmt.Errorf("failed to gather ICE candidates within context deadline; err: %w", signal.ctx.Err())
+	if err := pc.waitForICEGathering(ctx, signal.trickle); err != nil {
+		return err
 	}
 
-	if _, err = signal.docRef.Set(signal.ctx, map[string]interface{}{
+	if _, err = signal.docRef.Set(ctx, map[string]interface{}{
 		FieldOffer: map[string]interface{}{
 			FieldCreatedAt: firestore.ServerTimestamp,
-			FieldSDP:       pc.peerConnection.LocalDescription().SDP,
+			FieldSDP:       pc.GetPeerConnection().LocalDescription().SDP,
 			FieldUpdatedAt: firestore.ServerTimestamp,
 		},
 		FieldStatus: FieldStatusPending,
@@ -86,12 +114,16 @@ func (signal *FirebaseOfferSignal) Connect(category string, pc *PeerConnection)
 		return fmt.Errorf("error while setting data to firestore: %w", err)
 	}
 
-	fmt.Println("Offer updated in firestore. Waiting for peer connection...")
+	pc.logger.Info("offer updated in firestore; waiting for answer")
 
-	return signal.offer(pc)
+	if signal.trickle {
+		go receiveFirestoreCandidates(session, signal.docRef.Collection(FieldAnswerCandidates), pc)
+	}
+
+	return signal.offer(ctx, pc)
 }
 
-func (signal *FirebaseOfferSignal) offer(pc *PeerConnection) error {
+func (signal *FirebaseOfferSignal) offer(ctx context.Context, pc *PeerConnection) error {
 
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -99,10 +131,10 @@ func (signal *FirebaseOfferSignal) offer(pc *PeerConnection) error {
 loop:
 	for {
 		select {
-		case <-signal.ctx.Done():
-			return fmt.Errorf("failed to get answer within context deadline; err: %w", signal.ctx.Err())
+		case <-ctx.Done():
+			return fmt.Errorf("failed to get answer within context deadline; err: %w", ctx.Err())
 		case <-ticker.C:
-			snapshot, err := signal.docRef.Get(signal.ctx)
+			snapshot, err := signal.docRef.Get(ctx)
 			if err != nil || snapshot == nil {
 				if status.Code(err) == codes.NotFound {
 					continue loop
@@ -118,11 +150,11 @@ loop:
 			if !ok {
 				continue loop
 			}
-			if err = pc.peerConnection.SetRemoteDescription(webrtc.SessionDescription{
+			if err = pc.setRemoteDescription(webrtc.SessionDescription{
 				Type: webrtc.SDPTypeAnswer,
 				SDP:  sdp,
 			}); err != nil {
-				fmt.Printf("error while setting remote description: %s\n", err.Error())
+				pc.logger.Warn("error while setting remote description", "err", err)
 				continue loop
 			}
 
@@ -134,9 +166,10 @@ loop:
 }
 
 func (signal *FirebaseOfferSignal) Close() error {
+	signal.sessions.close()
+
 	if err := signal.firebaseClient.Close(); err != nil {
-		fmt.Printf("failed to close firebase client with error: %v\n", err)
-		return err
+		return fmt.Errorf("failed to close firebase client; err: %w", err)
 	}
 
 	return nil
@@ -9,74 +9,109 @@ import (
 )
 
 type GenericAnswerSignal struct {
-	ctx context.Context
+	sessions candidateSessions
+	ctx      context.Context
 
 	forOffer ForOffer
 	onAnswer OnAnswer
+	signalConfig
 }
 
-func NewGenericAnswerSignal(ctx context.Context, onAnswer OnAnswer, forOffer ForOffer) *GenericAnswerSignal {
+func NewGenericAnswerSignal(ctx context.Context, onAnswer OnAnswer, forOffer ForOffer, options ...SignalOption) *GenericAnswerSignal {
 	return &GenericAnswerSignal{
-		ctx:      ctx,
-		forOffer: forOffer,
-		onAnswer: onAnswer,
+		ctx:          ctx,
+		forOffer:     forOffer,
+		onAnswer:     onAnswer,
+		signalConfig: newSignalConfig(options...),
 	}
 }
 
-func (s *GenericAnswerSignal) Connect(_ string, pc *PeerConnection) error {
+// Connect waits for an offer and answers it. On a connected PeerConnection, calling it again answers the
+// next offer, which is how renegotiations started by GenericOfferSignal.Renegotiate are served.
+func (s *GenericAnswerSignal) Connect(category string, pc *PeerConnection) error {
+	return s.ConnectContext(s.ctx, category, pc)
+}
+
+// ConnectContext implements ContextSignal; ctx is handed to the callbacks of the exchange.
+func (s *GenericAnswerSignal) ConnectContext(ctx context.Context, category string, pc *PeerConnection) error {
 	if s.onAnswer == nil || s.forOffer == nil {
 		return errors.New("connect method cannot be used. use offer and answer methods instead")
 	}
 
-	offerSDP, err := s.forOffer(s.ctx)
+	ctx, cancel := exchangeContext(ctx, s.ctx)
+	defer cancel()
+
+	offerSDP, err := s.forOffer(ctx)
 	if err != nil {
 		return err
 	}
 
+	if err := pc.lockNegotiation(ctx); err != nil {
+		return err
+	}
+	defer pc.unlockNegotiation()
+
+	session := s.sessions.start(s.ctx, category, pc)
+	if s.trickle && s.forCandidate != nil {
+		go receiveGenericCandidates(session, pc, s.forCandidate)
+	}
+
 	if err := s.Offer(pc, offerSDP); err != nil {
 		return err
 	}
 
-	answerSDP, err := s.Answer(pc)
+	answerSDP, err := s.answer(ctx, session, pc)
 	if err != nil {
 		return err
 	}
 
-	return s.onAnswer(s.ctx, answerSDP)
+	return s.onAnswer(ctx, answerSDP)
 }
 
 // Offer sets the remote offer SDP on the PeerConnection.
 func (s *GenericAnswerSignal) Offer(pc *PeerConnection, sdp string) error {
-	if err := pc.peerConnection.SetRemoteDescription(webrtc.SessionDescription{
+	return pc.setRemoteDescription(webrtc.SessionDescription{
 		Type: webrtc.SDPTypeOffer,
 		SDP:  sdp,
-	}); err != nil {
-		return fmt.Errorf("failed to set remote description (pc=%s); err: %w", pc.GetLabel(), err)
-	}
-	return nil
+	})
 }
 
-// Answer creates and sets the local answer, waits for ICE gathering to complete, and returns the local SDP.
+// Answer creates and sets the local answer, waits for ICE gathering to complete (unless trickle is enabled),
+// and returns the local SDP.
+// Trickled local candidates are sent in a new candidate session of pc.
 func (s *GenericAnswerSignal) Answer(pc *PeerConnection) (string, error) {
-	answer, err := pc.peerConnection.CreateAnswer(nil)
+	category, _ := pc.getSignal()
+	return s.answer(s.ctx, s.sessions.start(s.ctx, category, pc), pc)
+}
+
+// answer sends the local candidates with the session context, so that they stop once the session ends.
+func (s *GenericAnswerSignal) answer(ctx context.Context, session context.Context, pc *PeerConnection) (string, error) {
+	if s.trickle && s.onCandidate != nil {
+		pc.setLocalCandidateHandler(sendGenericCandidate(session, pc, s.onCandidate))
+	}
+
+	answer, err := pc.GetPeerConnection().CreateAnswer(nil)
 	if err != nil {
 		return "", fmt.Errorf("error while creating answer: %w", err)
 	}
 
-	if err := pc.peerConnection.SetLocalDescription(answer); err != nil {
+	if err := pc.GetPeerConnection().SetLocalDescription(answer); err != nil {
 		return "", fmt.Errorf("error while setting local sdp: %w", err)
 	}
 
-	select {
-	case <-s.ctx.Done():
-		return "", fmt.Errorf("failed to gather ICE candidates within context deadline; err: %w", s.ctx.Err())
-	case <-webrtc.GatheringCompletePromise(pc.peerConnection):
+	if err := pc.waitForICEGathering(ctx, s.trickle); err != nil {
+		return "", err
 	}
 
-	return pc.peerConnection.LocalDescription().SDP, nil
+	return pc.GetPeerConnection().LocalDescription().SDP, nil
+}
+
+// Candidate applies a remote trickled candidate; candidates received before the offer are queued.
+func (s *GenericAnswerSignal) Candidate(pc *PeerConnection, candidate webrtc.ICECandidateInit) error {
+	return pc.addRemoteCandidate(candidate)
 }
 
 func (s *GenericAnswerSignal) Close() error {
-	// NOTE: INTENTIONALLY EMPTY
+	s.sessions.close()
 	return nil
 }
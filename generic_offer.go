@@ -9,78 +9,145 @@ import (
 )
 
 type GenericOfferSignal struct {
-	ctx context.Context
+	sessions candidateSessions
+	ctx      context.Context
 
 	onOffer   OnOffer
 	forAnswer ForAnswer
+	signalConfig
 }
 
-func NewGenericOfferSignal(ctx context.Context, onOffer OnOffer, forAnswer ForAnswer) *GenericOfferSignal {
+func NewGenericOfferSignal(ctx context.Context, onOffer OnOffer, forAnswer ForAnswer, options ...SignalOption) *GenericOfferSignal {
 	return &GenericOfferSignal{
-		ctx:       ctx,
-		onOffer:   onOffer,
-		forAnswer: forAnswer,
+		ctx:          ctx,
+		onOffer:      onOffer,
+		forAnswer:    forAnswer,
+		signalConfig: newSignalConfig(options...),
 	}
 }
 
-func (s *GenericOfferSignal) Connect(_ string, pc *PeerConnection) error {
+func (s *GenericOfferSignal) Connect(category string, pc *PeerConnection) error {
+	return s.ConnectContext(s.ctx, category, pc)
+}
+
+// ConnectContext implements ContextSignal; ctx is handed to the callbacks of the exchange.
+func (s *GenericOfferSignal) ConnectContext(ctx context.Context, category string, pc *PeerConnection) error {
 	if s.onOffer == nil || s.forAnswer == nil {
 		return errors.New("connect method cannot be used. use offer and answer methods instead")
 	}
 
-	offer, err := s.Offer(pc)
-	if err != nil {
+	ctx, cancel := exchangeContext(ctx, s.ctx)
+	defer cancel()
+
+	if err := pc.lockNegotiation(ctx); err != nil {
 		return err
 	}
+	defer pc.unlockNegotiation()
 
-	if err := s.onOffer(s.ctx, offer); err != nil {
-		return err
+	return s.exchange(ctx, category, pc)
+}
+
+// Renegotiate runs the onOffer/forAnswer exchange of Connect again on a connected PeerConnection. The
+// remote side answers it by calling GenericAnswerSignal.Connect again.
+func (s *GenericOfferSignal) Renegotiate(ctx context.Context, pc *PeerConnection) error {
+	if s.onOffer == nil || s.forAnswer == nil {
+		return errors.New("renegotiate method cannot be used. use offer and answer methods instead")
 	}
 
-	answer, err := s.forAnswer(s.ctx)
+	category, _ := pc.getSignal()
+	return s.exchange(ctx, category, pc)
+}
+
+// exchange runs one offer/answer exchange in a new candidate session of pc, which ends the candidate
+// goroutines of the previous one.
+func (s *GenericOfferSignal) exchange(ctx context.Context, category string, pc *PeerConnection) error {
+	session := s.sessions.start(s.ctx, category, pc)
+
+	offer, err := s.offer(ctx, session, pc)
 	if err != nil {
 		return err
 	}
 
-	if err := s.Answer(pc, answer); err != nil {
+	if err := s.onOffer(ctx, offer); err != nil {
 		return err
 	}
 
-	return nil
+	if s.trickle && s.forCandidate != nil {
+		go receiveGenericCandidates(session, pc, s.forCandidate)
+	}
+
+	answer, err := s.forAnswer(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.Answer(pc, answer)
 }
 
+// Offer creates and sets the local offer; trickled local candidates are sent in a new candidate session
+// of pc.
 func (s *GenericOfferSignal) Offer(pc *PeerConnection) (string, error) {
-	offer, err := pc.peerConnection.CreateOffer(nil)
+	category, _ := pc.getSignal()
+	return s.offer(s.ctx, s.sessions.start(s.ctx, category, pc), pc)
+}
+
+// offer sends the local candidates with the session context, so that they stop once the session ends.
+func (s *GenericOfferSignal) offer(ctx context.Context, session context.Context, pc *PeerConnection) (string, error) {
+	if s.trickle && s.onCandidate != nil {
+		pc.setLocalCandidateHandler(sendGenericCandidate(session, pc, s.onCandidate))
+	}
+
+	offer, err := pc.createOffer()
 	if err != nil {
 		return "", fmt.Errorf("error while creating offer: %w", err)
 	}
 
-	if err := pc.peerConnection.SetLocalDescription(offer); err != nil {
+	if err := pc.GetPeerConnection().SetLocalDescription(offer); err != nil {
 		return "", fmt.Errorf("error while setting local sdp: %w", err)
 
 	}
 
-	select {
-	case <-s.ctx.Done():
-		return "", fmt.Errorf("failed to gather ICE candidates within context deadline; err: %w", s.ctx.Err())
-	case <-webrtc.GatheringCompletePromise(pc.peerConnection):
+	if err := pc.waitForICEGathering(ctx, s.trickle); err != nil {
+		return "", err
 	}
 
-	return pc.peerConnection.LocalDescription().SDP, nil
+	return pc.GetPeerConnection().LocalDescription().SDP, nil
 }
 
 func (s *GenericOfferSignal) Answer(pc *PeerConnection, sdp string) error {
-	if err := pc.peerConnection.SetRemoteDescription(webrtc.SessionDescription{
+	return pc.setRemoteDescription(webrtc.SessionDescription{
 		Type: webrtc.SDPTypeAnswer,
 		SDP:  sdp,
-	}); err != nil {
-		return fmt.Errorf("failed to set remote description (pc=%s); err: %w", pc.GetLabel(), err)
-	}
+	})
+}
 
-	return nil
+// Candidate applies a remote trickled candidate; candidates received before the answer are queued.
+func (s *GenericOfferSignal) Candidate(pc *PeerConnection, candidate webrtc.ICECandidateInit) error {
+	return pc.addRemoteCandidate(candidate)
 }
 
 func (s *GenericOfferSignal) Close() error {
-	// NOTE: INTENTIONALLY EMPTY
+	s.sessions.close()
 	return nil
 }
+
+func sendGenericCandidate(ctx context.Context, pc *PeerConnection, onCandidate OnCandidate) func(webrtc.ICECandidateInit) {
+	return func(candidate webrtc.ICECandidateInit) {
+		if err := onCandidate(ctx, candidate); err != nil {
+			pc.logger.Warn("error while sending local candidate", "err", err)
+		}
+	}
+}
+
+func receiveGenericCandidates(ctx context.Context, pc *PeerConnection, forCandidate ForCandidate) {
+	for {
+		candidate, err := forCandidate(ctx)
+		if err != nil {
+			return
+		}
+
+		if err := pc.addRemoteCandidate(candidate); err != nil {
+			pc.logger.Warn("error while adding remote candidate", "err", err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Package logger holds the package-wide logger of the packages of this module, which they use wherever
+// no other logger was given.
+package logger
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+// Package is the logger of a package. The zero value logs with slog.Default().
+type Package struct {
+	logger atomic.Pointer[slog.Logger]
+}
+
+// Set sets the logger; nil restores slog.Default().
+func (p *Package) Set(l *slog.Logger) {
+	p.logger.Store(l)
+}
+
+func (p *Package) Get() *slog.Logger {
+	if l := p.logger.Load(); l != nil {
+		return l
+	}
+
+	return slog.Default()
+}
+
+// OrDiscard returns l, or a logger that drops everything if l is nil.
+func OrDiscard(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+
+	return l
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/pion/logging"
+)
+
+// LogLevelTrace is the level pion's trace logs are written with by the logger factory of
+// NewSlogLoggerFactory; it is below slog.LevelDebug, so they stay silent unless asked for.
+const LogLevelTrace = slog.LevelDebug - 4
+
+// WithLogger sets the logger of the client. Every peer connection logs with it, adding its label as
+// "pc"; data channels, media sources and media sinks add "channel", "track" and "sink" respectively.
+// Without this option slog.Default() is used. Pion's own logs are configured separately, see
+// WithPionLoggerFactory.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(client *Client) error {
+		if logger == nil {
+			return fmt.Errorf("logger cannot be nil")
+		}
+
+		client.logger = logger
+		return nil
+	}
+}
+
+// WithPionLoggerFactory sets the logger factory of pion's setting engine, e.g.
+// NewSlogLoggerFactory(logger) to route pion's logs to the same sink as the client's.
+func WithPionLoggerFactory(factory logging.LoggerFactory) ClientOption {
+	return func(client *Client) error {
+		client.settingsEngine.LoggerFactory = factory
+		return nil
+	}
+}
+
+// NewSlogLoggerFactory adapts a slog.Logger to pion's logging.LoggerFactory. The scope of every pion
+// logger is added as "scope".
+func NewSlogLoggerFactory(logger *slog.Logger) logging.LoggerFactory {
+	return &slogLoggerFactory{logger: logger}
+}
+
+type slogLoggerFactory struct {
+	logger *slog.Logger
+}
+
+func (f *slogLoggerFactory) NewLogger(scope string) logging.LeveledLogger {
+	return &slogLeveledLogger{logger: f.logger.With("scope", scope)}
+}
+
+type slogLeveledLogger struct {
+	logger *slog.Logger
+}
+
+func (l *slogLeveledLogger) log(level slog.Level, msg string) {
+	l.logger.Log(context.Background(), level, msg)
+}
+
+// logf only formats when the level is enabled, as pion logs a lot at trace and debug level.
+func (l *slogLeveledLogger) logf(level slog.Level, format string, args ...any) {
+	if !l.logger.Enabled(context.Background(), level) {
+		return
+	}
+
+	l.logger.Log(context.Background(), level, fmt.Sprintf(format, args...))
+}
+
+func (l *slogLeveledLogger) Trace(msg string) {
+	l.log(LogLevelTrace, msg)
+}
+
+func (l *slogLeveledLogger) Tracef(format string, args ...any) {
+	l.logf(LogLevelTrace, format, args...)
+}
+
+func (l *slogLeveledLogger) Debug(msg string) {
+	l.log(slog.LevelDebug, msg)
+}
+
+func (l *slogLeveledLogger) Debugf(format string, args ...any) {
+	l.logf(slog.LevelDebug, format, args...)
+}
+
+func (l *slogLeveledLogger) Info(msg string) {
+	l.log(slog.LevelInfo, msg)
+}
+
+func (l *slogLeveledLogger) Infof(format string, args ...any) {
+	l.logf(slog.LevelInfo, format, args...)
+}
+
+func (l *slogLeveledLogger) Warn(msg string) {
+	l.log(slog.LevelWarn, msg)
+}
+
+func (l *slogLeveledLogger) Warnf(format string, args ...any) {
+	l.logf(slog.LevelWarn, format, args...)
+}
+
+func (l *slogLeveledLogger) Error(msg string) {
+	l.log(slog.LevelError, msg)
+}
+
+func (l *slogLeveledLogger) Errorf(format string, args ...any) {
+	l.logf(slog.LevelError, format, args...)
+}
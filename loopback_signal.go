@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// LoopbackSignal is an in-process signal; NewLoopbackSignals returns an offer and an answer side sharing
+// an in-memory relay. Connecting one Client with each side connects their peer connections of the same
+// label without any signaling server, which is what tests need. The relay behaves like
+// WebSocketSignalServer, so trickle ICE (WithTrickleICE) and renegotiation are supported as well.
+type LoopbackSignal struct {
+	hub      *loopbackHub
+	role     string
+	sessions *messageSessions
+	ctx      context.Context
+	signalConfig
+}
+
+func NewLoopbackSignals(ctx context.Context, options ...SignalOption) (offer *LoopbackSignal, answer *LoopbackSignal) {
+	hub := &loopbackHub{rooms: make(map[string]*loopbackRoom)}
+
+	return newLoopbackSignal(ctx, hub, WebSocketRoleOffer, options...), newLoopbackSignal(ctx, hub, WebSocketRoleAnswer, options...)
+}
+
+func newLoopbackSignal(ctx context.Context, hub *loopbackHub, role string, options ...SignalOption) *LoopbackSignal {
+	return &LoopbackSignal{
+		hub:          hub,
+		role:         role,
+		sessions:     newMessageSessions("loopback"),
+		ctx:          ctx,
+		signalConfig: newSignalConfig(options...),
+	}
+}
+
+func (signal *LoopbackSignal) Connect(category string, pc *PeerConnection) error {
+	return signal.ConnectContext(signal.ctx, category, pc)
+}
+
+// ConnectContext implements ContextSignal; ctx bounds the exchange, the session itself lives as long as
+// the signal.
+func (signal *LoopbackSignal) ConnectContext(ctx context.Context, category string, pc *PeerConnection) error {
+	ctx, cancel := exchangeContext(ctx, signal.ctx)
+	defer cancel()
+
+	transport := signal.hub.join(webSocketRoomKey(category, pc.label), signal.role)
+	session := newMessageSession(signal.ctx, transport, signal.role, category, signal.trickle, pc)
+
+	return signal.sessions.connect(session, func(session *messageSession) error {
+		if signal.role == WebSocketRoleOffer {
+			return session.connectOffer(ctx)
+		}
+
+		return session.connectAnswer(ctx)
+	})
+}
+
+// Renegotiate sends a new offer over the session of pc; only the offer side renegotiates.
+func (signal *LoopbackSignal) Renegotiate(ctx context.Context, pc *PeerConnection) error {
+	if signal.role != WebSocketRoleOffer {
+		return fmt.Errorf("%w (pc=%s)", ErrRenegotiationNotOfferer, pc.label)
+	}
+
+	category, _ := pc.getSignal()
+
+	session, err := signal.sessions.get(category, pc.label)
+	if err != nil {
+		return err
+	}
+
+	return session.offer(ctx)
+}
+
+func (signal *LoopbackSignal) Close() error {
+	return signal.sessions.close()
+}
+
+type loopbackHub struct {
+	rooms map[string]*loopbackRoom
+	mux   sync.Mutex
+}
+
+type loopbackRoom struct {
+	peers   map[string]*loopbackTransport
+	pending map[string][]WebSocketMessage
+	mux     sync.Mutex
+}
+
+// join registers a new transport for the role, replacing the previous one, exactly like
+// WebSocketSignalServer does on register.
+func (hub *loopbackHub) join(key, role string) *loopbackTransport {
+	hub.mux.Lock()
+	room, exists := hub.rooms[key]
+	if !exists {
+		room = &loopbackRoom{
+			peers:   make(map[string]*loopbackTransport),
+			pending: make(map[string][]WebSocketMessage),
+		}
+		hub.rooms[key] = room
+	}
+	hub.mux.Unlock()
+
+	transport := &loopbackTransport{
+		room:   room,
+		role:   role,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	room.mux.Lock()
+	defer room.mux.Unlock()
+
+	if old, exists := room.peers[role]; exists {
+		old.shutdown()
+	}
+	room.peers[role] = transport
+
+	// NOTE: ANYTHING QUEUED FROM A PREVIOUS SESSION OF THIS ROLE IS STALE NOW
+	delete(room.pending, otherWebSocketRole(role))
+
+	for _, msg := range room.pending[role] {
+		transport.push(msg)
+	}
+	delete(room.pending, role)
+
+	return transport
+}
+
+func (room *loopbackRoom) forward(role string, msg WebSocketMessage) {
+	room.mux.Lock()
+	defer room.mux.Unlock()
+
+	if peer, exists := room.peers[role]; exists {
+		peer.push(msg)
+		return
+	}
+
+	room.pending[role] = append(room.pending[role], msg)
+}
+
+type loopbackTransport struct {
+	room   *loopbackRoom
+	role   string
+	queue  []WebSocketMessage
+	notify chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	mux    sync.Mutex
+}
+
+func (t *loopbackTransport) push(msg WebSocketMessage) {
+	t.mux.Lock()
+	t.queue = append(t.queue, msg)
+	t.mux.Unlock()
+
+	select {
+	case t.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (t *loopbackTransport) write(_ context.Context, msg WebSocketMessage) error {
+	select {
+	case <-t.done:
+		return errors.New("loopback transport closed")
+	default:
+	}
+
+	msg.Role = t.role
+	t.room.forward(otherWebSocketRole(t.role), msg)
+	return nil
+}
+
+func (t *loopbackTransport) read(ctx context.Context) (WebSocketMessage, error) {
+	for {
+		t.mux.Lock()
+		if len(t.queue) > 0 {
+			msg := t.queue[0]
+			t.queue = t.queue[1:]
+			t.mux.Unlock()
+			return msg, nil
+		}
+		t.mux.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return WebSocketMessage{}, ctx.Err()
+		case <-t.done:
+			return WebSocketMessage{}, errors.New("loopback transport closed")
+		case <-t.notify:
+		}
+	}
+}
+
+func (t *loopbackTransport) shutdown() {
+	t.once.Do(func() {
+		close(t.done)
+	})
+}
+
+func (t *loopbackTransport) close() error {
+	t.shutdown()
+
+	t.room.mux.Lock()
+	defer t.room.mux.Unlock()
+
+	if t.room.peers[t.role] == t {
+		delete(t.room.peers, t.role)
+	}
+
+	return nil
+}
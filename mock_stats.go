@@ -3,7 +3,6 @@ package client
 import (
 	"crypto/rand"
 	"encoding/base64"
-	"fmt"
 	"math"
 	"time"
 
@@ -12,8 +11,10 @@ import (
 
 type MockStatsGetter struct{}
 
-func NewMockStatsGetter() *MockStatsGetter {
-	fmt.Println("Using MOCK Stats Getter...")
+// NewMockStatsGetter creates a stats generator producing made-up stats, warning on the logger of c that
+// they are.
+func NewMockStatsGetter(c *Client) *MockStatsGetter {
+	c.logger.Warn("using mock stats getter")
 	return &MockStatsGetter{}
 }
 
@@ -131,6 +132,24 @@ func (stats *MockStatsGetter) Generate(_ *PeerConnection) Stat {
 			BytesSent:             524288, // 512KB
 			BytesReceived:         487424, // ~475KB
 		},
+
+		BandwidthEstimation: &BWESample{
+			Time:               now,
+			TargetBitrate:      1500000, // 1.5Mbps
+			SmoothedBitrate:    1450000,
+			LossTargetBitrate:  1600000,
+			AverageLoss:        0.01,
+			DelayTargetBitrate: 1500000,
+			DelayMeasurement:   2.1, // ms
+			DelayEstimate:      1.8,
+			DelayThreshold:     12.5,
+			Usage:              "normal",
+			State:              "hold",
+			Allocations: []BWEAllocation{
+				{Subscriber: "video", Allocated: 1300000, Delivered: 1300000, Updated: true},
+				{Subscriber: "audio", Allocated: 150000, Delivered: 150000},
+			},
+		},
 	}
 }
 
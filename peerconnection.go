@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"iter"
+	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pion/webrtc/v4"
 
+	internallogger "github.com/harshabose/simple_webrtc_comm/client/internal/logger"
 	"github.com/harshabose/simple_webrtc_comm/client/pkg/datachannel"
 	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
 	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
@@ -115,6 +120,8 @@ type Stat struct {
 	CodecStats           map[string]webrtc.CodecStats       `json:"codec_stats"`
 	ICETransportStat     webrtc.TransportStats              `json:"ice_transport_stat"`
 	SCTPTransportStat    webrtc.SCTPTransportStats          `json:"sctp_transport_stat"`
+	// BandwidthEstimation is the last tick of the BWEController; nil without bandwidth estimation
+	BandwidthEstimation *BWESample `json:"bandwidth_estimation,omitempty"`
 }
 
 type stat struct {
@@ -183,6 +190,13 @@ func (s *stat) Generate() Stat {
 		codecCopy[k] = v
 	}
 
+	var bandwidthEstimation *BWESample
+	if s.pc != nil && s.pc.bwc != nil {
+		if sample, ok := s.pc.bwc.Latest(); ok {
+			bandwidthEstimation = &sample
+		}
+	}
+
 	return Stat{
 		PeerConnectionStat:   s.Stat.PeerConnectionStat,
 		ICECandidatePairStat: s.ICECandidatePairStat,
@@ -190,18 +204,53 @@ func (s *stat) Generate() Stat {
 		CodecStats:           codecCopy,
 		ICETransportStat:     s.ICETransportStat,
 		SCTPTransportStat:    s.SCTPTransportStat,
+		BandwidthEstimation:  bandwidthEstimation,
 	}
 }
 
 type PeerConnection struct {
-	label          string
-	peerConnection *webrtc.PeerConnection
+	label string
+	// peerConnection is replaced when the connection is recreated; read it with GetPeerConnection
+	peerConnection atomic.Pointer[webrtc.PeerConnection]
+	api            *webrtc.API
+	config         webrtc.Configuration
+
+	// signal and category are the ones last used to connect; the reconnect supervisor reuses them
+	signal     BaseSignal
+	category   string
+	iceRestart atomic.Bool
+	supervised sync.Once
+
+	// negotiated is set once the first offer/answer exchange completed, offerer if this side sent its
+	// offer. negotiation is a lock, taken with lockNegotiation, which serialises the exchanges; it is not
+	// held while waiting for an offer
+	negotiated  atomic.Bool
+	offerer     atomic.Bool
+	negotiation chan struct{}
 
 	dataChannels *datachannel.DataChannels
 	tracks       *mediasource.Tracks
 	sinks        *mediasink.Sinks
 	bwc          *BWEController
 	stat         *stat
+	// estimators hands the bandwidth estimator pion creates to this peer connection; estimatorID is what
+	// the estimator of the current underlying connection is filed under
+	estimators  *estimatorHandoff
+	estimatorID string
+	logger      *slog.Logger
+
+	// events are the events of this peer connection; every event is published to clientEvents as well,
+	// if set, which aggregates the events of all peer connections of a Client
+	events       *eventBus
+	clientEvents *eventBus
+
+	candidateHandler func(webrtc.ICECandidateInit)
+	remoteCandidates []webrtc.ICECandidateInit
+	candidateMux     sync.Mutex
+	// connectionCtx lives as long as the current underlying connection; signals tie the goroutines of a
+	// session to it. Guarded by candidateMux
+	connectionCtx    context.Context
+	connectionCancel context.CancelFunc
 
 	cond   *cond.ContextCond
 	state  webrtc.PeerConnectionState
@@ -211,31 +260,52 @@ type PeerConnection struct {
 	cancel context.CancelFunc
 }
 
-func CreatePeerConnection(ctx context.Context, label string, api *webrtc.API, config webrtc.Configuration) (*PeerConnection, error) {
-	peerConnection, err := api.NewPeerConnection(config)
+// CreatePeerConnection creates a peer connection outside of a Client, logging with logger; a nil logger
+// discards its logs. Client.CreatePeerConnection passes the logger of the client.
+func CreatePeerConnection(ctx context.Context, label string, api *webrtc.API, config webrtc.Configuration, logger *slog.Logger) (*PeerConnection, error) {
+	return createPeerConnection(ctx, ctx, label, api, config, internallogger.OrDiscard(logger), nil, nil, false)
+}
+
+func createPeerConnection(ctx, buildCtx context.Context, label string, api *webrtc.API, config webrtc.Configuration, logger *slog.Logger, clientEvents *eventBus, estimators *estimatorHandoff, withEstimator bool) (*PeerConnection, error) {
+	estimatorID, peerConnection, err := estimators.build(buildCtx, label, withEstimator, api, config)
 	if err != nil {
 		return nil, err
 	}
 
 	ctx2, cancel2 := context.WithCancel(ctx)
+	logger = logger.With("pc", label)
 
 	pc := &PeerConnection{
-		label:          label,
-		peerConnection: peerConnection,
-		ctx:            ctx2,
-		cancel:         cancel2,
-		dataChannels:   datachannel.CreateDataChannels(ctx2),
-		bwc:            createBWController(ctx2),
-		tracks:         mediasource.CreateTracks(ctx2),
-		sinks:          mediasink.CreateSinks(ctx2, peerConnection),
-		state:          webrtc.PeerConnectionStateUnknown,
-		istate:         webrtc.ICEConnectionStateUnknown,
-		cond:           cond.NewContextCond(&sync.Mutex{}),
+		label:        label,
+		api:          api,
+		config:       config,
+		ctx:          ctx2,
+		cancel:       cancel2,
+		dataChannels: datachannel.CreateDataChannels(ctx2),
+		bwc:          createBWController(ctx2, logger),
+		tracks:       mediasource.CreateTracks(ctx2),
+		sinks:        mediasink.CreateSinks(ctx2, peerConnection),
+		state:        webrtc.PeerConnectionStateUnknown,
+		istate:       webrtc.ICEConnectionStateUnknown,
+		logger:       logger,
+		events:       newEventBus(),
+		clientEvents: clientEvents,
+		estimators:   estimators,
+		estimatorID:  estimatorID,
+		cond:         cond.NewContextCond(&sync.Mutex{}),
+		negotiation:  make(chan struct{}, 1),
 	}
 
+	pc.peerConnection.Store(peerConnection)
+	pc.connectionCtx, pc.connectionCancel = context.WithCancel(ctx2)
+	pc.dataChannels.SetLogger(pc.logger)
+	pc.tracks.SetLogger(pc.logger)
+	pc.sinks.SetLogger(pc.logger)
+	pc.dataChannels.SetStateHandler(pc.onDataChannelState)
+	pc.sinks.SetTrackHandler(pc.onSinkTrack)
 	pc.stat = newStat(pc)
 
-	return pc.onConnectionStateChangeEvent().onICEConnectionStateChange().onICEGatheringStateChange().onICECandidate(), err
+	return pc.onConnectionStateChangeEvent().onICEConnectionStateChange().onICEGatheringStateChange().onICECandidate().onSelectedCandidatePairChange().onNegotiationNeeded(), err
 }
 
 type StateCond = func(state webrtc.PeerConnectionState, istate webrtc.ICEConnectionState) bool
@@ -261,8 +331,18 @@ func (pc *PeerConnection) GetLabel() string {
 	return pc.label
 }
 
+// connectionContext is done once the current underlying connection is recreated or closed.
+func (pc *PeerConnection) connectionContext() context.Context {
+	pc.candidateMux.Lock()
+	defer pc.candidateMux.Unlock()
+
+	return pc.connectionCtx
+}
+
+// GetPeerConnection returns the underlying connection; it changes when the reconnect supervisor
+// recreates the connection.
 func (pc *PeerConnection) GetPeerConnection() *webrtc.PeerConnection {
-	return pc.peerConnection
+	return pc.peerConnection.Load()
 }
 
 func (pc *PeerConnection) GetDataChannel(label string) (*datachannel.DataChannel, error) {
@@ -294,12 +374,14 @@ func (pc *PeerConnection) MediaSinks() iter.Seq2[string, *mediasink.Sink] {
 }
 
 func (pc *PeerConnection) onConnectionStateChangeEvent() *PeerConnection {
-	pc.peerConnection.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
-		fmt.Printf("peer connection state with label changed to %s\n", state.String())
+	pc.GetPeerConnection().OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		pc.logger.Info("peer connection state changed", "state", state.String())
+		pc.emit(Event{Type: EventConnectionState, ConnectionState: state})
 		pc.cond.L.Lock()
 		defer pc.cond.L.Unlock()
 
-		pc.state = state
+		// NOTE: PION CALLS EVERY HANDLER IN ITS OWN GOROUTINE, SO THE STATES MAY ARRIVE OUT OF ORDER; KEEP THE CURRENT ONE
+		pc.state = pc.GetPeerConnection().ConnectionState()
 		pc.cond.Broadcast()
 	})
 
@@ -307,12 +389,13 @@ func (pc *PeerConnection) onConnectionStateChangeEvent() *PeerConnection {
 }
 
 func (pc *PeerConnection) onICEConnectionStateChange() *PeerConnection {
-	pc.peerConnection.OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
-		fmt.Printf("ICE Connection State changed: %s\n", state.String())
+	pc.GetPeerConnection().OnICEConnectionStateChange(func(state webrtc.ICEConnectionState) {
+		pc.logger.Info("ice connection state changed", "state", state.String())
+		pc.emit(Event{Type: EventICEConnectionState, ICEConnectionState: state})
 		pc.cond.L.Lock()
 		defer pc.cond.L.Unlock()
 
-		pc.istate = state
+		pc.istate = pc.GetPeerConnection().ICEConnectionState()
 		pc.cond.Broadcast()
 	})
 
@@ -320,33 +403,356 @@ func (pc *PeerConnection) onICEConnectionStateChange() *PeerConnection {
 }
 
 func (pc *PeerConnection) onICEGatheringStateChange() *PeerConnection {
-	pc.peerConnection.OnICEGatheringStateChange(func(state webrtc.ICEGatheringState) {
-		fmt.Printf("ICE Gathering State changed: %s\n", state.String())
+	pc.GetPeerConnection().OnICEGatheringStateChange(func(state webrtc.ICEGatheringState) {
+		pc.logger.Debug("ice gathering state changed", "state", state.String())
+		pc.emit(Event{Type: EventICEGatheringState, ICEGatheringState: state})
 	})
 	return pc
 }
 
 func (pc *PeerConnection) onICECandidate() *PeerConnection {
-	pc.peerConnection.OnICECandidate(func(candidate *webrtc.ICECandidate) {
+	pc.GetPeerConnection().OnICECandidate(func(candidate *webrtc.ICECandidate) {
 		if candidate == nil {
-			fmt.Println("ICE gathering complete")
+			pc.logger.Debug("ice gathering complete")
 			return
 		}
 
-		fmt.Printf("Found candidate: %s (type=%s)\n", candidate.String(), candidate.Typ)
+		pc.logger.Debug("found local candidate", "candidate", candidate.String(), "type", candidate.Typ.String())
+		pc.emit(Event{Type: EventLocalCandidate, Candidate: candidate})
+
+		pc.candidateMux.Lock()
+		handler := pc.candidateHandler
+		pc.candidateMux.Unlock()
+
+		if handler != nil {
+			handler(candidate.ToJSON())
+		}
 	})
 	return pc
 }
 
+func (pc *PeerConnection) onNegotiationNeeded() *PeerConnection {
+	pc.GetPeerConnection().OnNegotiationNeeded(func() {
+		// NOTE: THE FIRST EXCHANGE IS DRIVEN BY Client.Connect
+		if !pc.negotiated.Load() {
+			return
+		}
+
+		if !pc.offerer.Load() {
+			pc.logger.Warn("ignoring negotiationneeded on the answering side; only the offering side renegotiates")
+			return
+		}
+
+		go pc.renegotiate()
+	})
+	return pc
+}
+
+// ErrRenegotiationNotOfferer is returned for changes which need a renegotiation on the answering side of
+// a connected peer connection. Only the side that sent the first offer renegotiates, so that both sides
+// never offer at once; pion cannot roll back a colliding offer.
+var ErrRenegotiationNotOfferer = errors.New("only the side that sent the first offer can renegotiate; make the change there, or before connecting")
+
+// markNegotiated records that the first offer/answer exchange completed, and which side offered it.
+func (pc *PeerConnection) markNegotiated() {
+	local := pc.GetPeerConnection().CurrentLocalDescription()
+	pc.offerer.Store(local != nil && local.Type == webrtc.SDPTypeOffer)
+	pc.negotiated.Store(true)
+}
+
+// canRenegotiate fails with ErrRenegotiationNotOfferer on the answering side of a connected peer
+// connection.
+func (pc *PeerConnection) canRenegotiate() error {
+	if pc.negotiated.Load() && !pc.offerer.Load() {
+		return fmt.Errorf("%w (pc=%s)", ErrRenegotiationNotOfferer, pc.label)
+	}
+
+	return nil
+}
+
+const renegotiationTimeout = 30 * time.Second
+
+func (pc *PeerConnection) renegotiate() {
+	ctx, cancel := context.WithTimeout(pc.ctx, renegotiationTimeout)
+	defer cancel()
+
+	if err := pc.Renegotiate(ctx); err != nil {
+		pc.logger.Error("error while renegotiating", "err", err)
+	}
+}
+
+// Renegotiate runs a new offer/answer exchange over the signal the peer connection was connected with,
+// which needs to implement RenegotiationSignal. Once connected, this is done automatically whenever pion
+// fires negotiationneeded, e.g. after CreateMediaSource or RemoveMediaSource. A failed exchange is rolled
+// back so that the previous session keeps working. Only the side that sent the first offer renegotiates;
+// the answering side gets ErrRenegotiationNotOfferer.
+func (pc *PeerConnection) Renegotiate(ctx context.Context) error {
+	_, signal := pc.getSignal()
+	if signal == nil {
+		return errors.New("peer connection was never connected using a signal")
+	}
+
+	if err := pc.canRenegotiate(); err != nil {
+		return err
+	}
+
+	renegotiator, ok := signal.(RenegotiationSignal)
+	if !ok {
+		return fmt.Errorf("signal of peer connection does not support renegotiation (pc=%s)", pc.label)
+	}
+
+	if err := pc.lockNegotiation(ctx); err != nil {
+		return err
+	}
+	defer pc.unlockNegotiation()
+
+	if err := renegotiator.Renegotiate(ctx, pc); err != nil {
+		pc.rollback()
+		return err
+	}
+
+	return nil
+}
+
+// lockNegotiation waits till no other offer/answer exchange runs on the peer connection and takes the
+// negotiation lock, unless ctx is done first.
+func (pc *PeerConnection) lockNegotiation(ctx context.Context) error {
+	select {
+	case pc.negotiation <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("another negotiation is in progress (pc=%s); err: %w", pc.label, ctx.Err())
+	}
+}
+
+// tryLockNegotiation takes the negotiation lock if no other exchange runs.
+func (pc *PeerConnection) tryLockNegotiation() bool {
+	select {
+	case pc.negotiation <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (pc *PeerConnection) unlockNegotiation() {
+	<-pc.negotiation
+}
+
+// rollback discards a pending local or remote offer, returning the signaling state to stable.
+func (pc *PeerConnection) rollback() {
+	state := pc.GetPeerConnection().SignalingState()
+	if state != webrtc.SignalingStateHaveLocalOffer && state != webrtc.SignalingStateHaveRemoteOffer {
+		return
+	}
+
+	rollback := webrtc.SessionDescription{Type: webrtc.SDPTypeRollback}
+
+	var err error
+	if state == webrtc.SignalingStateHaveLocalOffer {
+		err = pc.GetPeerConnection().SetLocalDescription(rollback)
+	} else {
+		err = pc.GetPeerConnection().SetRemoteDescription(rollback)
+	}
+
+	if err != nil {
+		// NOTE: PION DOES NOT IMPLEMENT ROLLBACK YET; ANSWERING THE REMOTE OFFER WITHOUT SENDING THE ANSWER, OR
+		// APPLYING THE LAST ANSWER OF THE REMOTE TO THE LOCAL OFFER, AT LEAST RETURNS TO stable, SO THAT THE
+		// NEXT OFFER CAN BE APPLIED
+		if state == webrtc.SignalingStateHaveRemoteOffer {
+			err = pc.settleRemoteOffer()
+		} else {
+			err = pc.settleLocalOffer()
+		}
+	}
+
+	if err != nil {
+		pc.logger.Warn("error while rolling back pending offer", "err", err)
+	}
+}
+
+func (pc *PeerConnection) settleRemoteOffer() error {
+	answer, err := pc.GetPeerConnection().CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+
+	return pc.GetPeerConnection().SetLocalDescription(answer)
+}
+
+func (pc *PeerConnection) settleLocalOffer() error {
+	answer := pc.GetPeerConnection().CurrentRemoteDescription()
+	if answer == nil {
+		return errors.New("no previous answer to return to")
+	}
+
+	return pc.GetPeerConnection().SetRemoteDescription(*answer)
+}
+
+// setLocalCandidateHandler registers where locally gathered candidates are forwarded in trickle mode.
+// It must be set before SetLocalDescription, as gathering starts right after it.
+func (pc *PeerConnection) setLocalCandidateHandler(handler func(webrtc.ICECandidateInit)) {
+	pc.candidateMux.Lock()
+	defer pc.candidateMux.Unlock()
+
+	pc.candidateHandler = handler
+}
+
+// addRemoteCandidate applies a trickled remote candidate. Candidates that arrive before the remote
+// description are queued and applied by setRemoteDescription.
+func (pc *PeerConnection) addRemoteCandidate(candidate webrtc.ICECandidateInit) error {
+	pc.candidateMux.Lock()
+	defer pc.candidateMux.Unlock()
+
+	if pc.GetPeerConnection().RemoteDescription() == nil {
+		pc.remoteCandidates = append(pc.remoteCandidates, candidate)
+		return nil
+	}
+
+	if err := pc.GetPeerConnection().AddICECandidate(candidate); err != nil {
+		return fmt.Errorf("failed to add remote ICE candidate (pc=%s); err: %w", pc.label, err)
+	}
+
+	return nil
+}
+
+func (pc *PeerConnection) setRemoteDescription(description webrtc.SessionDescription) error {
+	if err := pc.GetPeerConnection().SetRemoteDescription(description); err != nil {
+		return fmt.Errorf("failed to set remote description (pc=%s); err: %w", pc.label, err)
+	}
+
+	pc.candidateMux.Lock()
+	defer pc.candidateMux.Unlock()
+
+	var merr error
+	for _, candidate := range pc.remoteCandidates {
+		if err := pc.GetPeerConnection().AddICECandidate(candidate); err != nil {
+			merr = multierr.Append(merr, err)
+		}
+	}
+	pc.remoteCandidates = nil
+
+	return merr
+}
+
+// createOffer creates an offer, restarting ICE if a restart was requested since the last offer.
+func (pc *PeerConnection) createOffer() (webrtc.SessionDescription, error) {
+	var options *webrtc.OfferOptions
+	if pc.iceRestart.Swap(false) {
+		options = &webrtc.OfferOptions{ICERestart: true}
+	}
+
+	return pc.GetPeerConnection().CreateOffer(options)
+}
+
+func (pc *PeerConnection) setSignal(category string, signal BaseSignal) {
+	pc.cond.L.Lock()
+	defer pc.cond.L.Unlock()
+
+	pc.category = category
+	pc.signal = signal
+}
+
+func (pc *PeerConnection) getSignal() (string, BaseSignal) {
+	pc.cond.L.Lock()
+	defer pc.cond.L.Unlock()
+
+	return pc.category, pc.signal
+}
+
+// restartICE runs the last used signal again with an ICE restart offer, till ctx is done. On the
+// answering side the signal simply waits for the restart offer from the remote. The signal takes the
+// negotiation lock for the exchange itself, so renegotiations are not blocked while it waits for the
+// remote.
+func (pc *PeerConnection) restartICE(ctx context.Context) error {
+	category, signal := pc.getSignal()
+	if signal == nil {
+		return errors.New("peer connection was never connected using a signal")
+	}
+
+	pc.iceRestart.Store(true)
+	return connectSignal(ctx, signal, category, pc)
+}
+
+// recreate tears down the underlying webrtc.PeerConnection and builds a new one with the same API and
+// configuration. Data channels, media sources and media sinks are re-registered on the new one; the
+// caller is expected to signal it again.
+func (pc *PeerConnection) recreate() error {
+	estimatorID, peerConnection, err := pc.estimators.build(pc.ctx, pc.label, pc.bwc != nil && pc.bwc.get() != nil, pc.api, pc.config)
+	if err != nil {
+		return err
+	}
+	pc.estimatorID = estimatorID
+
+	// NOTE: candidateMux IS HELD AS WELL, AS REMOTE CANDIDATES ARE ADDED FROM SIGNALING GOROUTINES
+	pc.candidateMux.Lock()
+	pc.cond.L.Lock()
+	old := pc.peerConnection.Swap(peerConnection)
+	pc.state = webrtc.PeerConnectionStateUnknown
+	pc.istate = webrtc.ICEConnectionStateUnknown
+	pc.cond.L.Unlock()
+
+	pc.candidateHandler = nil
+	pc.remoteCandidates = nil
+	pc.connectionCancel()
+	pc.connectionCtx, pc.connectionCancel = context.WithCancel(pc.ctx)
+	pc.candidateMux.Unlock()
+
+	pc.iceRestart.Store(false)
+	pc.negotiated.Store(false)
+	pc.offerer.Store(false)
+
+	// NOTE: THE OLD HANDLERS ARE REPLACED SO THAT CLOSING THE OLD CONNECTION DOES NOT UPDATE THE NEW STATE
+	old.OnConnectionStateChange(func(webrtc.PeerConnectionState) {})
+	old.OnICEConnectionStateChange(func(webrtc.ICEConnectionState) {})
+	old.OnICEGatheringStateChange(func(webrtc.ICEGatheringState) {})
+	old.OnICECandidate(func(*webrtc.ICECandidate) {})
+	old.OnTrack(func(*webrtc.TrackRemote, *webrtc.RTPReceiver) {})
+	old.OnNegotiationNeeded(func() {})
+	old.SCTP().Transport().ICETransport().OnSelectedCandidatePairChange(func(*webrtc.ICECandidatePair) {})
+
+	if err := old.Close(); err != nil {
+		pc.logger.Warn("error while closing old peer connection", "err", err)
+	}
+
+	pc.onConnectionStateChangeEvent().onICEConnectionStateChange().onICEGatheringStateChange().onICECandidate().onSelectedCandidatePairChange().onNegotiationNeeded()
+
+	if err := pc.dataChannels.Rebind(peerConnection); err != nil {
+		return err
+	}
+	if err := pc.tracks.Rebind(peerConnection); err != nil {
+		return err
+	}
+	pc.sinks.Rebind(peerConnection)
+
+	pc.cond.Broadcast()
+	return nil
+}
+
+// waitForICEGathering blocks till ICE gathering completes, unless trickle is set in which case
+// candidates are exchanged separately and the local description can be sent right away.
+func (pc *PeerConnection) waitForICEGathering(ctx context.Context, trickle bool) error {
+	if trickle {
+		return nil
+	}
+
+	select {
+	case <-webrtc.GatheringCompletePromise(pc.GetPeerConnection()):
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to gather ICE candidates within context deadline; err: %w", ctx.Err())
+	}
+}
+
 func (pc *PeerConnection) GetState() webrtc.PeerConnectionState {
-	return pc.peerConnection.ConnectionState()
+	return pc.GetPeerConnection().ConnectionState()
 }
 
 func (pc *PeerConnection) CreateDataChannel(label string, options ...datachannel.Option) (*datachannel.DataChannel, error) {
 	if pc.dataChannels == nil {
 		return nil, errors.New("data channels are not enabled")
 	}
-	channel, err := pc.dataChannels.CreateDataChannel(label, pc.peerConnection, options...)
+	channel, err := pc.dataChannels.CreateDataChannel(label, pc.GetPeerConnection(), options...)
 	if err != nil {
 		return nil, err
 	}
@@ -354,12 +760,19 @@ func (pc *PeerConnection) CreateDataChannel(label string, options ...datachannel
 	return channel, nil
 }
 
+// CreateMediaSource adds a media source to the peer connection. Once connected, it reaches the remote
+// with an automatic renegotiation, which only the side that sent the first offer can start; the
+// answering side gets ErrRenegotiationNotOfferer.
 func (pc *PeerConnection) CreateMediaSource(label string, options ...mediasource.TrackOption) (*mediasource.Track, error) {
 	if pc.tracks == nil {
 		return nil, errors.New("media source are not enabled")
 	}
 
-	track, err := pc.tracks.CreateTrack(label, pc.peerConnection, options...)
+	if err := pc.canRenegotiate(); err != nil {
+		return nil, err
+	}
+
+	track, err := pc.tracks.CreateTrack(label, pc.GetPeerConnection(), options...)
 	if err != nil {
 		return nil, err
 	}
@@ -372,7 +785,11 @@ func (pc *PeerConnection) CreateRTPMediaSource(label string, options ...mediasou
 		return nil, errors.New("media source are not enabled")
 	}
 
-	track, err := pc.tracks.CreateRTPTrack(label, pc.peerConnection, options...)
+	if err := pc.canRenegotiate(); err != nil {
+		return nil, err
+	}
+
+	track, err := pc.tracks.CreateRTPTrack(label, pc.GetPeerConnection(), options...)
 	if err != nil {
 		return nil, err
 	}
@@ -380,6 +797,33 @@ func (pc *PeerConnection) CreateRTPMediaSource(label string, options ...mediasou
 	return track, nil
 }
 
+// RemoveMediaSource detaches the media source from the peer connection and stops its RTPSender. The
+// removal reaches the remote with the next renegotiation, which is started automatically once connected,
+// and, like CreateMediaSource, only on the side that sent the first offer.
+func (pc *PeerConnection) RemoveMediaSource(label string) error {
+	if pc.tracks == nil {
+		return errors.New("media source are not enabled")
+	}
+
+	if err := pc.canRenegotiate(); err != nil {
+		return err
+	}
+
+	return pc.tracks.RemoveTrack(label, pc.GetPeerConnection())
+}
+
+func (pc *PeerConnection) RemoveRTPMediaSource(label string) error {
+	if pc.tracks == nil {
+		return errors.New("media source are not enabled")
+	}
+
+	if err := pc.canRenegotiate(); err != nil {
+		return err
+	}
+
+	return pc.tracks.RemoveRTPTrack(label, pc.GetPeerConnection())
+}
+
 func (pc *PeerConnection) CreateMediaSink(label string, options ...mediasink.SinkOption) (*mediasink.Sink, error) {
 	if pc.sinks == nil {
 		return nil, errors.New("media sinks are not enabled")
@@ -393,6 +837,74 @@ func (pc *PeerConnection) CreateMediaSink(label string, options ...mediasink.Sin
 	return sink, nil
 }
 
+// RecordMediaSink records everything the sink receives to disk, see mediasink.RecorderConfig. The
+// recording is finalised when the peer connection is closed, or earlier with the recorder's Close.
+func (pc *PeerConnection) RecordMediaSink(label string, config mediasink.RecorderConfig) (*mediasink.Recorder, error) {
+	if pc.sinks == nil {
+		return nil, errors.New("media sinks are not enabled")
+	}
+
+	return pc.sinks.Record(label, config)
+}
+
+// ForwardMediaSink re-sends everything the sink receives as plain RTP to a UDP address and writes the
+// matching SDP, see mediasink.ForwarderConfig. Forwarding stops when the peer connection is closed, or
+// earlier with the forwarder's Close.
+func (pc *PeerConnection) ForwardMediaSink(label string, config mediasink.ForwarderConfig) (*mediasink.Forwarder, error) {
+	if pc.sinks == nil {
+		return nil, errors.New("media sinks are not enabled")
+	}
+
+	return pc.sinks.Forward(label, config)
+}
+
+// AddMediaSinkOutput closes output, e.g. a transcode.SinkMuxer reading sinks of this peer connection,
+// when the peer connection is closed.
+func (pc *PeerConnection) AddMediaSinkOutput(output io.Closer) error {
+	if pc.sinks == nil {
+		return errors.New("media sinks are not enabled")
+	}
+
+	pc.sinks.AddOutput(output)
+	return nil
+}
+
+// SetAutoSinkFactory makes the peer connection create sinks with factory for remote tracks no sink is
+// registered for, e.g. mediasink.AutoSinkByTrackID to accept whatever the peer sends. The sinks are
+// reported with EventTrack and can be fetched with GetMediaSink.
+func (pc *PeerConnection) SetAutoSinkFactory(factory mediasink.AutoSinkFactory) error {
+	if pc.sinks == nil {
+		return errors.New("media sinks are not enabled")
+	}
+
+	pc.sinks.SetAutoSinkFactory(factory)
+	return nil
+}
+
+// RemoveMediaSink stops the transceiver the sink receives from and forgets the sink. A transceiver which
+// also sends a media source of this side is kept, as stopping it would stop that source too. Stopping a
+// transceiver does not fire negotiationneeded in pion, so the renegotiation is started here; on the
+// answering side of a connected peer connection this fails with ErrRenegotiationNotOfferer.
+func (pc *PeerConnection) RemoveMediaSink(label string) error {
+	if pc.sinks == nil {
+		return errors.New("media sinks are not enabled")
+	}
+
+	if err := pc.canRenegotiate(); err != nil {
+		return err
+	}
+
+	if err := pc.sinks.RemoveSink(label, pc.GetPeerConnection()); err != nil {
+		return err
+	}
+
+	if pc.negotiated.Load() {
+		go pc.renegotiate()
+	}
+
+	return nil
+}
+
 func (pc *PeerConnection) GetBWEstimator() (*BWEController, error) {
 	if pc.bwc == nil {
 		return nil, errors.New("bitrate control is not enabled")
@@ -412,9 +924,14 @@ func (pc *PeerConnection) Close() error {
 			pc.cancel()
 		}
 
-		if err := pc.peerConnection.Close(); err != nil {
+		if err := pc.GetPeerConnection().Close(); err != nil {
 			merr = multierr.Append(merr, err)
-			return
+		}
+
+		if pc.sinks != nil {
+			if err := pc.sinks.Close(); err != nil {
+				merr = multierr.Append(merr, err)
+			}
 		}
 
 		if pc.bwc != nil {
@@ -0,0 +1,231 @@
+// Package clienttest connects two client.Client instances in one process over the host loopback, using
+// client.LoopbackSignal, and provides assertions for tests built on top of the client package.
+package clienttest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/ice/v4"
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media"
+
+	"github.com/harshabose/simple_webrtc_comm/client"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/datachannel"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+// DefaultTimeout bounds every helper of this package.
+var DefaultTimeout = 10 * time.Second
+
+// SettingEngine only gathers IPv4 host candidates on the loopback interface and disables mDNS, so that
+// connecting does not depend on the network the test runs on.
+func SettingEngine() *webrtc.SettingEngine {
+	settings := &webrtc.SettingEngine{}
+	settings.SetIncludeLoopbackCandidate(true)
+	settings.SetIPFilter(func(ip net.IP) bool {
+		return ip.IsLoopback()
+	})
+	settings.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	settings.SetICEMulticastDNSMode(ice.MulticastDNSModeDisabled)
+
+	return settings
+}
+
+// Pair is an offering and an answering Client. Peer connections created with the same label on both are
+// connected to each other by Connect.
+type Pair struct {
+	Offer  *client.Client
+	Answer *client.Client
+
+	signals []client.BaseSignal
+	ctx     context.Context
+}
+
+// NewPair creates both clients with SettingEngine; options are applied to both, so media tests need to
+// pass a media engine option such as client.WithDefaultMediaEngine. Everything is closed on test cleanup.
+func NewPair(tb testing.TB, options ...client.ClientOption) *Pair {
+	tb.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	offer, err := client.NewClient(ctx, nil, nil, SettingEngine(), options...)
+	if err != nil {
+		cancel()
+		tb.Fatalf("error while creating offer client: %v", err)
+	}
+
+	answer, err := client.NewClient(ctx, nil, nil, SettingEngine(), options...)
+	if err != nil {
+		cancel()
+		tb.Fatalf("error while creating answer client: %v", err)
+	}
+
+	pair := &Pair{
+		Offer:  offer,
+		Answer: answer,
+		ctx:    ctx,
+	}
+
+	tb.Cleanup(func() {
+		for _, signal := range pair.signals {
+			_ = signal.Close()
+		}
+		offer.Close()
+		answer.Close()
+		cancel()
+	})
+
+	return pair
+}
+
+// PeerConnections creates a peer connection with the given label on both clients.
+func (pair *Pair) PeerConnections(tb testing.TB, label string) (offer *client.PeerConnection, answer *client.PeerConnection) {
+	tb.Helper()
+
+	offer, err := pair.Offer.CreatePeerConnection(label, webrtc.Configuration{})
+	if err != nil {
+		tb.Fatalf("error while creating offer peer connection (label=%s): %v", label, err)
+	}
+
+	answer, err = pair.Answer.CreatePeerConnection(label, webrtc.Configuration{})
+	if err != nil {
+		tb.Fatalf("error while creating answer peer connection (label=%s): %v", label, err)
+	}
+
+	return offer, answer
+}
+
+// Connect connects every peer connection of both clients over a new LoopbackSignal pair and waits till the
+// offering side reports them connected. Signal options, e.g. client.WithTrickleICE, apply to both sides.
+func (pair *Pair) Connect(tb testing.TB, options ...client.SignalOption) {
+	tb.Helper()
+
+	ctx, cancel := context.WithTimeout(pair.ctx, DefaultTimeout)
+	defer cancel()
+
+	offer, answer := client.NewLoopbackSignals(pair.ctx, options...)
+	pair.signals = append(pair.signals, offer, answer)
+
+	var (
+		wg        sync.WaitGroup
+		answerErr error
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		answerErr = pair.Answer.Connect("clienttest", answer)
+	}()
+
+	if err := pair.Offer.Connect("clienttest", offer); err != nil {
+		tb.Fatalf("error while connecting offer client: %v", err)
+	}
+
+	wg.Wait()
+	if answerErr != nil {
+		tb.Fatalf("error while connecting answer client: %v", answerErr)
+	}
+
+	for label, pc := range pair.Offer.PeerConnections() {
+		if err := pc.WaitTill(ctx, func(state webrtc.PeerConnectionState, _ webrtc.ICEConnectionState) bool {
+			return state != webrtc.PeerConnectionStateConnected
+		}); err != nil {
+			tb.Fatalf("peer connection (label=%s) did not connect: %v", label, err)
+		}
+	}
+}
+
+// AssertDataChannelOpen fails the test if the data channel does not open within DefaultTimeout.
+func AssertDataChannelOpen(tb testing.TB, dc *datachannel.DataChannel) {
+	tb.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	if err := dc.WaitTillOpen(ctx); err != nil {
+		tb.Fatalf("data channel (label=%s) did not open: %v", dc.GetLabel(), err)
+	}
+}
+
+// AssertMediaFlows writes samples to source till sink reads an RTP packet, failing the test if none
+// arrives within DefaultTimeout.
+func AssertMediaFlows(tb testing.TB, source *mediasource.Track, sink *mediasink.Sink) {
+	tb.Helper()
+
+	assertMediaFlows(tb, sink, func() error {
+		return source.WriteSample(media.Sample{Data: samplePayload, Duration: sampleInterval})
+	})
+}
+
+// AssertRTPMediaFlows is AssertMediaFlows for RTP media sources.
+func AssertRTPMediaFlows(tb testing.TB, source *mediasource.RTPTrack, sink *mediasink.Sink) {
+	tb.Helper()
+
+	packetizer := newPacketizer()
+	assertMediaFlows(tb, sink, func() error {
+		return source.WriteRTP(packetizer.next())
+	})
+}
+
+// AssertBitrateUpdates subscribes to the bandwidth estimator of pc and fails the test if no bitrate
+// update arrives within DefaultTimeout. The peer connection needs to be created with
+// client.Client.CreatePeerConnectionWithBWEstimator, and client.WithBandwidthControlInterceptor needs to be
+// passed to NewPair before client.WithTWCCHeaderExtensionSender.
+func AssertBitrateUpdates(tb testing.TB, pc *client.PeerConnection) {
+	tb.Helper()
+
+	bwc, err := pc.GetBWEstimator()
+	if err != nil {
+		tb.Fatalf("no bandwidth estimator on peer connection (label=%s): %v", pc.GetLabel(), err)
+	}
+
+	updates := make(chan int64, 1)
+	id := "clienttest-" + pc.GetLabel()
+
+	if err := bwc.Subscribe(id, mediasource.Level1, func(bps int64) error {
+		select {
+		case updates <- bps:
+		default:
+		}
+		return nil
+	}); err != nil {
+		tb.Fatalf("error while subscribing to bandwidth estimator: %v", err)
+	}
+	defer bwc.Unsubscribe(id)
+
+	select {
+	case <-updates:
+	case <-time.After(DefaultTimeout):
+		tb.Fatalf("no bitrate update within %s (label=%s)", DefaultTimeout, pc.GetLabel())
+	}
+}
+
+func assertMediaFlows(tb testing.TB, sink *mediasink.Sink, write func() error) {
+	tb.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(sampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = write()
+			}
+		}
+	}()
+
+	if _, _, err := sink.ReadRTP(ctx); err != nil {
+		tb.Fatalf("no media reached the sink: %v", err)
+	}
+}
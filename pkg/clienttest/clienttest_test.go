@@ -0,0 +1,101 @@
+package clienttest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/clienttest"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/datachannel"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+func TestDataChannelOpens(t *testing.T) {
+	pair := clienttest.NewPair(t)
+	offer, answer := pair.PeerConnections(t, "data")
+
+	negotiated := datachannel.WithDataChannelInit(&webrtc.DataChannelInit{Negotiated: &datachannel.NegotiatedTrue, ID: &datachannel.IDOne})
+
+	offerDC, err := offer.CreateDataChannel("control", negotiated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	answerDC, err := answer.CreateDataChannel("control", negotiated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pair.Connect(t)
+
+	clienttest.AssertDataChannelOpen(t, offerDC)
+	clienttest.AssertDataChannelOpen(t, answerDC)
+}
+
+func TestMediaFlows(t *testing.T) {
+	for _, trickle := range []bool{false, true} {
+		name := "gathered"
+		if trickle {
+			name = "trickle"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			pair := clienttest.NewPair(t, client.WithDefaultMediaEngine())
+			offer, answer := pair.PeerConnections(t, "media")
+
+			source, err := offer.CreateMediaSource("video", mediasource.WithVP8Track(90000))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sink, err := answer.CreateMediaSink("video", mediasink.WithVP8Track(90000))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var options []client.SignalOption
+			if trickle {
+				options = append(options, client.WithTrickleICE())
+			}
+			pair.Connect(t, options...)
+
+			clienttest.AssertMediaFlows(t, source, sink)
+		})
+	}
+}
+
+func TestCreateMediaSourceAfterConnect(t *testing.T) {
+	pair := clienttest.NewPair(t, client.WithDefaultMediaEngine())
+	offer, answer := pair.PeerConnections(t, "media")
+
+	source, err := offer.CreateMediaSource("video", mediasource.WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := answer.CreateMediaSink("video", mediasink.WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pair.Connect(t)
+	clienttest.AssertMediaFlows(t, source, sink)
+
+	// NOTE: THE OFFERING SIDE RENEGOTIATES THE NEW SOURCE AUTOMATICALLY
+	screen, err := answer.CreateMediaSink("screen", mediasink.WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	screenSource, err := offer.CreateMediaSource("screen", mediasource.WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clienttest.AssertMediaFlows(t, screenSource, screen)
+
+	// NOTE: THE ANSWERING SIDE CANNOT OFFER, SO IT REFUSES THE SOURCE INSTEAD OF NEVER NEGOTIATING IT
+	if _, err := answer.CreateMediaSource("camera", mediasource.WithVP8Track(90000)); !errors.Is(err, client.ErrRenegotiationNotOfferer) {
+		t.Fatalf("expected the answering side to refuse the source, got %v", err)
+	}
+
+	clienttest.AssertMediaFlows(t, source, sink)
+}
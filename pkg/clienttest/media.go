@@ -0,0 +1,37 @@
+package clienttest
+
+import (
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const sampleInterval = 20 * time.Millisecond
+
+// samplePayload is not a valid frame of any codec; the payloaders only split it into packets.
+var samplePayload = []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a, 0x00, 0x00}
+
+type packetizer struct {
+	sequenceNumber uint16
+	timestamp      uint32
+}
+
+func newPacketizer() *packetizer {
+	return &packetizer{}
+}
+
+// next returns a minimal packet; the track rewrites SSRC and payload type when writing it.
+func (p *packetizer) next() *rtp.Packet {
+	p.sequenceNumber++
+	p.timestamp += uint32(sampleInterval.Seconds() * 90000)
+
+	return &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         true,
+			SequenceNumber: p.sequenceNumber,
+			Timestamp:      p.timestamp,
+		},
+		Payload: samplePayload,
+	}
+}
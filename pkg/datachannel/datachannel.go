@@ -5,18 +5,24 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"log/slog"
 	"sync"
 
 	"github.com/pion/webrtc/v4"
 
 	"github.com/harshabose/tools/pkg/cond"
 	"github.com/harshabose/tools/pkg/multierr"
+
+	"github.com/harshabose/simple_webrtc_comm/client/internal/logger"
 )
 
 type DataChannel struct {
 	label       string
 	datachannel *webrtc.DataChannel
 	init        *webrtc.DataChannelInit
+	local       bool
+	onState     StateHandler
+	logger      *slog.Logger
 	cond        *cond.ContextCond
 	ctx         context.Context
 }
@@ -25,6 +31,8 @@ func CreateDataChannel(ctx context.Context, label string, peerConnection *webrtc
 	dc := &DataChannel{
 		label:       label,
 		datachannel: nil,
+		local:       true,
+		logger:      packageLogger.Get(),
 		cond:        cond.NewContextCond(&sync.Mutex{}),
 		ctx:         ctx,
 	}
@@ -34,6 +42,7 @@ func CreateDataChannel(ctx context.Context, label string, peerConnection *webrtc
 			return nil, err
 		}
 	}
+	dc.logger = dc.logger.With("channel", label)
 
 	datachannel, err := peerConnection.CreateDataChannel(label, dc.init)
 	if err != nil {
@@ -45,16 +54,40 @@ func CreateDataChannel(ctx context.Context, label string, peerConnection *webrtc
 	return dc.onOpen().onClose(), nil
 }
 
-func CreateRawDataChannel(ctx context.Context, channel *webrtc.DataChannel) (*DataChannel, error) {
+func CreateRawDataChannel(ctx context.Context, channel *webrtc.DataChannel, options ...Option) (*DataChannel, error) {
 	dataChannel := &DataChannel{
 		label:       channel.Label(),
 		datachannel: channel,
+		logger:      packageLogger.Get(),
+		cond:        cond.NewContextCond(&sync.Mutex{}),
 		ctx:         ctx,
 	}
 
+	for _, option := range options {
+		if err := option(dataChannel); err != nil {
+			return nil, err
+		}
+	}
+	dataChannel.logger = dataChannel.logger.With("channel", channel.Label())
+
 	return dataChannel.onOpen().onClose(), nil
 }
 
+// rebind creates the channel again with the same init on another peer connection.
+func (dc *DataChannel) rebind(peerConnection *webrtc.PeerConnection) error {
+	datachannel, err := peerConnection.CreateDataChannel(dc.label, dc.init)
+	if err != nil {
+		return err
+	}
+
+	dc.cond.L.Lock()
+	dc.datachannel = datachannel
+	dc.cond.L.Unlock()
+
+	dc.onOpen().onClose()
+	return nil
+}
+
 func (dc *DataChannel) GetLabel() string {
 	return dc.label
 }
@@ -70,7 +103,8 @@ func (dc *DataChannel) Close() error {
 func (dc *DataChannel) onOpen() *DataChannel {
 	dc.datachannel.OnOpen(func() {
 		dc.cond.Broadcast()
-		fmt.Printf("data channel (id=%s) opened\n", dc.datachannel.Label())
+		dc.logger.Info("data channel opened")
+		dc.notify(webrtc.DataChannelStateOpen)
 	})
 
 	return dc
@@ -78,11 +112,18 @@ func (dc *DataChannel) onOpen() *DataChannel {
 
 func (dc *DataChannel) onClose() *DataChannel {
 	dc.datachannel.OnClose(func() {
-		fmt.Printf("data channel (id=%s) closed\n", dc.datachannel.Label())
+		dc.logger.Info("data channel closed")
+		dc.notify(webrtc.DataChannelStateClosed)
 	})
 	return dc
 }
 
+func (dc *DataChannel) notify(state webrtc.DataChannelState) {
+	if dc.onState != nil {
+		dc.onState(dc.label, state)
+	}
+}
+
 func (dc *DataChannel) WaitTillOpen(ctx context.Context) error {
 	dc.cond.L.Lock()
 	defer dc.cond.L.Unlock()
@@ -104,22 +145,48 @@ func (dc *DataChannel) DataChannel() *webrtc.DataChannel {
 
 type DataChannels struct {
 	datachannel map[string]*DataChannel
+	logger      *slog.Logger
+	onState     StateHandler
 	ctx         context.Context
 }
 
+// packageLogger is what data channels log with till WithLogger or DataChannels.SetLogger says otherwise.
+var packageLogger logger.Package
+
+// SetLogger sets the logger of data channels that were given none; nil restores slog.Default().
+func SetLogger(l *slog.Logger) {
+	packageLogger.Set(l)
+}
+
 func CreateDataChannels(ctx context.Context) *DataChannels {
 	return &DataChannels{
 		datachannel: map[string]*DataChannel{},
+		logger:      packageLogger.Get(),
 		ctx:         ctx,
 	}
 }
 
+// SetLogger sets the logger the data channels created from now on log with. Options given to
+// CreateDataChannel still take precedence.
+func (dataChannels *DataChannels) SetLogger(logger *slog.Logger) {
+	dataChannels.logger = logger
+}
+
+// SetStateHandler sets the handler the data channels created from now on report opening and closing to.
+func (dataChannels *DataChannels) SetStateHandler(handler StateHandler) {
+	dataChannels.onState = handler
+}
+
+func (dataChannels *DataChannels) options(options []Option) []Option {
+	return append([]Option{WithLogger(dataChannels.logger), WithStateHandler(dataChannels.onState)}, options...)
+}
+
 func (dataChannels *DataChannels) CreateDataChannel(label string, peerConnection *webrtc.PeerConnection, options ...Option) (*DataChannel, error) {
 	if _, exits := dataChannels.datachannel[label]; exits {
 		return nil, fmt.Errorf("datachannel with id = '%s' already exists", label)
 	}
 
-	channel, err := CreateDataChannel(dataChannels.ctx, label, peerConnection, options...)
+	channel, err := CreateDataChannel(dataChannels.ctx, label, peerConnection, dataChannels.options(options)...)
 	if err != nil {
 		return nil, err
 	}
@@ -134,7 +201,7 @@ func (dataChannels *DataChannels) CreateRawDataChannel(channel *webrtc.DataChann
 		return nil, fmt.Errorf("data channel already exists with label: %s", channel.Label())
 	}
 
-	dataChannel, err := CreateRawDataChannel(dataChannels.ctx, channel)
+	dataChannel, err := CreateRawDataChannel(dataChannels.ctx, channel, dataChannels.options(nil)...)
 	if err != nil {
 		return nil, err
 	}
@@ -144,6 +211,25 @@ func (dataChannels *DataChannels) CreateRawDataChannel(channel *webrtc.DataChann
 	return dataChannel, nil
 }
 
+// Rebind recreates the locally created data channels on the given peer connection. This is used when the
+// underlying peer connection is recreated; the DataChannel values handed out earlier remain valid, but the
+// inner webrtc.DataChannel (and anything detached from it) must be fetched again. Channels that were
+// created by the remote are dropped, as the remote will announce them again.
+func (dataChannels *DataChannels) Rebind(peerConnection *webrtc.PeerConnection) error {
+	for label, datachannel := range dataChannels.datachannel {
+		if !datachannel.local {
+			delete(dataChannels.datachannel, label)
+			continue
+		}
+
+		if err := datachannel.rebind(peerConnection); err != nil {
+			return fmt.Errorf("failed to rebind datachannel (label=%s); err: %w", label, err)
+		}
+	}
+
+	return nil
+}
+
 func (dataChannels *DataChannels) GetDataChannel(label string) (*DataChannel, error) {
 	dataChannel, exists := dataChannels.datachannel[label]
 	if !exists {
@@ -1,6 +1,10 @@
 package datachannel
 
-import "github.com/pion/webrtc/v4"
+import (
+	"log/slog"
+
+	"github.com/pion/webrtc/v4"
+)
 
 type Option = func(*DataChannel) error
 
@@ -11,6 +15,27 @@ func WithDataChannelInit(init *webrtc.DataChannelInit) Option {
 	}
 }
 
+// WithLogger sets the logger of the data channel; the channel label is added to it.
+func WithLogger(logger *slog.Logger) Option {
+	return func(channel *DataChannel) error {
+		if logger != nil {
+			channel.logger = logger
+		}
+		return nil
+	}
+}
+
+// StateHandler is called when a data channel opens or closes.
+type StateHandler = func(label string, state webrtc.DataChannelState)
+
+// WithStateHandler sets the handler the data channel reports opening and closing to.
+func WithStateHandler(handler StateHandler) Option {
+	return func(channel *DataChannel) error {
+		channel.onState = handler
+		return nil
+	}
+}
+
 var (
 	OrderedTrue              = true
 	MaxRetransmits    uint16 = 2  // either MaxRetransmits or MaxPacketLifeTime can be specified at once
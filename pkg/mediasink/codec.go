@@ -0,0 +1,161 @@
+package mediasink
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// CodecMatcher decides whether a remote track with codec remote can be received by a sink expecting
+// codec sink. It returns nil on a match and otherwise an error telling why they do not match, usually a
+// *CodecMismatchError.
+type CodecMatcher = func(remote, sink webrtc.RTPCodecParameters) error
+
+// CodecMismatchError lists every reason a remote codec was rejected by a sink.
+type CodecMismatchError struct {
+	Remote  webrtc.RTPCodecParameters
+	Sink    webrtc.RTPCodecParameters
+	Reasons []string
+}
+
+func (e *CodecMismatchError) Error() string {
+	return fmt.Sprintf("remote codec %s (pt=%d) does not match sink codec %s (pt=%d): %s", e.Remote.MimeType, e.Remote.PayloadType, e.Sink.MimeType, e.Sink.PayloadType, strings.Join(e.Reasons, "; "))
+}
+
+// DefaultCodecMatcher matches on MIME type, clock rate, channels and the fmtp parameters which change
+// how the stream has to be decoded (see fmtpDifferences). The payload type is ignored, as it is picked
+// dynamically by whoever sends the offer; browsers for example often negotiate H.264 as 125 or 127.
+func DefaultCodecMatcher(remote, sink webrtc.RTPCodecParameters) error {
+	var reasons []string
+
+	if !strings.EqualFold(remote.MimeType, sink.MimeType) {
+		reasons = append(reasons, fmt.Sprintf("mime type %s != %s", remote.MimeType, sink.MimeType))
+	}
+
+	if remote.ClockRate != sink.ClockRate {
+		reasons = append(reasons, fmt.Sprintf("clock rate %d != %d", remote.ClockRate, sink.ClockRate))
+	}
+
+	if remote.Channels != sink.Channels && !(isZeroOrOneChannel(remote.Channels) && isZeroOrOneChannel(sink.Channels)) {
+		reasons = append(reasons, fmt.Sprintf("channels %d != %d", remote.Channels, sink.Channels))
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, fmtpDifferences(remote.MimeType, remote.SDPFmtpLine, sink.SDPFmtpLine)...)
+	}
+
+	if len(reasons) > 0 {
+		return &CodecMismatchError{Remote: remote, Sink: sink, Reasons: reasons}
+	}
+
+	return nil
+}
+
+// StrictCodecMatcher is DefaultCodecMatcher which also needs the payload types to be equal; this was the
+// behaviour of the sinks before payload types were matched dynamically.
+func StrictCodecMatcher(remote, sink webrtc.RTPCodecParameters) error {
+	err := DefaultCodecMatcher(remote, sink)
+	if remote.PayloadType == sink.PayloadType {
+		return err
+	}
+
+	reason := fmt.Sprintf("payload type %d != %d", remote.PayloadType, sink.PayloadType)
+	if mismatch, ok := err.(*CodecMismatchError); ok {
+		mismatch.Reasons = append([]string{reason}, mismatch.Reasons...)
+		return mismatch
+	}
+
+	return &CodecMismatchError{Remote: remote, Sink: sink, Reasons: []string{reason}}
+}
+
+// NOTE: SDP OMITS THE CHANNEL COUNT OF MONO AND VIDEO CODECS, SO 0 AND 1 ARE THE SAME
+func isZeroOrOneChannel(channels uint16) bool {
+	return channels <= 1
+}
+
+// fmtpDifferences compares the fmtp parameters that matter for the codec:
+//   - H.264: packetization-mode (defaults to 0) and the profile of profile-level-id; the level is
+//     negotiable and thus ignored
+//   - H.265: profile-id (defaults to 1)
+//   - VP9: profile-id (defaults to 0)
+//   - AV1: profile (defaults to 0)
+//
+// Everything else in the fmtp line is ignored. A sink without fmtp line accepts any value.
+func fmtpDifferences(mimeType string, remoteLine, sinkLine string) []string {
+	if sinkLine == "" {
+		return nil
+	}
+
+	remote := parseFmtp(remoteLine)
+	sink := parseFmtp(sinkLine)
+
+	var reasons []string
+	compare := func(key, fallback string, normalise func(string) string) {
+		a, b := fallback, fallback
+		if value, ok := remote[key]; ok {
+			a = value
+		}
+		if value, ok := sink[key]; ok {
+			b = value
+		}
+		if normalise != nil {
+			a, b = normalise(a), normalise(b)
+		}
+		if a != b {
+			reasons = append(reasons, fmt.Sprintf("fmtp %s %s != %s", key, a, b))
+		}
+	}
+
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		compare("packetization-mode", "0", nil)
+		compare("profile-level-id", "42e01f", h264Profile)
+	case strings.ToLower(webrtc.MimeTypeH265):
+		compare("profile-id", "1", nil)
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		compare("profile-id", "0", nil)
+	case strings.ToLower(webrtc.MimeTypeAV1):
+		compare("profile", "0", nil)
+	}
+
+	return reasons
+}
+
+// h264Profile keeps profile_idc and profile-iop of a profile-level-id and drops the level. Constrained
+// baseline is signalled in both 42e0 and 42c0 forms (and more), so only the constraint_set1 flag, the
+// one telling it apart from plain baseline, is kept of the baseline profile-iop.
+func h264Profile(profileLevelID string) string {
+	profileLevelID = strings.ToLower(profileLevelID)
+	if len(profileLevelID) != 6 {
+		return profileLevelID
+	}
+
+	profileIDC, profileIOP := profileLevelID[:2], profileLevelID[2:4]
+	if profileIDC == "42" {
+		var iop uint8
+		if _, err := fmt.Sscanf(profileIOP, "%02x", &iop); err != nil {
+			return profileLevelID
+		}
+		if iop&0x40 != 0 {
+			return "42 constrained"
+		}
+		return "42"
+	}
+
+	return profileIDC + profileIOP
+}
+
+func parseFmtp(line string) map[string]string {
+	parameters := make(map[string]string)
+
+	for _, parameter := range strings.Split(line, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(parameter), "=")
+		if key == "" {
+			continue
+		}
+		parameters[strings.ToLower(key)] = value
+	}
+
+	return parameters
+}
@@ -0,0 +1,75 @@
+package mediasink_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+)
+
+func codec(mimeType string, payloadType webrtc.PayloadType, clockRate uint32, channels uint16, fmtp string) webrtc.RTPCodecParameters {
+	return webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeType, ClockRate: clockRate, Channels: channels, SDPFmtpLine: fmtp},
+		PayloadType:        payloadType,
+	}
+}
+
+func TestDefaultCodecMatcher(t *testing.T) {
+	h264 := codec(webrtc.MimeTypeH264, 102, 90000, 0, "packetization-mode=1;profile-level-id=42e01f")
+
+	tests := []struct {
+		name    string
+		remote  webrtc.RTPCodecParameters
+		sink    webrtc.RTPCodecParameters
+		matches bool
+	}{
+		{"other payload type", codec(webrtc.MimeTypeH264, 125, 90000, 0, "profile-level-id=42e01f;packetization-mode=1"), h264, true},
+		{"mime type case", codec("video/h264", 102, 90000, 0, "packetization-mode=1;profile-level-id=42e01f"), h264, true},
+		{"other level", codec(webrtc.MimeTypeH264, 102, 90000, 0, "packetization-mode=1;profile-level-id=42e034"), h264, true},
+		{"constrained baseline as 42c0", codec(webrtc.MimeTypeH264, 102, 90000, 0, "packetization-mode=1;profile-level-id=42c01f"), h264, true},
+		{"baseline instead of constrained baseline", codec(webrtc.MimeTypeH264, 102, 90000, 0, "packetization-mode=1;profile-level-id=42001f"), h264, false},
+		{"high profile", codec(webrtc.MimeTypeH264, 102, 90000, 0, "packetization-mode=1;profile-level-id=640032"), h264, false},
+		{"packetization mode defaults to 0", codec(webrtc.MimeTypeH264, 102, 90000, 0, "profile-level-id=42e01f"), h264, false},
+		{"sink without fmtp accepts any", codec(webrtc.MimeTypeH264, 102, 90000, 0, "profile-level-id=640032"), codec(webrtc.MimeTypeH264, 102, 90000, 0, ""), true},
+		{"other mime type", codec(webrtc.MimeTypeVP8, 96, 90000, 0, ""), h264, false},
+		{"other clock rate", codec(webrtc.MimeTypeOpus, 111, 8000, 2, ""), codec(webrtc.MimeTypeOpus, 111, 48000, 2, ""), false},
+		{"mono as 0 or 1 channels", codec(webrtc.MimeTypeOpus, 111, 48000, 0, ""), codec(webrtc.MimeTypeOpus, 111, 48000, 1, ""), true},
+		{"stereo and mono", codec(webrtc.MimeTypeOpus, 111, 48000, 2, ""), codec(webrtc.MimeTypeOpus, 111, 48000, 1, ""), false},
+		{"vp9 profile", codec(webrtc.MimeTypeVP9, 98, 90000, 0, "profile-id=2"), codec(webrtc.MimeTypeVP9, 98, 90000, 0, "profile-id=0"), false},
+		{"vp9 profile default", codec(webrtc.MimeTypeVP9, 98, 90000, 0, ""), codec(webrtc.MimeTypeVP9, 98, 90000, 0, "profile-id=0"), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := mediasink.DefaultCodecMatcher(test.remote, test.sink)
+			if test.matches && err != nil {
+				t.Fatalf("expected a match, got %v", err)
+			}
+			if !test.matches {
+				var mismatch *mediasink.CodecMismatchError
+				if !errors.As(err, &mismatch) || len(mismatch.Reasons) == 0 {
+					t.Fatalf("expected a mismatch with reasons, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestStrictCodecMatcherNeedsEqualPayloadTypes(t *testing.T) {
+	sink := codec(webrtc.MimeTypeVP8, 96, 90000, 0, "")
+
+	if err := mediasink.StrictCodecMatcher(codec(webrtc.MimeTypeVP8, 96, 90000, 0, ""), sink); err != nil {
+		t.Fatalf("expected a match, got %v", err)
+	}
+
+	var mismatch *mediasink.CodecMismatchError
+	if err := mediasink.StrictCodecMatcher(codec(webrtc.MimeTypeVP8, 97, 90000, 0, ""), sink); !errors.As(err, &mismatch) || len(mismatch.Reasons) != 1 {
+		t.Fatalf("expected a payload type mismatch, got %v", err)
+	}
+
+	if err := mediasink.StrictCodecMatcher(codec(webrtc.MimeTypeH264, 97, 90000, 0, ""), sink); !errors.As(err, &mismatch) || len(mismatch.Reasons) != 2 {
+		t.Fatalf("expected payload type and mime type mismatches, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package mediasink
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// assertCountsWrittenBytes writes frames through the container and checks that what writeFrame reports
+// adds up to the size of the file, less the headers written before the first frame.
+func assertCountsWrittenBytes(t *testing.T, path string, container containerWriter, header int64) {
+	t.Helper()
+
+	var written int64
+	for i, size := range []int{1, 254, 255, 256, 1200, 4000} {
+		frame := &Frame{Data: make([]byte, size), Timestamp: uint32(i * 960), PTS: time.Duration(i) * 20 * time.Millisecond}
+
+		n, err := container.writeFrame(frame, frame.PTS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n <= size {
+			t.Fatalf("expected more than the %d bytes of the frame to be written, got %d", size, n)
+		}
+		written += int64(n)
+	}
+
+	if err := container.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header+written != info.Size() {
+		t.Fatalf("expected %d bytes of headers and %d bytes of frames to make up the file, got %d bytes", header, written, info.Size())
+	}
+}
+
+func TestOggContainerCountsWrittenBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audio.ogg")
+
+	container, err := newOggContainer(path, webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: THE ID AND COMMENT HEADER PAGES ARE WRITTEN WHEN THE CONTAINER IS CREATED
+	assertCountsWrittenBytes(t, path, container, int64(container.(*oggContainer).counter.n))
+}
+
+func TestIVFContainerCountsWrittenBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.ivf")
+
+	container, err := newIVFContainer("VP80")(path, webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertCountsWrittenBytes(t, path, container, 32)
+}
@@ -0,0 +1,34 @@
+package mediasink
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+)
+
+func TestUsesFIR(t *testing.T) {
+	fir := webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBCCM, Parameter: "fir"}
+	pli := webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBNACK, Parameter: "pli"}
+	nack := webrtc.RTCPFeedback{Type: webrtc.TypeRTCPFBNACK}
+
+	tests := []struct {
+		name     string
+		feedback []webrtc.RTCPFeedback
+		expected bool
+	}{
+		{"no feedback", nil, false},
+		{"pli only", []webrtc.RTCPFeedback{pli}, false},
+		{"fir only", []webrtc.RTCPFeedback{fir, nack}, true},
+		{"fir and pli", []webrtc.RTCPFeedback{fir, pli}, false},
+		{"case insensitive", []webrtc.RTCPFeedback{{Type: "CCM", Parameter: "FIR"}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			codec := webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{RTCPFeedback: test.feedback}}
+			if usesFIR(codec) != test.expected {
+				t.Fatalf("expected usesFIR to be %t", test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package mediasink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// ForwarderConfig configures where a Forwarder re-sends the RTP packets of a sink to.
+type ForwarderConfig struct {
+	// Address is the UDP address to send to, e.g. "127.0.0.1:4002".
+	Address string
+	// SSRC and PayloadType replace those of the remote track in every packet; zero keeps them.
+	SSRC        uint32
+	PayloadType webrtc.PayloadType
+	// SDPPath is where the SDP describing the forwarded stream is written, e.g. for
+	// "ffplay -protocol_whitelist file,udp,rtp -i stream.sdp". It is rewritten whenever the remote track
+	// changes; empty writes none.
+	SDPPath string
+	// SessionName is the s= line of the SDP; Sinks.Forward uses the label of the sink.
+	SessionName string
+}
+
+// Forwarder re-sends everything a Sink receives as plain RTP over UDP, so that tools like ffplay,
+// ffmpeg or GStreamer can consume remote tracks. It reads the sink with ReadRTP, so nothing else should
+// read the sink while forwarding.
+type Forwarder struct {
+	sink   *Sink
+	config ForwarderConfig
+	conn   *net.UDPConn
+	remote *net.UDPAddr
+
+	codec *webrtc.RTPCodecParameters // the codec the current SDP describes
+	sdp   string
+
+	logger *slog.Logger
+	mux    sync.RWMutex
+	once   sync.Once
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewForwarder(ctx context.Context, sink *Sink, config ForwarderConfig) (*Forwarder, error) {
+	if config.Address == "" {
+		return nil, errors.New("forwarder needs an address")
+	}
+	if config.PayloadType > 127 {
+		return nil, fmt.Errorf("invalid payload type %d", config.PayloadType)
+	}
+
+	remote, err := net.ResolveUDPAddr("udp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving %s: %w", config.Address, err)
+	}
+
+	conn, err := net.DialUDP("udp", nil, remote)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing %s: %w", config.Address, err)
+	}
+
+	if config.SessionName == "" {
+		config.SessionName = "-"
+	}
+
+	ctx2, cancel2 := context.WithCancel(ctx)
+
+	return &Forwarder{
+		sink:   sink,
+		config: config,
+		conn:   conn,
+		remote: remote,
+		logger: sink.logger.With("forwarding", config.Address),
+		ctx:    ctx2,
+		cancel: cancel2,
+	}, nil
+}
+
+func (f *Forwarder) Start() {
+	f.wg.Add(1)
+	go f.loop()
+}
+
+// Close stops forwarding and closes the UDP socket. The SDP file is left in place.
+func (f *Forwarder) Close() error {
+	var err error
+	f.once.Do(func() {
+		f.cancel()
+		f.wg.Wait()
+
+		err = f.conn.Close()
+	})
+
+	return err
+}
+
+// SDP returns the SDP describing the forwarded stream. It is empty till the first packet is forwarded,
+// as the codec parameters are only known once they are negotiated.
+func (f *Forwarder) SDP() string {
+	f.mux.RLock()
+	defer f.mux.RUnlock()
+
+	return f.sdp
+}
+
+func (f *Forwarder) loop() {
+	defer f.wg.Done()
+
+	for {
+		packet, _, err := f.sink.ReadRTP(f.ctx)
+		if err != nil {
+			if f.ctx.Err() == nil {
+				f.logger.Warn("stopped forwarding", "err", err)
+			}
+			return
+		}
+
+		if err := f.updateSDP(); err != nil {
+			f.logger.Warn("failed to write SDP", "err", err)
+		}
+
+		if f.config.SSRC != 0 {
+			packet.SSRC = f.config.SSRC
+		}
+		if f.config.PayloadType != 0 {
+			packet.PayloadType = uint8(f.config.PayloadType)
+		}
+
+		data, err := packet.Marshal()
+		if err != nil {
+			f.logger.Warn("failed to marshal packet", "err", err)
+			continue
+		}
+
+		if _, err := f.conn.Write(data); err != nil {
+			// NOTE: NOTHING LISTENING ON THE ADDRESS (YET) IS NOT A REASON TO STOP
+			f.logger.Debug("failed to forward packet", "err", err)
+		}
+	}
+}
+
+// updateSDP generates the SDP again if the codec of the remote track changed since it was last written.
+func (f *Forwarder) updateSDP() error {
+	source := f.sink.Codec()
+
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	if f.codec != nil && sameCodec(*f.codec, source) {
+		return nil
+	}
+
+	codec := source
+	if f.config.PayloadType != 0 {
+		codec.PayloadType = f.config.PayloadType
+	}
+
+	sdp, err := GenerateSDP(f.config.SessionName, f.remote, codec)
+	if err != nil {
+		return err
+	}
+
+	f.codec = &source
+	f.sdp = sdp
+	f.logger.Info("forwarding track", "codec", codec.MimeType, "payload type", codec.PayloadType)
+
+	if f.config.SDPPath == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.config.SDPPath), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", f.config.SDPPath, err)
+	}
+
+	return os.WriteFile(f.config.SDPPath, []byte(sdp), 0644)
+}
+
+func sameCodec(a, b webrtc.RTPCodecParameters) bool {
+	return a.PayloadType == b.PayloadType && a.MimeType == b.MimeType && a.ClockRate == b.ClockRate &&
+		a.Channels == b.Channels && a.SDPFmtpLine == b.SDPFmtpLine
+}
+
+// GenerateSDP describes a plain RTP stream of codec sent to address, in the form ffmpeg and GStreamer
+// read it.
+func GenerateSDP(name string, address *net.UDPAddr, codec webrtc.RTPCodecParameters) (string, error) {
+	kind, encoding, found := strings.Cut(codec.MimeType, "/")
+	if !found || encoding == "" {
+		return "", fmt.Errorf("invalid mime type '%s'", codec.MimeType)
+	}
+
+	kind = strings.ToLower(kind)
+	if kind != "audio" && kind != "video" {
+		return "", fmt.Errorf("invalid media kind in mime type '%s'", codec.MimeType)
+	}
+
+	network := "IP4"
+	if address.IP.To4() == nil {
+		network = "IP6"
+	}
+
+	rtpmap := fmt.Sprintf("%s/%d", encoding, codec.ClockRate)
+	if kind == "audio" && codec.Channels > 1 {
+		rtpmap = fmt.Sprintf("%s/%d", rtpmap, codec.Channels)
+	}
+
+	var b strings.Builder
+	b.WriteString("v=0\r\n")
+	fmt.Fprintf(&b, "o=- 0 0 IN %s %s\r\n", network, address.IP)
+	fmt.Fprintf(&b, "s=%s\r\n", name)
+	fmt.Fprintf(&b, "c=IN %s %s\r\n", network, address.IP)
+	b.WriteString("t=0 0\r\n")
+	fmt.Fprintf(&b, "m=%s %d RTP/AVP %d\r\n", kind, address.Port, codec.PayloadType)
+	fmt.Fprintf(&b, "a=rtpmap:%d %s\r\n", codec.PayloadType, rtpmap)
+	if codec.SDPFmtpLine != "" {
+		fmt.Fprintf(&b, "a=fmtp:%d %s\r\n", codec.PayloadType, codec.SDPFmtpLine)
+	}
+
+	return b.String(), nil
+}
+
+// Forward starts re-sending the RTP packets of the sink with the given label over UDP. Forwarding stops
+// with the forwarder's Close, or with Sinks.Close.
+func (s *Sinks) Forward(label string, config ForwarderConfig) (*Forwarder, error) {
+	sink, err := s.GetSink(label)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.SessionName == "" {
+		config.SessionName = label
+	}
+
+	forwarder, err := NewForwarder(s.ctx, sink, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to forward sink (id=%s); err: %w", label, err)
+	}
+
+	s.mux.Lock()
+	s.outputs = append(s.outputs, forwarder)
+	s.mux.Unlock()
+
+	forwarder.Start()
+	return forwarder, nil
+}
@@ -0,0 +1,90 @@
+package mediasink_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/harshabose/simple_webrtc_comm/client"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/clienttest"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+// listenUDP listens on a free loopback port for what a forwarder sends.
+func listenUDP(t *testing.T) *net.UDPConn {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn
+}
+
+// awaitForwarded fails the test if nothing is forwarded within DefaultTimeout.
+func awaitForwarded(t *testing.T, conn *net.UDPConn) {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(clienttest.DefaultTimeout)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Read(make([]byte, 1500)); err != nil {
+		t.Fatalf("nothing was forwarded: %v", err)
+	}
+}
+
+// drainUntilIdle reads what the forwarder sends till nothing arrives for idle.
+func drainUntilIdle(t *testing.T, conn *net.UDPConn, idle time.Duration) {
+	t.Helper()
+
+	buffer := make([]byte, 1500)
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(idle)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Read(buffer); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return
+			}
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestForwarderClosesOnIdleTrack(t *testing.T) {
+	pair := clienttest.NewPair(t, client.WithDefaultMediaEngine())
+	offer, answer := pair.PeerConnections(t, "media")
+
+	source := createSource(t, offer, "video", mediasource.WithVP8Track(90000))
+	sink := createSink(t, answer, "video", mediasink.WithVP8Track(90000))
+
+	pair.Connect(t)
+
+	listener := listenUDP(t)
+	forwarder, err := mediasink.NewForwarder(context.Background(), sink, mediasink.ForwarderConfig{Address: listener.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forwarder.Start()
+
+	// NOTE: THE SOURCE SENDS TILL THE FIRST PACKET IS FORWARDED AND THEN STOPS, SO THE TRACK GOES IDLE
+	ctx, cancel := context.WithCancel(context.Background())
+	writeSamplesUntil(ctx, source)
+	awaitForwarded(t, listener)
+	cancel()
+	drainUntilIdle(t, listener, 100*time.Millisecond)
+
+	if forwarder.SDP() == "" {
+		t.Fatal("expected the SDP of the forwarded stream once packets were forwarded")
+	}
+
+	assertClosesPromptly(t, forwarder)
+}
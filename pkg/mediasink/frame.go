@@ -0,0 +1,336 @@
+package mediasink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media/samplebuilder"
+)
+
+// Frame is a complete access unit reassembled from RTP packets: an Annex-B access unit for H.264 and
+// H.265, a frame for VP8 and VP9, a temporal unit of OBUs for AV1 and a packet for Opus.
+type Frame struct {
+	Data []byte
+	// Timestamp is the RTP timestamp of the frame
+	Timestamp uint32
+	// PTS is the presentation timestamp, relative to the first frame read from the sink
+	PTS      time.Duration
+	Duration time.Duration
+	Keyframe bool
+	// Lost is set when packets before this frame were lost or had to be dropped; decoders usually
+	// need to wait for the next keyframe then
+	Lost bool
+}
+
+// ErrNoDepacketizer is returned by ReadFrame for codecs it cannot reassemble frames of.
+var ErrNoDepacketizer = errors.New("no depacketizer for codec")
+
+const (
+	DefaultFrameMaxLate  uint16 = 256
+	DefaultFrameMaxDelay        = 500 * time.Millisecond
+)
+
+// WithFrameBuffer sets the reorder buffer ReadFrame reassembles frames with: maxLate is how many packets
+// it holds at most and maxDelay how long it waits for missing packets before giving up on a frame.
+func WithFrameBuffer(maxLate uint16, maxDelay time.Duration) SinkOption {
+	return func(sink *Sink) error {
+		if maxLate == 0 {
+			return errors.New("frame buffer needs to hold at least one packet")
+		}
+		sink.frameMaxLate = maxLate
+		sink.frameMaxDelay = maxDelay
+		return nil
+	}
+}
+
+type frameAssembler struct {
+	generator  *webrtc.TrackRemote
+	builder    *samplebuilder.SampleBuilder
+	keyframe   func(data []byte, head any) bool
+	clockRate  uint32
+	lastTS     uint32
+	unwrapped  int64
+	started    bool
+	lastSeq    uint16
+	hasLastSeq bool
+}
+
+func newFrameAssembler(generator *webrtc.TrackRemote, maxLate uint16, maxDelay time.Duration) (*frameAssembler, error) {
+	assembler, err := newCodecFrameAssembler(generator.Codec(), maxLate, maxDelay)
+	if err != nil {
+		return nil, err
+	}
+	assembler.generator = generator
+
+	return assembler, nil
+}
+
+func newCodecFrameAssembler(codec webrtc.RTPCodecParameters, maxLate uint16, maxDelay time.Duration) (*frameAssembler, error) {
+	var (
+		depacketizer rtp.Depacketizer
+		keyframe     func(data []byte, head any) bool
+	)
+
+	switch strings.ToLower(codec.MimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		depacketizer, keyframe = &codecs.H264Packet{}, isH264Keyframe
+	case strings.ToLower(webrtc.MimeTypeH265):
+		depacketizer, keyframe = &h265Depacketizer{}, isH265Keyframe
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		depacketizer, keyframe = &codecs.VP8Packet{}, isVP8Keyframe
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		depacketizer, keyframe = &codecs.VP9Packet{}, isVP9Keyframe
+	case strings.ToLower(webrtc.MimeTypeAV1):
+		depacketizer, keyframe = &codecs.AV1Depacketizer{}, isAV1Keyframe
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		depacketizer, keyframe = &codecs.OpusPacket{}, func([]byte, any) bool { return true }
+	default:
+		return nil, fmt.Errorf("%w '%s'", ErrNoDepacketizer, codec.MimeType)
+	}
+
+	options := []samplebuilder.Option{
+		samplebuilder.WithRTPHeaders(true),
+		samplebuilder.WithPacketHeadHandler(copyPacketHead),
+	}
+	if maxDelay > 0 {
+		options = append(options, samplebuilder.WithMaxTimeDelay(maxDelay))
+	}
+
+	return &frameAssembler{
+		builder:   samplebuilder.New(maxLate, depacketizer, codec.ClockRate, options...),
+		keyframe:  keyframe,
+		clockRate: codec.ClockRate,
+	}, nil
+}
+
+// copyPacketHead keeps the flags of the depacketizer after it unmarshalled the first packet of a frame,
+// as the depacketizer itself is reused for the following packets.
+func copyPacketHead(head any) any {
+	switch depacketizer := head.(type) {
+	case *codecs.VP9Packet:
+		return *depacketizer
+	case *codecs.AV1Depacketizer:
+		return depacketizer.N
+	default:
+		return nil
+	}
+}
+
+func (a *frameAssembler) push(packet *rtp.Packet) {
+	a.builder.Push(packet)
+}
+
+func (a *frameAssembler) pop() *Frame {
+	sample := a.builder.Pop()
+	if sample == nil {
+		return nil
+	}
+
+	frame := &Frame{
+		Data:      sample.Data,
+		Timestamp: sample.PacketTimestamp,
+		Duration:  sample.Duration,
+		Keyframe:  a.keyframe(sample.Data, sample.Metadata),
+		Lost:      sample.PrevDroppedPackets > 0,
+	}
+
+	if len(sample.RTPHeaders) > 0 {
+		first, last := sample.RTPHeaders[0].SequenceNumber, sample.RTPHeaders[len(sample.RTPHeaders)-1].SequenceNumber
+		if a.hasLastSeq && first != a.lastSeq+1 {
+			frame.Lost = true
+		}
+		a.lastSeq, a.hasLastSeq = last, true
+	}
+
+	// NOTE: RTP TIMESTAMPS WRAP AROUND; THE DIFFERENCE IS TAKEN AS SIGNED TO SURVIVE THAT
+	if !a.started {
+		a.lastTS, a.started = sample.PacketTimestamp, true
+	}
+	a.unwrapped += int64(int32(sample.PacketTimestamp - a.lastTS))
+	a.lastTS = sample.PacketTimestamp
+	if a.clockRate > 0 {
+		frame.PTS = time.Duration(a.unwrapped) * time.Second / time.Duration(a.clockRate)
+	}
+
+	return frame
+}
+
+// ReadFrame reads the next complete frame of the remote track. Packets are reordered and reassembled
+// with the depacketizer of the track's codec, see WithFrameBuffer. H.264, H.265, VP8, VP9, AV1 and Opus
+// are supported; H.265 packets with PACI headers are dropped. ReadFrame and ReadRTP should not be mixed on the same sink, and only one goroutine should
+// call ReadFrame at a time.
+func (s *Sink) ReadFrame(ctx context.Context) (*Frame, error) {
+	s.frameMux.Lock()
+	defer s.frameMux.Unlock()
+
+	for {
+		if s.frames != nil {
+			if frame := s.frames.pop(); frame != nil {
+				if frame.Lost && !frame.Keyframe {
+					s.autoRequestKeyframe("frame lost")
+				}
+				return frame, nil
+			}
+		}
+
+		reader, err := s.waitReader(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.frames == nil || s.frames.generator != reader.generator {
+			// a new remote track (e.g. after a renegotiation) starts a new stream of frames
+			if s.frames, err = newFrameAssembler(reader.generator, s.frameMaxLate, s.frameMaxDelay); err != nil {
+				return nil, err
+			}
+		}
+
+		packet, _, err := s.readPacket(ctx, reader)
+		if err != nil && s.replaced(reader) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		s.frames.push(packet)
+	}
+}
+
+func isH264Keyframe(data []byte, _ any) bool {
+	for _, nalu := range splitAnnexB(data) {
+		switch nalu[0] & 0x1F {
+		case 5, 7: // IDR slice, SPS
+			return true
+		}
+	}
+
+	return false
+}
+
+func isH265Keyframe(data []byte, _ any) bool {
+	for _, nalu := range splitAnnexB(data) {
+		switch (nalu[0] >> 1) & 0x3F {
+		case 16, 17, 18, 19, 20, 21, 32, 33, 34: // IRAP slices, VPS, SPS, PPS
+			return true
+		}
+	}
+
+	return false
+}
+
+func isVP8Keyframe(data []byte, _ any) bool {
+	// the P bit of the frame tag is 0 for key frames
+	return len(data) > 0 && data[0]&0x01 == 0
+}
+
+func isVP9Keyframe(_ []byte, head any) bool {
+	packet, ok := head.(codecs.VP9Packet)
+	// the P bit is set for frames predicted from earlier ones
+	return ok && !packet.P
+}
+
+func isAV1Keyframe(_ []byte, head any) bool {
+	// the N bit marks the first packet of a new coded video sequence
+	newSequence, ok := head.(bool)
+	return ok && newSequence
+}
+
+// splitAnnexB returns the non-empty NAL units of an Annex-B byte stream.
+func splitAnnexB(data []byte) [][]byte {
+	var nalus [][]byte
+
+	start := -1
+	for i := 0; i+2 < len(data); i++ {
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			continue
+		}
+		if start >= 0 {
+			nalus = appendNALU(nalus, data[start:i])
+		}
+		start = i + 3
+		i += 2
+	}
+	if start >= 0 {
+		nalus = appendNALU(nalus, data[start:])
+	}
+
+	return nalus
+}
+
+func appendNALU(nalus [][]byte, nalu []byte) [][]byte {
+	// a 4 byte start code leaves a trailing zero on the previous NAL unit
+	for len(nalu) > 0 && nalu[len(nalu)-1] == 0 {
+		nalu = nalu[:len(nalu)-1]
+	}
+	if len(nalu) == 0 {
+		return nalus
+	}
+
+	return append(nalus, nalu)
+}
+
+var annexBStartCode = []byte{0x00, 0x00, 0x00, 0x01}
+
+// h265Depacketizer turns H.265 RTP payloads into an Annex-B byte stream, like codecs.H264Packet does for
+// H.264; codecs.H265Packet only parses them.
+type h265Depacketizer struct {
+	packet codecs.H265Packet
+}
+
+func (d *h265Depacketizer) Unmarshal(payload []byte) ([]byte, error) {
+	if _, err := d.packet.Unmarshal(payload); err != nil {
+		return nil, err
+	}
+
+	switch packet := d.packet.Packet().(type) {
+	case *codecs.H265SingleNALUnitPacket:
+		return appendH265NALU(nil, packet.PayloadHeader(), packet.Payload()), nil
+	case *codecs.H265AggregationPacket:
+		data := appendAnnexB(nil, packet.FirstUnit().NalUnit())
+		for _, unit := range packet.OtherUnits() {
+			data = appendAnnexB(data, unit.NalUnit())
+		}
+		return data, nil
+	case *codecs.H265FragmentationUnitPacket:
+		if !packet.FuHeader().S() {
+			return packet.Payload(), nil
+		}
+		// the NAL unit header of the fragmented unit is the FU payload header with the type of the FU header
+		header := packet.PayloadHeader()&0x81FF | codecs.H265NALUHeader(packet.FuHeader().FuType())<<9
+		return appendH265NALU(nil, header, packet.Payload()), nil
+	default:
+		// NOTE: PACI PACKETS ARE NOT USED BY ANY KNOWN SENDER; THEIR PAYLOAD IS DROPPED
+		return nil, nil
+	}
+}
+
+func (d *h265Depacketizer) IsPartitionHead(payload []byte) bool {
+	// a fragmented NAL unit starts with the fragment with the S bit set
+	if len(payload) > 2 && (payload[0]>>1)&0x3F == 49 {
+		return payload[2]&0x80 != 0
+	}
+
+	return len(payload) > 0
+}
+
+func (d *h265Depacketizer) IsPartitionTail(marker bool, _ []byte) bool {
+	return marker
+}
+
+func appendH265NALU(data []byte, header codecs.H265NALUHeader, payload []byte) []byte {
+	data = append(data, annexBStartCode...)
+	data = append(data, byte(header>>8), byte(header))
+	return append(data, payload...)
+}
+
+func appendAnnexB(data []byte, nalu []byte) []byte {
+	data = append(data, annexBStartCode...)
+	return append(data, nalu...)
+}
@@ -0,0 +1,235 @@
+package mediasink
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v4"
+)
+
+type testPacket struct {
+	seq       uint16
+	timestamp uint32
+	marker    bool
+	payload   []byte
+}
+
+// assembleFrames pushes the packets through a frame assembler for the codec and returns every frame
+// it gives. A frame is only complete once a later packet arrives, so a packet of a next frame follows.
+func assembleFrames(t *testing.T, mimeType string, maxDelay time.Duration, packets []testPacket) []*Frame {
+	t.Helper()
+
+	codec := webrtc.RTPCodecParameters{RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: mimeType, ClockRate: 90000}}
+	assembler, err := newCodecFrameAssembler(codec, DefaultFrameMaxLate, maxDelay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := packets[len(packets)-1]
+	for _, packet := range append(packets, testPacket{last.seq + 1, last.timestamp + 3000, true, last.payload}) {
+		assembler.push(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, SequenceNumber: packet.seq, Timestamp: packet.timestamp, Marker: packet.marker},
+			Payload: packet.payload,
+		})
+	}
+
+	var frames []*Frame
+	for frame := assembler.pop(); frame != nil; frame = assembler.pop() {
+		frames = append(frames, frame)
+	}
+
+	return frames
+}
+
+func TestFrameAssemblerReassemblesNALUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		mimeType string
+		packets  []testPacket
+		expected []byte
+		keyframe bool
+	}{
+		{
+			name:     "h264 single nal unit",
+			mimeType: webrtc.MimeTypeH264,
+			packets:  []testPacket{{1, 0, true, []byte{0x41, 0xAA}}},
+			expected: []byte{0, 0, 0, 1, 0x41, 0xAA},
+		},
+		{
+			name:     "h264 stap-a",
+			mimeType: webrtc.MimeTypeH264,
+			packets:  []testPacket{{1, 0, true, []byte{0x78, 0x00, 0x02, 0x67, 0x42, 0x00, 0x02, 0x68, 0xCE}}},
+			expected: []byte{0, 0, 0, 1, 0x67, 0x42, 0, 0, 0, 1, 0x68, 0xCE},
+			keyframe: true,
+		},
+		{
+			name:     "h264 fu-a",
+			mimeType: webrtc.MimeTypeH264,
+			packets: []testPacket{
+				{1, 0, false, []byte{0x7C, 0x85, 0xAA}},
+				{2, 0, false, []byte{0x7C, 0x05, 0xBB}},
+				{3, 0, true, []byte{0x7C, 0x45, 0xCC}},
+			},
+			expected: []byte{0, 0, 0, 1, 0x65, 0xAA, 0xBB, 0xCC},
+			keyframe: true,
+		},
+		{
+			name:     "h264 stap-a and fu-a in one access unit",
+			mimeType: webrtc.MimeTypeH264,
+			packets: []testPacket{
+				{1, 0, false, []byte{0x78, 0x00, 0x02, 0x67, 0x42, 0x00, 0x02, 0x68, 0xCE}},
+				{2, 0, false, []byte{0x7C, 0x85, 0xAA}},
+				{3, 0, true, []byte{0x7C, 0x45, 0xBB}},
+			},
+			expected: []byte{0, 0, 0, 1, 0x67, 0x42, 0, 0, 0, 1, 0x68, 0xCE, 0, 0, 0, 1, 0x65, 0xAA, 0xBB},
+			keyframe: true,
+		},
+		{
+			name:     "h265 single nal unit",
+			mimeType: webrtc.MimeTypeH265,
+			packets:  []testPacket{{1, 0, true, []byte{0x02, 0x01, 0xAA}}},
+			expected: []byte{0, 0, 0, 1, 0x02, 0x01, 0xAA},
+		},
+		{
+			name:     "h265 aggregation packet",
+			mimeType: webrtc.MimeTypeH265,
+			packets:  []testPacket{{1, 0, true, []byte{0x60, 0x01, 0x00, 0x03, 0x40, 0x01, 0xAA, 0x00, 0x03, 0x42, 0x01, 0xBB}}},
+			expected: []byte{0, 0, 0, 1, 0x40, 0x01, 0xAA, 0, 0, 0, 1, 0x42, 0x01, 0xBB},
+			keyframe: true,
+		},
+		{
+			name:     "h265 fragmentation unit",
+			mimeType: webrtc.MimeTypeH265,
+			packets: []testPacket{
+				{1, 0, false, []byte{0x62, 0x01, 0x93, 0xAA}},
+				{2, 0, false, []byte{0x62, 0x01, 0x13, 0xBB}},
+				{3, 0, true, []byte{0x62, 0x01, 0x53, 0xCC}},
+			},
+			expected: []byte{0, 0, 0, 1, 0x26, 0x01, 0xAA, 0xBB, 0xCC},
+			keyframe: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			frames := assembleFrames(t, test.mimeType, 0, test.packets)
+			if len(frames) != 1 {
+				t.Fatalf("expected one frame, got %d", len(frames))
+			}
+			if !bytes.Equal(frames[0].Data, test.expected) {
+				t.Fatalf("expected % x, got % x", test.expected, frames[0].Data)
+			}
+			if frames[0].Keyframe != test.keyframe {
+				t.Fatalf("expected the keyframe flag to be %t", test.keyframe)
+			}
+		})
+	}
+}
+
+func TestFrameAssemblerFlagsLostFrames(t *testing.T) {
+	// NOTE: THE PACKET OF THE THIRD FRAME NEVER ARRIVES; IT IS GIVEN UP ON ONCE IT IS 50MS LATE
+	frames := assembleFrames(t, webrtc.MimeTypeVP8, 50*time.Millisecond, []testPacket{
+		{1, 0, true, []byte{0x10, 0x10}},
+		{2, 3000, true, []byte{0x10, 0x11}},
+		{4, 9000, true, []byte{0x10, 0x11}},
+		{5, 12000, true, []byte{0x10, 0x11}},
+	})
+
+	expected := []struct {
+		timestamp uint32
+		lost      bool
+	}{{0, false}, {3000, false}, {9000, true}, {12000, false}}
+
+	if len(frames) != len(expected) {
+		t.Fatalf("expected %d frames, got %d", len(expected), len(frames))
+	}
+	for i, frame := range frames {
+		if frame.Timestamp != expected[i].timestamp || frame.Lost != expected[i].lost {
+			t.Fatalf("frame %d: expected timestamp %d and lost %t, got %d and %t", i, expected[i].timestamp, expected[i].lost, frame.Timestamp, frame.Lost)
+		}
+	}
+}
+
+func TestFrameAssemblerPTSAcrossTimestampWrap(t *testing.T) {
+	const first uint32 = 0xFFFFFFFF - 9000
+
+	var packets []testPacket
+	for i := uint32(0); i < 4; i++ {
+		packets = append(packets, testPacket{uint16(i + 1), first + i*9000, true, []byte{0x10, 0x11}})
+	}
+
+	frames := assembleFrames(t, webrtc.MimeTypeVP8, 0, packets)
+	if len(frames) != len(packets) {
+		t.Fatalf("expected %d frames, got %d", len(packets), len(frames))
+	}
+	for i, frame := range frames {
+		if expected := time.Duration(i) * 100 * time.Millisecond; frame.PTS != expected {
+			t.Fatalf("frame %d (timestamp %d): expected a pts of %s, got %s", i, frame.Timestamp, expected, frame.PTS)
+		}
+	}
+}
+
+func TestSplitAnnexB(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		expected [][]byte
+	}{
+		{"3 byte start codes", []byte{0, 0, 1, 0x67, 0xAA, 0, 0, 1, 0x68, 0xBB}, [][]byte{{0x67, 0xAA}, {0x68, 0xBB}}},
+		{"4 byte start codes", []byte{0, 0, 0, 1, 0x67, 0xAA, 0, 0, 0, 1, 0x68, 0xBB}, [][]byte{{0x67, 0xAA}, {0x68, 0xBB}}},
+		{"mixed start codes", []byte{0, 0, 0, 1, 0x67, 0xAA, 0, 0, 1, 0x68, 0xBB}, [][]byte{{0x67, 0xAA}, {0x68, 0xBB}}},
+		{"empty nal units", []byte{0, 0, 1, 0, 0, 0, 1, 0x65}, [][]byte{{0x65}}},
+		{"no start code", []byte{0x65, 0xAA}, nil},
+		{"empty", nil, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nalus := splitAnnexB(test.data)
+			if len(nalus) != len(test.expected) {
+				t.Fatalf("expected %d nal units, got %d", len(test.expected), len(nalus))
+			}
+			for i := range nalus {
+				if !bytes.Equal(nalus[i], test.expected[i]) {
+					t.Fatalf("nal unit %d: expected % x, got % x", i, test.expected[i], nalus[i])
+				}
+			}
+		})
+	}
+}
+
+func TestKeyframeDetection(t *testing.T) {
+	tests := []struct {
+		name     string
+		keyframe func(data []byte, head any) bool
+		data     []byte
+		head     any
+		expected bool
+	}{
+		{"h264 idr", isH264Keyframe, []byte{0, 0, 0, 1, 0x65, 0xAA}, nil, true},
+		{"h264 sps", isH264Keyframe, []byte{0, 0, 1, 0x67, 0x42}, nil, true},
+		{"h264 idr after sei", isH264Keyframe, []byte{0, 0, 1, 0x06, 0x05, 0, 0, 1, 0x65, 0xAA}, nil, true},
+		{"h264 non-idr", isH264Keyframe, []byte{0, 0, 0, 1, 0x41, 0xAA}, nil, false},
+		{"h264 empty", isH264Keyframe, nil, nil, false},
+		{"h265 idr", isH265Keyframe, []byte{0, 0, 0, 1, 0x26, 0x01, 0xAA}, nil, true},
+		{"h265 vps", isH265Keyframe, []byte{0, 0, 1, 0x40, 0x01}, nil, true},
+		{"h265 trail", isH265Keyframe, []byte{0, 0, 0, 1, 0x02, 0x01, 0xAA}, nil, false},
+		{"vp8 key frame", isVP8Keyframe, []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a}, nil, true},
+		{"vp8 inter frame", isVP8Keyframe, []byte{0x11, 0x02, 0x00}, nil, false},
+		{"vp8 empty", isVP8Keyframe, nil, nil, false},
+		{"vp9 key frame", isVP9Keyframe, nil, codecs.VP9Packet{B: true}, true},
+		{"vp9 inter frame", isVP9Keyframe, nil, codecs.VP9Packet{B: true, P: true}, false},
+		{"vp9 without head", isVP9Keyframe, nil, nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.keyframe(test.data, test.head) != test.expected {
+				t.Fatalf("expected the keyframe check to be %t", test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+package mediasink
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// JitterBufferConfig configures the playout delay of the jitter buffer. The delay starts at Target and
+// follows 4 times the observed interarrival jitter (as estimated per RFC 3550) within [Min, Max]: it grows
+// at once when the jitter does and shrinks slowly when the network calms down.
+type JitterBufferConfig struct {
+	Target time.Duration
+	Min    time.Duration
+	Max    time.Duration
+}
+
+var DefaultJitterBufferConfig = JitterBufferConfig{
+	Target: 50 * time.Millisecond,
+	Min:    20 * time.Millisecond,
+	Max:    500 * time.Millisecond,
+}
+
+// JitterBufferStats are the counters of a sink's jitter buffer since the current remote track was
+// attached.
+type JitterBufferStats struct {
+	Received   uint64
+	Played     uint64
+	Reordered  uint64 // arrived after a packet with a higher sequence number, in time to be played
+	Late       uint64 // arrived after a packet with a higher sequence number was played; dropped
+	Lost       uint64 // never arrived before the packets after them were played
+	Duplicates uint64
+	Overflows  uint64 // dropped because the buffer was full
+	Jitter     time.Duration
+	Delay      time.Duration
+	Buffered   int
+}
+
+// jitterBufferCapacity bounds the packets held, in case the remote sends faster than it claims.
+const jitterBufferCapacity = 2048
+
+// WithJitterBuffer reorders and smooths the packets of the sink before ReadRTP and ReadFrame hand them
+// out, independent of pion's jitter buffer interceptor. Packets are played out at their RTP timestamp
+// plus the playout delay; packets arriving after their successors were played are dropped.
+func WithJitterBuffer(config JitterBufferConfig) SinkOption {
+	return func(sink *Sink) error {
+		if config.Min < 0 || config.Min > config.Target || config.Target > config.Max {
+			return errors.New("jitter buffer needs 0 <= min <= target <= max")
+		}
+		sink.jitter = newJitterBuffer(config)
+		return nil
+	}
+}
+
+type jitterPacket struct {
+	packet *rtp.Packet
+	seq    int64
+	due    time.Time
+}
+
+type jitterBuffer struct {
+	config  JitterBufferConfig
+	packets []jitterPacket // sorted by extended sequence number
+	notify  chan struct{}
+	mux     sync.Mutex
+
+	clockRate uint32
+	ssrc      uint32
+	started   bool
+
+	highestSeq int64
+	playedSeq  int64
+	hasPlayed  bool
+	highestTS  int64
+
+	// the reference maps RTP timestamps to wall clock: it is the packet with the smallest transit time
+	// seen so far, the one least delayed by the network
+	refTime time.Time
+	refTS   int64
+
+	lastArrival time.Time
+	lastTS      int64
+	jitter      float64 // in seconds
+	playout     time.Duration
+
+	stats JitterBufferStats
+}
+
+func newJitterBuffer(config JitterBufferConfig) *jitterBuffer {
+	return &jitterBuffer{
+		config:  config,
+		notify:  make(chan struct{}, 1),
+		playout: config.Target,
+	}
+}
+
+// reset starts over for a new remote track.
+func (j *jitterBuffer) reset(clockRate uint32) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	j.packets = nil
+	j.clockRate = clockRate
+	j.started = false
+	j.hasPlayed = false
+	j.jitter = 0
+	j.playout = j.config.Target
+	j.stats = JitterBufferStats{}
+}
+
+func (j *jitterBuffer) delay() time.Duration {
+	return j.playout
+}
+
+// adaptDelay moves the playout delay towards 4 times the jitter. It grows at once, as packets would be
+// late otherwise, and shrinks by 1/64 of the difference per packet, so that a few calm packets do not
+// undo it.
+func (j *jitterBuffer) adaptDelay() {
+	wanted := time.Duration(4 * j.jitter * float64(time.Second))
+	if wanted > j.playout {
+		j.playout = wanted
+	} else {
+		j.playout -= (j.playout - wanted) / 64
+	}
+
+	j.playout = min(max(j.playout, j.config.Min), j.config.Max)
+}
+
+// extend unwraps a 16 bit sequence number or 32 bit timestamp around the highest one seen.
+func extend(highest int64, value uint64, bits uint) int64 {
+	mask := uint64(1)<<bits - 1
+	diff := int64((value - uint64(highest)) & mask)
+	if diff >= int64(1)<<(bits-1) {
+		diff -= int64(1) << bits
+	}
+
+	return highest + diff
+}
+
+func (j *jitterBuffer) push(packet *rtp.Packet, arrival time.Time) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.started && packet.SSRC != j.ssrc {
+		// NOTE: A NEW SSRC IS A NEW STREAM, SEQUENCE NUMBERS AND TIMESTAMPS START OVER
+		j.started = false
+		j.hasPlayed = false
+		j.packets = j.packets[:0]
+	}
+
+	if !j.started {
+		j.started = true
+		j.ssrc = packet.SSRC
+		j.highestSeq = int64(packet.SequenceNumber)
+		j.highestTS = int64(packet.Timestamp)
+		j.refTime, j.refTS = arrival, j.highestTS
+		j.lastArrival, j.lastTS = arrival, j.highestTS
+	}
+
+	j.stats.Received++
+
+	seq := extend(j.highestSeq, uint64(packet.SequenceNumber), 16)
+	ts := extend(j.highestTS, uint64(packet.Timestamp), 32)
+
+	if j.hasPlayed && seq <= j.playedSeq {
+		j.stats.Late++
+		return
+	}
+
+	index := sort.Search(len(j.packets), func(i int) bool { return j.packets[i].seq >= seq })
+	if index < len(j.packets) && j.packets[index].seq == seq {
+		j.stats.Duplicates++
+		return
+	}
+
+	if len(j.packets) >= jitterBufferCapacity {
+		j.stats.Overflows++
+		// NOTE: THE OLDEST PACKET IS DROPPED, WHICH IS THE INCOMING ONE IF IT IS OLDER THAN THE HEAD
+		if index == 0 {
+			return
+		}
+		j.packets = j.packets[1:]
+		index--
+	}
+
+	if seq < j.highestSeq {
+		j.stats.Reordered++
+	} else {
+		j.highestSeq = seq
+	}
+	j.highestTS = max(j.highestTS, ts)
+
+	j.updateJitter(arrival, ts)
+	j.adaptDelay()
+
+	j.packets = append(j.packets, jitterPacket{})
+	copy(j.packets[index+1:], j.packets[index:])
+	// NOTE: NO PACKET IS HELD LONGER THAN THE MAXIMUM DELAY, WHATEVER ITS TIMESTAMP SAYS
+	due := j.playoutTime(ts)
+	if latest := arrival.Add(j.config.Max); due.After(latest) {
+		due = latest
+	}
+	j.packets[index] = jitterPacket{packet: packet, seq: seq, due: due}
+
+	select {
+	case j.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (j *jitterBuffer) mediaTime(ts int64) time.Duration {
+	if j.clockRate == 0 {
+		return 0
+	}
+
+	return time.Duration(ts) * time.Second / time.Duration(j.clockRate)
+}
+
+// updateJitter estimates the interarrival jitter like RFC 3550 does and moves the reference to packets
+// that made it through the network faster than the current one.
+func (j *jitterBuffer) updateJitter(arrival time.Time, ts int64) {
+	d := arrival.Sub(j.lastArrival) - j.mediaTime(ts-j.lastTS)
+	if d < 0 {
+		d = -d
+	}
+	j.jitter += (d.Seconds() - j.jitter) / 16
+	j.lastArrival, j.lastTS = arrival, ts
+
+	if arrival.Sub(j.refTime) < j.mediaTime(ts-j.refTS) {
+		j.refTime, j.refTS = arrival, ts
+	}
+}
+
+func (j *jitterBuffer) playoutTime(ts int64) time.Time {
+	return j.refTime.Add(j.mediaTime(ts-j.refTS) + j.delay())
+}
+
+// next returns the packet with the lowest sequence number if it is due, and otherwise how long to wait
+// for it; a negative wait means the buffer is empty.
+func (j *jitterBuffer) next() (*rtp.Packet, time.Duration) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if len(j.packets) == 0 {
+		return nil, -1
+	}
+
+	head := j.packets[0]
+	if wait := time.Until(head.due); wait > 0 {
+		return nil, wait
+	}
+
+	j.packets = j.packets[1:]
+	if j.hasPlayed && head.seq > j.playedSeq+1 {
+		j.stats.Lost += uint64(head.seq - j.playedSeq - 1)
+	}
+	j.playedSeq, j.hasPlayed = head.seq, true
+	j.stats.Played++
+
+	return head.packet, 0
+}
+
+// pop waits till the packet with the lowest sequence number is due and returns it.
+func (j *jitterBuffer) pop(ctx context.Context) (*rtp.Packet, error) {
+	for {
+		packet, wait := j.next()
+		if packet != nil {
+			return packet, nil
+		}
+
+		var (
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-j.notify:
+		case <-timeout:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (j *jitterBuffer) getStats() JitterBufferStats {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	stats := j.stats
+	stats.Jitter = time.Duration(j.jitter * float64(time.Second))
+	stats.Delay = j.delay()
+	stats.Buffered = len(j.packets)
+
+	return stats
+}
+
+// JitterBufferStats returns the counters of the sink's jitter buffer, and false if the sink has none.
+func (s *Sink) JitterBufferStats() (JitterBufferStats, bool) {
+	if s.jitter == nil {
+		return JitterBufferStats{}, false
+	}
+
+	return s.jitter.getStats(), true
+}
+
+// jitterLoop fills the jitter buffer from the remote track till reading from it fails, which happens
+// once its transceiver is stopped, another track is attached or the peer connection is closed.
+func (s *Sink) jitterLoop(reader *remoteReader) {
+	s.jitter.reset(reader.generator.Codec().ClockRate)
+
+	for {
+		packet, _, err := reader.read(s.ctx)
+		if err != nil {
+			s.logger.Debug("stopped filling jitter buffer", "err", err)
+			return
+		}
+
+		s.jitter.push(packet, time.Now())
+	}
+}
@@ -0,0 +1,90 @@
+package mediasink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+func jitterTestPacket(seq uint16, ts uint32) *rtp.Packet {
+	return &rtp.Packet{Header: rtp.Header{SSRC: 1, SequenceNumber: seq, Timestamp: ts}}
+}
+
+func TestJitterBufferDelayAdaptsBetweenMinAndMax(t *testing.T) {
+	config := JitterBufferConfig{Target: 100 * time.Millisecond, Min: 20 * time.Millisecond, Max: 300 * time.Millisecond}
+	j := newJitterBuffer(config)
+	j.reset(90000)
+
+	if delay := j.delay(); delay != config.Target {
+		t.Fatalf("expected the delay to start at the target, got %s", delay)
+	}
+
+	// packets 20ms apart in media time arriving exactly 20ms apart: no jitter
+	start := time.Now()
+	seq := uint16(0)
+	for ; seq < 1000; seq++ {
+		j.push(jitterTestPacket(seq, uint32(seq)*1800), start.Add(time.Duration(seq)*20*time.Millisecond))
+	}
+	if delay := j.delay(); delay != config.Min {
+		t.Fatalf("expected the delay to shrink to the minimum without jitter, got %s", delay)
+	}
+
+	// every other packet is 200ms late
+	for end := seq + 100; seq < end; seq++ {
+		arrival := start.Add(time.Duration(seq) * 20 * time.Millisecond)
+		if seq%2 == 0 {
+			arrival = arrival.Add(200 * time.Millisecond)
+		}
+		j.push(jitterTestPacket(seq, uint32(seq)*1800), arrival)
+	}
+	if delay := j.delay(); delay != config.Max {
+		t.Fatalf("expected the delay to grow to the maximum with jitter, got %s", delay)
+	}
+}
+
+func TestJitterBufferOverflowDropsOldestPacket(t *testing.T) {
+	j := newJitterBuffer(DefaultJitterBufferConfig)
+	j.reset(90000)
+
+	now := time.Now()
+	for i := 0; i < jitterBufferCapacity; i++ {
+		j.push(jitterTestPacket(uint16(100+i), uint32(i)), now)
+	}
+
+	// older than the head, so it is the one dropped
+	j.push(jitterTestPacket(99, 0), now)
+	if head := j.packets[0].packet.SequenceNumber; head != 100 || len(j.packets) != jitterBufferCapacity {
+		t.Fatalf("expected the incoming packet to be dropped, head is %d with %d packets", head, len(j.packets))
+	}
+
+	j.push(jitterTestPacket(uint16(100+jitterBufferCapacity), jitterBufferCapacity), now)
+	if head := j.packets[0].packet.SequenceNumber; head != 101 || len(j.packets) != jitterBufferCapacity {
+		t.Fatalf("expected the head to be dropped, head is %d with %d packets", head, len(j.packets))
+	}
+
+	if overflows := j.getStats().Overflows; overflows != 2 {
+		t.Fatalf("expected 2 overflows, got %d", overflows)
+	}
+}
+
+func TestJitterBufferReorders(t *testing.T) {
+	j := newJitterBuffer(JitterBufferConfig{Max: time.Millisecond, Target: time.Millisecond})
+	j.reset(90000)
+
+	past := time.Now().Add(-time.Second)
+	for _, seq := range []uint16{10, 12, 11} {
+		j.push(jitterTestPacket(seq, uint32(seq)), past)
+	}
+
+	for _, expected := range []uint16{10, 11, 12} {
+		packet, _ := j.next()
+		if packet == nil || packet.SequenceNumber != expected {
+			t.Fatalf("expected packet %d, got %v", expected, packet)
+		}
+	}
+
+	if reordered := j.getStats().Reordered; reordered != 1 {
+		t.Fatalf("expected 1 reordered packet, got %d", reordered)
+	}
+}
@@ -0,0 +1,111 @@
+package mediasink
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v4"
+)
+
+// DefaultKeyframeRequestInterval is the minimum time between two keyframe requests of a sink.
+const DefaultKeyframeRequestInterval = 500 * time.Millisecond
+
+var (
+	ErrNoRemoteTrack          = errors.New("no remote track attached to the sink")
+	ErrKeyframeRequestLimited = errors.New("keyframe was requested too recently")
+)
+
+// rtcpWriter sends RTCP packets to the remote peer, usually webrtc.PeerConnection.WriteRTCP.
+type rtcpWriter = func([]rtcp.Packet) error
+
+// WithKeyframeRequestInterval sets the minimum time between two keyframe requests; requests in between
+// fail with ErrKeyframeRequestLimited.
+func WithKeyframeRequestInterval(interval time.Duration) SinkOption {
+	return func(sink *Sink) error {
+		if interval < 0 {
+			return errors.New("keyframe request interval cannot be negative")
+		}
+		sink.keyframeInterval = interval
+		return nil
+	}
+}
+
+// WithAutoKeyframeRequest makes a video sink request a keyframe as soon as a remote track is attached,
+// and whenever ReadFrame hands out a frame that depends on lost packets; the decoder cannot recover
+// from that before the next keyframe.
+func WithAutoKeyframeRequest() SinkOption {
+	return func(sink *Sink) error {
+		sink.autoKeyframe = true
+		return nil
+	}
+}
+
+// RequestKeyframe asks the sender of the remote track for a keyframe. It sends a PLI, or a FIR if the
+// codec negotiated "ccm fir" but not "nack pli". Requests are rate limited, see
+// WithKeyframeRequestInterval.
+func (s *Sink) RequestKeyframe() error {
+	s.mux.RLock()
+	generator := s.generator
+	writer := s.rtcpWriter
+	s.mux.RUnlock()
+
+	if generator == nil || writer == nil {
+		return ErrNoRemoteTrack
+	}
+
+	s.keyframeMux.Lock()
+	defer s.keyframeMux.Unlock()
+
+	now := time.Now()
+	if !s.lastKeyframeRequest.IsZero() && now.Sub(s.lastKeyframeRequest) < s.keyframeInterval {
+		return ErrKeyframeRequestLimited
+	}
+
+	ssrc := uint32(generator.SSRC())
+
+	var packet rtcp.Packet = &rtcp.PictureLossIndication{MediaSSRC: ssrc}
+	if usesFIR(generator.Codec()) {
+		s.firSequence++
+		packet = &rtcp.FullIntraRequest{
+			MediaSSRC: ssrc,
+			FIR:       []rtcp.FIREntry{{SSRC: ssrc, SequenceNumber: s.firSequence}},
+		}
+	}
+
+	if err := writer([]rtcp.Packet{packet}); err != nil {
+		return err
+	}
+
+	s.lastKeyframeRequest = now
+	s.logger.Debug("requested keyframe", "ssrc", ssrc, "rtcp", packet)
+
+	return nil
+}
+
+func usesFIR(codec webrtc.RTPCodecParameters) bool {
+	fir, pli := false, false
+	for _, feedback := range codec.RTCPFeedback {
+		switch {
+		case strings.EqualFold(feedback.Type, webrtc.TypeRTCPFBCCM) && strings.EqualFold(feedback.Parameter, "fir"):
+			fir = true
+		case strings.EqualFold(feedback.Type, webrtc.TypeRTCPFBNACK) && strings.EqualFold(feedback.Parameter, "pli"):
+			pli = true
+		}
+	}
+
+	return fir && !pli
+}
+
+// autoRequestKeyframe requests a keyframe if WithAutoKeyframeRequest is set; requests suppressed by the
+// rate limit are not reported.
+func (s *Sink) autoRequestKeyframe(reason string) {
+	if !s.autoKeyframe || s.Kind() != webrtc.RTPCodecTypeVideo {
+		return
+	}
+
+	if err := s.RequestKeyframe(); err != nil && !errors.Is(err, ErrKeyframeRequestLimited) {
+		s.logger.Warn("failed to request keyframe", "reason", reason, "err", err)
+	}
+}
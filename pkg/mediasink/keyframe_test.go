@@ -0,0 +1,205 @@
+package mediasink_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+
+	"github.com/harshabose/simple_webrtc_comm/client"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/clienttest"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+// keyframeRequests returns a source option and the channel every keyframe request of the remote peer is
+// reported on.
+func keyframeRequests() (mediasource.TrackOption, <-chan mediasource.RTCPEventType) {
+	requests := make(chan mediasource.RTCPEventType, 64)
+
+	return mediasource.WithRTCPHandler(func(event mediasource.RTCPEvent) {
+		if event.Type != mediasource.RTCPEventPLI && event.Type != mediasource.RTCPEventFIR {
+			return
+		}
+		select {
+		case requests <- event.Type:
+		default:
+		}
+	}), requests
+}
+
+func awaitKeyframeRequest(t *testing.T, requests <-chan mediasource.RTCPEventType) {
+	t.Helper()
+
+	select {
+	case request := <-requests:
+		if request != mediasource.RTCPEventPLI {
+			t.Fatalf("expected a pli, as the codec negotiated 'nack pli', got %s", request)
+		}
+	case <-time.After(clienttest.DefaultTimeout):
+		t.Fatal("no keyframe request reached the source")
+	}
+}
+
+func assertNoKeyframeRequest(t *testing.T, requests <-chan mediasource.RTCPEventType) {
+	t.Helper()
+
+	select {
+	case request := <-requests:
+		t.Fatalf("expected no keyframe request, got %s", request)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// connectedKeyframeSink connects a VP8 source, reporting keyframe requests, to a sink.
+func connectedKeyframeSink(t *testing.T, options ...mediasink.SinkOption) (*mediasink.Sink, <-chan mediasource.RTCPEventType) {
+	t.Helper()
+
+	pair := clienttest.NewPair(t, client.WithDefaultMediaEngine())
+	offer, answer := pair.PeerConnections(t, "media")
+
+	option, requests := keyframeRequests()
+	source := createSource(t, offer, "video", mediasource.WithVP8Track(90000), option)
+	sink := createSink(t, answer, "video", append([]mediasink.SinkOption{mediasink.WithVP8Track(90000)}, options...)...)
+
+	pair.Connect(t)
+	clienttest.AssertMediaFlows(t, source, sink)
+
+	return sink, requests
+}
+
+func TestRequestKeyframeWithoutRemoteTrack(t *testing.T) {
+	sink, err := mediasink.CreateSink(context.Background(), mediasink.WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.RequestKeyframe(); !errors.Is(err, mediasink.ErrNoRemoteTrack) {
+		t.Fatalf("expected ErrNoRemoteTrack, got %v", err)
+	}
+}
+
+func TestRequestKeyframeIsRateLimited(t *testing.T) {
+	sink, requests := connectedKeyframeSink(t, mediasink.WithKeyframeRequestInterval(time.Hour))
+
+	if err := sink.RequestKeyframe(); err != nil {
+		t.Fatal(err)
+	}
+	awaitKeyframeRequest(t, requests)
+
+	if err := sink.RequestKeyframe(); !errors.Is(err, mediasink.ErrKeyframeRequestLimited) {
+		t.Fatalf("expected ErrKeyframeRequestLimited, got %v", err)
+	}
+	assertNoKeyframeRequest(t, requests)
+}
+
+func TestRequestKeyframeWithoutInterval(t *testing.T) {
+	sink, requests := connectedKeyframeSink(t, mediasink.WithKeyframeRequestInterval(0))
+
+	for i := 0; i < 2; i++ {
+		if err := sink.RequestKeyframe(); err != nil {
+			t.Fatal(err)
+		}
+		awaitKeyframeRequest(t, requests)
+	}
+}
+
+func TestAutoKeyframeRequestOnAttach(t *testing.T) {
+	_, requests := connectedKeyframeSink(t, mediasink.WithAutoKeyframeRequest())
+
+	awaitKeyframeRequest(t, requests)
+}
+
+func TestNoKeyframeRequestWithoutAuto(t *testing.T) {
+	_, requests := connectedKeyframeSink(t)
+
+	assertNoKeyframeRequest(t, requests)
+}
+
+// vp8Packets numbers VP8 packets of one packet per frame; the payload descriptor starts a partition and
+// the P bit of the payload header marks the frame as a keyframe or not.
+type vp8Packets struct {
+	sequenceNumber uint16
+	timestamp      uint32
+}
+
+func (p *vp8Packets) next(keyframe bool) *rtp.Packet {
+	p.sequenceNumber++
+	p.timestamp += 3000
+
+	header := byte(0x03)
+	if keyframe {
+		header = 0x02
+	}
+
+	return &rtp.Packet{
+		Header:  rtp.Header{Version: 2, Marker: true, SequenceNumber: p.sequenceNumber, Timestamp: p.timestamp},
+		Payload: []byte{0x10, header, 0x00, 0x9d, 0x01, 0x2a, 0x00, 0x00},
+	}
+}
+
+func TestAutoKeyframeRequestOnLoss(t *testing.T) {
+	pair := clienttest.NewPair(t, client.WithDefaultMediaEngine())
+	offer, answer := pair.PeerConnections(t, "media")
+
+	option, requests := keyframeRequests()
+	source, err := offer.CreateRTPMediaSource("video", mediasource.WithVP8Track(90000), option)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := createSink(t, answer, "video", mediasink.WithVP8Track(90000), mediasink.WithAutoKeyframeRequest(),
+		mediasink.WithKeyframeRequestInterval(0), mediasink.WithFrameBuffer(4, 0))
+
+	pair.Connect(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), clienttest.DefaultTimeout)
+	defer cancel()
+
+	frames := make(chan *mediasink.Frame, 256)
+	go func() {
+		for {
+			frame, err := sink.ReadFrame(ctx)
+			if err != nil {
+				return
+			}
+			frames <- frame
+		}
+	}()
+
+	packets := &vp8Packets{}
+	write := func(keyframe bool) {
+		if err := source.WriteRTP(packets.next(keyframe)); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// NOTE: THE FIRST REQUEST IS THE ONE FOR ATTACHING THE TRACK
+	for i := 0; i < 20; i++ {
+		write(i == 0)
+	}
+	awaitKeyframeRequest(t, requests)
+	for len(requests) > 0 {
+		<-requests
+	}
+
+	// NOTE: THE SEQUENCE NUMBER OF THE SKIPPED PACKET NEVER ARRIVES
+	packets.next(false)
+	for i := 0; i < 20; i++ {
+		write(false)
+	}
+
+	for {
+		select {
+		case frame := <-frames:
+			if frame.Lost {
+				awaitKeyframeRequest(t, requests)
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("no frame was reported lost after the gap")
+		}
+	}
+}
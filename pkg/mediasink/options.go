@@ -2,21 +2,59 @@ package mediasink
 
 import (
 	"errors"
+	"log/slog"
 
 	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
 )
 
 type SinkOption = func(*Sink) error
 
 // TODO: CLOCKRATE, STEREO, PROFILE etc ARE IN MEDIA SOURCE. MAYBE BE INCLUDE THEM HERE?
 
+// WithLogger sets the logger of the sink.
+func WithLogger(logger *slog.Logger) SinkOption {
+	return func(sink *Sink) error {
+		if logger != nil {
+			sink.logger = logger
+		}
+		return nil
+	}
+}
+
+// WithCodecMatcher sets how the sink decides whether a remote track has a codec it can receive;
+// DefaultCodecMatcher is used otherwise.
+func WithCodecMatcher(matcher CodecMatcher) SinkOption {
+	return func(sink *Sink) error {
+		if matcher == nil {
+			return errors.New("codec matcher cannot be nil")
+		}
+		sink.matcher = matcher
+		return nil
+	}
+}
+
+// WithFmtpLine sets the fmtp line of the sink's codec, e.g. "packetization-mode=1;profile-level-id=42e01f"
+// to only accept constrained baseline H.264 in packetization mode 1. It has to come after the track
+// option.
+func WithFmtpLine(line string) SinkOption {
+	return func(sink *Sink) error {
+		if sink.codecCapability == nil {
+			return errors.New("fmtp line needs to be set after the track")
+		}
+		sink.codecCapability.SDPFmtpLine = line
+		return nil
+	}
+}
+
 func WithH264Track(clockrate uint32) SinkOption {
 	return func(track *Sink) error {
 		if track.codecCapability != nil {
 			return errors.New("multiple tracks are not supported on single media source")
 		}
 		track.codecCapability = &webrtc.RTPCodecParameters{}
-		track.codecCapability.PayloadType = webrtc.PayloadType(102)
+		track.codecCapability.PayloadType = mediasource.H264PayloadType
 		track.codecCapability.MimeType = webrtc.MimeTypeH264
 		track.codecCapability.ClockRate = clockrate
 		track.codecCapability.Channels = 0
@@ -31,7 +69,7 @@ func WithVP8Track(clockrate uint32) SinkOption {
 			return errors.New("multiple tracks are not supported on single media source")
 		}
 		track.codecCapability = &webrtc.RTPCodecParameters{}
-		track.codecCapability.PayloadType = webrtc.PayloadType(96)
+		track.codecCapability.PayloadType = mediasource.VP8PayloadType
 		track.codecCapability.MimeType = webrtc.MimeTypeVP8
 		track.codecCapability.ClockRate = clockrate
 		track.codecCapability.Channels = 0
@@ -40,13 +78,67 @@ func WithVP8Track(clockrate uint32) SinkOption {
 	}
 }
 
+// WithVP9Track sets a VP9 sink of the given profile, which has to be 0 or 2. The payload types follow
+// the ones WithVP9MediaEngine of the client registers.
+func WithVP9Track(clockrate uint32, profile uint8) SinkOption {
+	return func(track *Sink) error {
+		if track.codecCapability != nil {
+			return errors.New("multiple tracks are not supported on single media source")
+		}
+		payloadType, _, fmtpLine, err := mediasource.VP9Codec(profile)
+		if err != nil {
+			return err
+		}
+		track.codecCapability = &webrtc.RTPCodecParameters{}
+		track.codecCapability.PayloadType = payloadType
+		track.codecCapability.MimeType = webrtc.MimeTypeVP9
+		track.codecCapability.ClockRate = clockrate
+		track.codecCapability.Channels = 0
+		track.codecCapability.SDPFmtpLine = fmtpLine
+
+		return nil
+	}
+}
+
+func WithAV1Track(clockrate uint32) SinkOption {
+	return func(track *Sink) error {
+		if track.codecCapability != nil {
+			return errors.New("multiple tracks are not supported on single media source")
+		}
+		track.codecCapability = &webrtc.RTPCodecParameters{}
+		track.codecCapability.PayloadType = mediasource.AV1PayloadType
+		track.codecCapability.MimeType = webrtc.MimeTypeAV1
+		track.codecCapability.ClockRate = clockrate
+		track.codecCapability.Channels = 0
+		track.codecCapability.SDPFmtpLine = mediasource.AV1Fmtp
+
+		return nil
+	}
+}
+
+func WithH265Track(clockrate uint32) SinkOption {
+	return func(track *Sink) error {
+		if track.codecCapability != nil {
+			return errors.New("multiple tracks are not supported on single media source")
+		}
+		track.codecCapability = &webrtc.RTPCodecParameters{}
+		track.codecCapability.PayloadType = mediasource.H265PayloadType
+		track.codecCapability.MimeType = webrtc.MimeTypeH265
+		track.codecCapability.ClockRate = clockrate
+		track.codecCapability.Channels = 0
+		track.codecCapability.SDPFmtpLine = mediasource.H265Fmtp
+
+		return nil
+	}
+}
+
 func WithOpusTrack(samplerate uint32, channelLayout uint16) SinkOption {
 	return func(track *Sink) error {
 		if track.codecCapability != nil {
 			return errors.New("multiple tracks are not supported on single media source")
 		}
 		track.codecCapability = &webrtc.RTPCodecParameters{}
-		track.codecCapability.PayloadType = webrtc.PayloadType(111)
+		track.codecCapability.PayloadType = mediasource.OpusPayloadType
 		track.codecCapability.MimeType = webrtc.MimeTypeOpus
 		track.codecCapability.ClockRate = samplerate
 		track.codecCapability.Channels = channelLayout
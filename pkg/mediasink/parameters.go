@@ -0,0 +1,462 @@
+package mediasink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// ErrNoVideoParameters is returned by ParseVideoParameters for frames that do not carry the size of
+// the video, like frames that are not keyframes, or H.264 keyframes sent without SPS and PPS.
+var ErrNoVideoParameters = errors.New("frame does not carry the video parameters")
+
+// VideoParameters is what a muxer needs to know about a video stream before writing its header.
+// Extradata is the SPS and PPS (Annex-B) for H.264 and the sequence header OBU for AV1; VP8 and VP9 have
+// none.
+type VideoParameters struct {
+	Width     int
+	Height    int
+	Extradata []byte
+}
+
+// ParseVideoParameters reads the size and codec configuration of the video from a keyframe, as returned
+// by ReadFrame, of the codec with the given MIME type.
+func ParseVideoParameters(mimeType string, keyframe []byte) (VideoParameters, error) {
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return parseH264Parameters(keyframe)
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return parseVP8Parameters(keyframe)
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		return parseVP9Parameters(keyframe)
+	case strings.ToLower(webrtc.MimeTypeAV1):
+		return parseAV1Parameters(keyframe)
+	default:
+		return VideoParameters{}, fmt.Errorf("%w '%s'", ErrNoDepacketizer, mimeType)
+	}
+}
+
+// OpusHead returns the identification header of an Opus stream (RFC 7845), the extradata muxers expect
+// for Opus. The pre-skip is the 312 samples libopus encoders use by default; output gain and channel
+// mapping family stay 0, which covers mono and stereo.
+func OpusHead(channels uint16) []byte {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1 // version
+	head[9] = byte(channels)
+	binary.LittleEndian.PutUint16(head[10:], 312)
+	binary.LittleEndian.PutUint32(head[12:], 48000)
+
+	return head
+}
+
+func parseH264Parameters(keyframe []byte) (VideoParameters, error) {
+	var sps, pps []byte
+	for _, nalu := range splitAnnexB(keyframe) {
+		switch nalu[0] & 0x1F {
+		case 7:
+			if sps == nil {
+				sps = nalu
+			}
+		case 8:
+			if pps == nil {
+				pps = nalu
+			}
+		}
+	}
+	if sps == nil || pps == nil {
+		return VideoParameters{}, ErrNoVideoParameters
+	}
+
+	width, height, err := parseH264SPS(sps)
+	if err != nil {
+		return VideoParameters{}, err
+	}
+
+	startCode := []byte{0, 0, 0, 1}
+	extradata := make([]byte, 0, 2*len(startCode)+len(sps)+len(pps))
+	extradata = append(append(extradata, startCode...), sps...)
+	extradata = append(append(extradata, startCode...), pps...)
+
+	return VideoParameters{Width: width, Height: height, Extradata: extradata}, nil
+}
+
+// parseH264SPS returns the cropped size of the pictures described by the SPS NAL unit (ITU-T H.264,
+// 7.3.2.1.1).
+func parseH264SPS(nalu []byte) (int, int, error) {
+	r := &bitReader{data: removeEmulationPrevention(nalu[1:])}
+
+	profile := r.bits(8)
+	r.skip(16) // constraint flags and level
+	r.ue()     // seq_parameter_set_id
+
+	chromaFormat := uint64(1)
+	separatePlanes := false
+	switch profile {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		chromaFormat = r.ue()
+		if chromaFormat == 3 {
+			separatePlanes = r.bit()
+		}
+		r.ue()       // bit_depth_luma_minus8
+		r.ue()       // bit_depth_chroma_minus8
+		r.skip(1)    // qpprime_y_zero_transform_bypass_flag
+		if r.bit() { // seq_scaling_matrix_present_flag
+			lists := 8
+			if chromaFormat == 3 {
+				lists = 12
+			}
+			for i := 0; i < lists; i++ {
+				if !r.bit() {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				skipScalingList(r, size)
+			}
+		}
+	}
+
+	r.ue()          // log2_max_frame_num_minus4
+	switch r.ue() { // pic_order_cnt_type
+	case 0:
+		r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		r.skip(1) // delta_pic_order_always_zero_flag
+		r.se()    // offset_for_non_ref_pic
+		r.se()    // offset_for_top_to_bottom_field
+		for n := r.ue(); n > 0 && r.err == nil; n-- {
+			r.se() // offset_for_ref_frame
+		}
+	}
+	r.ue()    // max_num_ref_frames
+	r.skip(1) // gaps_in_frame_num_value_allowed_flag
+
+	widthInMBs := r.ue() + 1
+	heightInMapUnits := r.ue() + 1
+	frameMBsOnly := r.bit()
+	if !frameMBsOnly {
+		r.skip(1) // mb_adaptive_frame_field_flag
+	}
+	r.skip(1) // direct_8x8_inference_flag
+
+	var cropLeft, cropRight, cropTop, cropBottom uint64
+	if r.bit() {
+		cropLeft, cropRight, cropTop, cropBottom = r.ue(), r.ue(), r.ue(), r.ue()
+	}
+
+	if r.err != nil {
+		return 0, 0, fmt.Errorf("invalid H.264 SPS: %w", r.err)
+	}
+
+	fieldFactor := uint64(2)
+	if frameMBsOnly {
+		fieldFactor = 1
+	}
+
+	cropUnitX, cropUnitY := uint64(1), fieldFactor
+	if chromaFormat != 0 && !separatePlanes {
+		subWidth, subHeight := uint64(2), uint64(2)
+		switch chromaFormat {
+		case 2:
+			subHeight = 1
+		case 3:
+			subWidth, subHeight = 1, 1
+		}
+		cropUnitX, cropUnitY = subWidth, subHeight*fieldFactor
+	}
+
+	width := widthInMBs*16 - (cropLeft+cropRight)*cropUnitX
+	height := fieldFactor*heightInMapUnits*16 - (cropTop+cropBottom)*cropUnitY
+
+	return int(width), int(height), nil
+}
+
+func skipScalingList(r *bitReader, size int) {
+	last, next := int64(8), int64(8)
+	for j := 0; j < size && r.err == nil; j++ {
+		if next != 0 {
+			next = (last + r.se() + 256) % 256
+		}
+		if next != 0 {
+			last = next
+		}
+	}
+}
+
+// removeEmulationPrevention drops the 0x03 bytes the encoder inserted after two zero bytes.
+func removeEmulationPrevention(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	zeros := 0
+	for _, b := range data {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		out = append(out, b)
+	}
+
+	return out
+}
+
+// parseVP8Parameters reads the size from the frame header of a VP8 keyframe (RFC 6386, 9.1).
+func parseVP8Parameters(keyframe []byte) (VideoParameters, error) {
+	if !isVP8Keyframe(keyframe, nil) {
+		return VideoParameters{}, ErrNoVideoParameters
+	}
+	if len(keyframe) < 10 || keyframe[3] != 0x9d || keyframe[4] != 0x01 || keyframe[5] != 0x2a {
+		return VideoParameters{}, errors.New("invalid VP8 keyframe header")
+	}
+
+	return VideoParameters{
+		Width:  int(binary.LittleEndian.Uint16(keyframe[6:]) & 0x3FFF),
+		Height: int(binary.LittleEndian.Uint16(keyframe[8:]) & 0x3FFF),
+	}, nil
+}
+
+// parseVP9Parameters reads the size from the uncompressed header of a VP9 keyframe (VP9 bitstream
+// specification, 6.2).
+func parseVP9Parameters(keyframe []byte) (VideoParameters, error) {
+	r := &bitReader{data: keyframe}
+
+	if r.bits(2) != 2 { // frame_marker
+		return VideoParameters{}, errors.New("invalid VP9 frame marker")
+	}
+	profile := r.bits(1)
+	profile |= r.bits(1) << 1
+	if profile == 3 {
+		r.skip(1)
+	}
+	if r.bit() { // show_existing_frame
+		return VideoParameters{}, ErrNoVideoParameters
+	}
+	if r.bit() { // frame_type; 0 is a keyframe
+		return VideoParameters{}, ErrNoVideoParameters
+	}
+	r.skip(2) // show_frame, error_resilient_mode
+
+	if r.bits(24) != 0x498342 {
+		return VideoParameters{}, errors.New("invalid VP9 sync code")
+	}
+
+	// color_config
+	if profile >= 2 {
+		r.skip(1) // ten_or_twelve_bit
+	}
+	if r.bits(3) != 7 { // color_space is not CS_RGB
+		r.skip(1) // color_range
+		if profile == 1 || profile == 3 {
+			r.skip(3) // subsampling_x, subsampling_y, reserved_zero
+		}
+	} else if profile == 1 || profile == 3 {
+		r.skip(1) // reserved_zero
+	}
+
+	width, height := r.bits(16)+1, r.bits(16)+1
+	if r.err != nil {
+		return VideoParameters{}, fmt.Errorf("invalid VP9 keyframe header: %w", r.err)
+	}
+
+	return VideoParameters{Width: int(width), Height: int(height)}, nil
+}
+
+const av1OBUSequenceHeader = 1
+
+// parseAV1Parameters finds the sequence header OBU of a temporal unit and reads the maximum frame size
+// from it (AV1 bitstream specification, 5.5). The OBU becomes the extradata.
+func parseAV1Parameters(keyframe []byte) (VideoParameters, error) {
+	for data := keyframe; len(data) > 0; {
+		header := data[0]
+		headerSize := 1
+		if header&0x04 != 0 { // obu_extension_flag
+			headerSize++
+		}
+		if len(data) < headerSize {
+			break
+		}
+
+		payloadSize, sizeLength := len(data)-headerSize, 0
+		if header&0x02 != 0 { // obu_has_size_field
+			size, n := readLEB128(data[headerSize:])
+			if n == 0 {
+				break
+			}
+			payloadSize, sizeLength = int(size), n
+		}
+
+		start := headerSize + sizeLength
+		if payloadSize < 0 || start+payloadSize > len(data) {
+			break
+		}
+		payload := data[start : start+payloadSize]
+
+		if (header>>3)&0x0F == av1OBUSequenceHeader {
+			width, height, err := parseAV1SequenceHeader(payload)
+			if err != nil {
+				return VideoParameters{}, err
+			}
+
+			// NOTE: MUXERS EXPECT THE OBU WITH ITS SIZE FIELD
+			obu := append([]byte{header | 0x02}, data[1:headerSize]...)
+			obu = appendLEB128(obu, uint64(len(payload)))
+			obu = append(obu, payload...)
+
+			return VideoParameters{Width: width, Height: height, Extradata: obu}, nil
+		}
+
+		data = data[start+payloadSize:]
+	}
+
+	return VideoParameters{}, ErrNoVideoParameters
+}
+
+func parseAV1SequenceHeader(payload []byte) (int, int, error) {
+	r := &bitReader{data: payload}
+
+	r.skip(3)    // seq_profile
+	r.skip(1)    // still_picture
+	if r.bit() { // reduced_still_picture_header
+		r.skip(5) // seq_level_idx[0]
+	} else {
+		decoderModelInfo := false
+		bufferDelayLength := uint64(0)
+		if r.bit() { // timing_info_present_flag
+			r.skip(64)   // num_units_in_display_tick, time_scale
+			if r.bit() { // equal_picture_interval
+				r.uvlc() // num_ticks_per_picture_minus_1
+			}
+			if decoderModelInfo = r.bit(); decoderModelInfo {
+				bufferDelayLength = r.bits(5) + 1
+				r.skip(32 + 5 + 5) // num_units_in_decoding_tick, buffer_removal_time_length_minus_1, frame_presentation_time_length_minus_1
+			}
+		}
+		initialDisplayDelay := r.bit()
+
+		for i := r.bits(5) + 1; i > 0 && r.err == nil; i-- { // operating_points_cnt_minus_1
+			r.skip(12)         // operating_point_idc
+			if r.bits(5) > 7 { // seq_level_idx
+				r.skip(1) // seq_tier
+			}
+			if decoderModelInfo && r.bit() { // decoder_model_present_for_this_op
+				r.skip(int(2*bufferDelayLength) + 1) // decoder_buffer_delay, encoder_buffer_delay, low_delay_mode_flag
+			}
+			if initialDisplayDelay && r.bit() { // initial_display_delay_present_for_this_op
+				r.skip(4)
+			}
+		}
+	}
+
+	widthBits := int(r.bits(4)) + 1
+	heightBits := int(r.bits(4)) + 1
+	width, height := r.bits(widthBits)+1, r.bits(heightBits)+1
+
+	if r.err != nil {
+		return 0, 0, fmt.Errorf("invalid AV1 sequence header: %w", r.err)
+	}
+
+	return int(width), int(height), nil
+}
+
+func readLEB128(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(data) && i < 8; i++ {
+		value |= uint64(data[i]&0x7F) << (7 * i)
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+
+	return 0, 0
+}
+
+func appendLEB128(data []byte, value uint64) []byte {
+	for {
+		b := byte(value & 0x7F)
+		value >>= 7
+		if value == 0 {
+			return append(data, b)
+		}
+		data = append(data, b|0x80)
+	}
+}
+
+var errBitstreamTooShort = errors.New("bitstream too short")
+
+// bitReader reads big-endian bit fields; after running past the end, it keeps returning 0 and err is set.
+type bitReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *bitReader) bit() bool {
+	return r.bits(1) == 1
+}
+
+func (r *bitReader) bits(n int) uint64 {
+	var value uint64
+	for ; n > 0; n-- {
+		if r.pos >= 8*len(r.data) {
+			r.err = errBitstreamTooShort
+			return 0
+		}
+		value = value<<1 | uint64(r.data[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+
+	return value
+}
+
+func (r *bitReader) skip(n int) {
+	r.bits(n)
+}
+
+// ue reads an unsigned Exp-Golomb code.
+func (r *bitReader) ue() uint64 {
+	zeros := 0
+	for !r.bit() {
+		if r.err != nil || zeros >= 32 {
+			r.err = errors.New("invalid exp-golomb code")
+			return 0
+		}
+		zeros++
+	}
+
+	return 1<<zeros - 1 + r.bits(zeros)
+}
+
+// se reads a signed Exp-Golomb code.
+func (r *bitReader) se() int64 {
+	value := r.ue()
+	if value%2 == 1 {
+		return int64(value+1) / 2
+	}
+
+	return -int64(value / 2)
+}
+
+// uvlc reads a variable length code of AV1.
+func (r *bitReader) uvlc() uint64 {
+	zeros := 0
+	for !r.bit() {
+		if r.err != nil {
+			return 0
+		}
+		zeros++
+	}
+	if zeros >= 32 {
+		return 1<<32 - 1
+	}
+
+	return r.bits(zeros) + (1<<zeros - 1)
+}
@@ -0,0 +1,105 @@
+package mediasink_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+)
+
+func TestParseVideoParameters(t *testing.T) {
+	// SPS and PPS of a 640x480 constrained baseline stream, followed by the start of an IDR slice
+	h264SPS := []byte{0x67, 0x42, 0xc0, 0x1f, 0x1a, 0x32, 0x35, 0x01, 0x40, 0x7a, 0x40, 0x3c, 0x22, 0x11, 0xa8}
+	h264PPS := []byte{0x68, 0x1a, 0x34, 0xe3, 0xc8}
+	h264 := bytes.Join([][]byte{{}, h264SPS, h264PPS, {0x65, 0x88, 0x84}}, []byte{0, 0, 0, 1})
+
+	av1SequenceHeader := []byte{0x0a, 0x08, 0x00, 0x00, 0x00, 0x42, 0xa6, 0x7f, 0xd9, 0xe0}
+	// a temporal delimiter, the sequence header and the start of a frame OBU
+	av1 := append(append([]byte{0x12, 0x00}, av1SequenceHeader...), 0x32, 0x01, 0x10)
+
+	tests := []struct {
+		name      string
+		mimeType  string
+		keyframe  []byte
+		width     int
+		height    int
+		extradata []byte
+	}{
+		{
+			name:      "h264",
+			mimeType:  webrtc.MimeTypeH264,
+			keyframe:  h264,
+			width:     640,
+			height:    480,
+			extradata: bytes.Join([][]byte{{}, h264SPS, h264PPS}, []byte{0, 0, 0, 1}),
+		},
+		{
+			name:     "vp8",
+			mimeType: webrtc.MimeTypeVP8,
+			keyframe: []byte{0x50, 0x42, 0x00, 0x9d, 0x01, 0x2a, 0x80, 0x02, 0xe0, 0x01, 0x00},
+			width:    640,
+			height:   480,
+		},
+		{
+			name:     "vp9",
+			mimeType: webrtc.MimeTypeVP9,
+			keyframe: []byte{0x82, 0x49, 0x83, 0x42, 0x20, 0x27, 0xf0, 0x16, 0x70, 0x00},
+			width:    640,
+			height:   360,
+		},
+		{
+			name:      "av1",
+			mimeType:  webrtc.MimeTypeAV1,
+			keyframe:  av1,
+			width:     1280,
+			height:    720,
+			extradata: av1SequenceHeader,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parameters, err := mediasink.ParseVideoParameters(test.mimeType, test.keyframe)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if parameters.Width != test.width || parameters.Height != test.height {
+				t.Fatalf("expected %dx%d, got %dx%d", test.width, test.height, parameters.Width, parameters.Height)
+			}
+			if !bytes.Equal(parameters.Extradata, test.extradata) {
+				t.Fatalf("expected extradata %x, got %x", test.extradata, parameters.Extradata)
+			}
+		})
+	}
+}
+
+func TestParseVideoParametersWithoutParameters(t *testing.T) {
+	tests := map[string]struct {
+		mimeType string
+		frame    []byte
+	}{
+		"h264 without sps": {webrtc.MimeTypeH264, []byte{0, 0, 0, 1, 0x65, 0x88, 0x84}},
+		"vp8 interframe":   {webrtc.MimeTypeVP8, []byte{0x51, 0x42, 0x00, 0x00}},
+		"vp9 interframe":   {webrtc.MimeTypeVP9, []byte{0x86, 0x00}},
+		"av1 frame only":   {webrtc.MimeTypeAV1, []byte{0x12, 0x00, 0x32, 0x01, 0x10}},
+	}
+
+	for name, test := range tests {
+		if _, err := mediasink.ParseVideoParameters(test.mimeType, test.frame); !errors.Is(err, mediasink.ErrNoVideoParameters) {
+			t.Errorf("%s: expected ErrNoVideoParameters, got %v", name, err)
+		}
+	}
+}
+
+func TestOpusHead(t *testing.T) {
+	head := mediasink.OpusHead(2)
+
+	expected := []byte{'O', 'p', 'u', 's', 'H', 'e', 'a', 'd', 1, 2, 0x38, 0x01, 0x80, 0xbb, 0x00, 0x00, 0, 0, 0}
+	if !bytes.Equal(head, expected) {
+		t.Fatalf("expected %x, got %x", expected, head)
+	}
+}
@@ -0,0 +1,65 @@
+package mediasink
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pion/webrtc/v4"
+)
+
+func newTestReceiver(t *testing.T, api *webrtc.API, dtls *webrtc.DTLSTransport) *webrtc.RTPReceiver {
+	t.Helper()
+
+	receiver, err := api.NewRTPReceiver(webrtc.RTPCodecTypeVideo, dtls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = receiver.Stop()
+	})
+
+	return receiver
+}
+
+func TestSinkReadsOneReceiverAtATime(t *testing.T) {
+	api := webrtc.NewAPI()
+	gatherer, err := api.NewICEGatherer(webrtc.ICEGatherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtls, err := api.NewDTLSTransport(api.NewICETransport(gatherer), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := CreateSink(context.Background(), WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := newTestReceiver(t, api, dtls)
+	sink.setGenerator(nil, first, nil)
+
+	stopped := 0
+	stop := sink.stopReceiver
+	sink.stopReceiver = func() {
+		stopped++
+		stop()
+	}
+
+	// NOTE: RENEGOTIATION FIRES OnTrack AGAIN FOR THE SAME RECEIVER
+	sink.setGenerator(nil, first, nil)
+	if stopped != 0 || sink.rtpReceiver != first {
+		t.Fatal("expected the loop of the first receiver to keep running")
+	}
+
+	sink.setGenerator(nil, newTestReceiver(t, api, dtls), nil)
+	if stopped != 1 {
+		t.Fatal("expected the loop of the first receiver to end once it was replaced")
+	}
+
+	sink.setGenerator(nil, nil, nil)
+	if sink.stopReceiver != nil || sink.rtpReceiver != nil {
+		t.Fatal("expected no receiver to be read after detaching")
+	}
+}
@@ -0,0 +1,408 @@
+package mediasink
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media/oggwriter"
+
+	"github.com/harshabose/tools/pkg/multierr"
+)
+
+// RecorderConfig configures where and how a Recorder writes. The container follows the codec of the
+// sink: IVF for VP8, VP9 and AV1, an Annex-B byte stream for H.264 and Ogg for Opus.
+type RecorderConfig struct {
+	// Path is the file to record to. With rotation the segments are numbered, "cam.ivf" becomes
+	// "cam-000.ivf", "cam-001.ivf" and so on.
+	Path string
+	// MaxDuration and MaxSize (in bytes) start a new segment once exceeded; zero disables either.
+	// Video segments always start at a keyframe, so segments overshoot till the next one.
+	MaxDuration time.Duration
+	MaxSize     int64
+}
+
+func (config RecorderConfig) rotates() bool {
+	return config.MaxDuration > 0 || config.MaxSize > 0
+}
+
+func (config RecorderConfig) segmentPath(index int) string {
+	if !config.rotates() {
+		return config.Path
+	}
+
+	extension := filepath.Ext(config.Path)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(config.Path, extension), index, extension)
+}
+
+// containerWriter writes the frames of one segment.
+type containerWriter interface {
+	// writeFrame writes the frame with its timestamp relative to the start of the segment and returns
+	// how many bytes were written
+	writeFrame(frame *Frame, pts time.Duration) (int, error)
+	close() error
+}
+
+type containerFactory = func(path string, codec webrtc.RTPCodecParameters) (containerWriter, error)
+
+func containerFor(codec webrtc.RTPCodecParameters) (containerFactory, error) {
+	switch strings.ToLower(codec.MimeType) {
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return newIVFContainer("VP80"), nil
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		return newIVFContainer("VP90"), nil
+	case strings.ToLower(webrtc.MimeTypeAV1):
+		return newIVFContainer("AV01"), nil
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return newAnnexBContainer, nil
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		return newOggContainer, nil
+	default:
+		return nil, fmt.Errorf("no container to record codec '%s' in", codec.MimeType)
+	}
+}
+
+// Recorder writes everything a Sink receives to disk. It reads the sink with ReadFrame, so nothing else
+// should read the sink while recording.
+type Recorder struct {
+	sink      *Sink
+	config    RecorderConfig
+	newWriter containerFactory
+	codec     webrtc.RTPCodecParameters
+	video     bool
+
+	writer       containerWriter
+	segment      int
+	segmentStart time.Duration
+	segmentSize  int64
+	files        []string
+
+	logger *slog.Logger
+	mux    sync.Mutex
+	once   sync.Once
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewRecorder(ctx context.Context, sink *Sink, config RecorderConfig) (*Recorder, error) {
+	if config.Path == "" {
+		return nil, errors.New("recorder needs a path")
+	}
+
+	codec := sink.Codec()
+	newWriter, err := containerFor(codec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory for %s: %w", config.Path, err)
+	}
+
+	ctx2, cancel2 := context.WithCancel(ctx)
+
+	return &Recorder{
+		sink:      sink,
+		config:    config,
+		newWriter: newWriter,
+		codec:     codec,
+		video:     sink.Kind() == webrtc.RTPCodecTypeVideo,
+		logger:    sink.logger.With("recording", config.Path),
+		ctx:       ctx2,
+		cancel:    cancel2,
+	}, nil
+}
+
+func (r *Recorder) Start() {
+	r.wg.Add(1)
+	go r.loop()
+}
+
+// Close stops the recording and finalises the current segment.
+func (r *Recorder) Close() error {
+	var err error
+	r.once.Do(func() {
+		r.cancel()
+		r.wg.Wait()
+
+		r.mux.Lock()
+		defer r.mux.Unlock()
+
+		if r.writer != nil {
+			err = r.writer.close()
+			r.writer = nil
+		}
+	})
+
+	return err
+}
+
+// Files returns the files written so far, the current segment last.
+func (r *Recorder) Files() []string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	return append([]string(nil), r.files...)
+}
+
+func (r *Recorder) loop() {
+	defer r.wg.Done()
+
+	for {
+		frame, err := r.sink.ReadFrame(r.ctx)
+		if err != nil {
+			if r.ctx.Err() == nil {
+				r.logger.Warn("stopped recording", "err", err)
+			}
+			return
+		}
+
+		if err := r.write(frame); err != nil {
+			r.logger.Warn("failed to record frame", "err", err)
+		}
+	}
+}
+
+func (r *Recorder) write(frame *Frame) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	if r.writer != nil && r.shouldRotate(frame) {
+		if err := r.writer.close(); err != nil {
+			r.logger.Warn("failed to finalise segment", "err", err)
+		}
+		r.writer = nil
+		r.segment++
+	}
+
+	if r.writer == nil {
+		// NOTE: A VIDEO SEGMENT THAT DOES NOT START WITH A KEYFRAME CANNOT BE DECODED
+		if r.video && !frame.Keyframe {
+			return nil
+		}
+
+		path := r.config.segmentPath(r.segment)
+		writer, err := r.newWriter(path, r.codec)
+		if err != nil {
+			return err
+		}
+
+		r.writer = writer
+		r.segmentStart = frame.PTS
+		r.segmentSize = 0
+		r.files = append(r.files, path)
+		r.logger.Info("recording segment", "file", path)
+	}
+
+	n, err := r.writer.writeFrame(frame, frame.PTS-r.segmentStart)
+	r.segmentSize += int64(n)
+
+	return err
+}
+
+func (r *Recorder) shouldRotate(frame *Frame) bool {
+	if !r.config.rotates() || (r.video && !frame.Keyframe) {
+		return false
+	}
+
+	if r.config.MaxDuration > 0 && frame.PTS-r.segmentStart >= r.config.MaxDuration {
+		return true
+	}
+
+	return r.config.MaxSize > 0 && r.segmentSize >= r.config.MaxSize
+}
+
+type ivfContainer struct {
+	file      *os.File
+	clockRate uint32
+	frames    uint32
+}
+
+func newIVFContainer(fourcc string) containerFactory {
+	return func(path string, codec webrtc.RTPCodecParameters) (containerWriter, error) {
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+
+		header := make([]byte, 32)
+		copy(header[0:], "DKIF")
+		binary.LittleEndian.PutUint16(header[6:], 32) // header size
+		copy(header[8:], fourcc)
+		// width and height (12:16) are left 0; decoders take them from the bitstream
+		binary.LittleEndian.PutUint32(header[16:], codec.ClockRate) // time base denominator
+		binary.LittleEndian.PutUint32(header[20:], 1)               // time base numerator
+		// the frame count (24:28) is written on close
+
+		if _, err := file.Write(header); err != nil {
+			return nil, multierr.Append(err, file.Close())
+		}
+
+		return &ivfContainer{file: file, clockRate: codec.ClockRate}, nil
+	}
+}
+
+func (c *ivfContainer) writeFrame(frame *Frame, pts time.Duration) (int, error) {
+	header := make([]byte, 12)
+	binary.LittleEndian.PutUint32(header[0:], uint32(len(frame.Data)))
+	binary.LittleEndian.PutUint64(header[4:], uint64(pts.Seconds()*float64(c.clockRate)))
+
+	if _, err := c.file.Write(header); err != nil {
+		return 0, err
+	}
+	n, err := c.file.Write(frame.Data)
+	c.frames++
+
+	return len(header) + n, err
+}
+
+func (c *ivfContainer) close() error {
+	count := make([]byte, 4)
+	binary.LittleEndian.PutUint32(count, c.frames)
+
+	_, err := c.file.WriteAt(count, 24)
+	return multierr.Append(err, c.file.Close())
+}
+
+type annexBContainer struct {
+	file *os.File
+}
+
+func newAnnexBContainer(path string, _ webrtc.RTPCodecParameters) (containerWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &annexBContainer{file: file}, nil
+}
+
+// writeFrame writes the access unit as is; the H.264 depacketizer already emits Annex-B with start
+// codes. A raw byte stream has no timestamps.
+func (c *annexBContainer) writeFrame(frame *Frame, _ time.Duration) (int, error) {
+	return c.file.Write(frame.Data)
+}
+
+func (c *annexBContainer) close() error {
+	return c.file.Close()
+}
+
+// countingWriter counts the bytes written through it, for writers that do not report them.
+type countingWriter struct {
+	writer io.Writer
+	n      int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.n += n
+	return n, err
+}
+
+type oggContainer struct {
+	file    *os.File
+	counter *countingWriter
+	writer  *oggwriter.OggWriter
+	seq     uint16
+}
+
+func newOggContainer(path string, codec webrtc.RTPCodecParameters) (containerWriter, error) {
+	channels := codec.Channels
+	if channels == 0 {
+		channels = 1
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := &countingWriter{writer: file}
+	writer, err := oggwriter.NewWith(counter, codec.ClockRate, channels)
+	if err != nil {
+		return nil, multierr.Append(err, file.Close())
+	}
+
+	return &oggContainer{file: file, counter: counter, writer: writer}, nil
+}
+
+// writeFrame hands the Opus packet to pion's Ogg writer, which derives the granule positions from the
+// RTP timestamps. The writer does not report what it wrote, so the pages are counted on their way to
+// the file.
+func (c *oggContainer) writeFrame(frame *Frame, _ time.Duration) (int, error) {
+	c.seq++
+	packet := &rtp.Packet{
+		Header:  rtp.Header{Version: 2, SequenceNumber: c.seq, Timestamp: frame.Timestamp},
+		Payload: frame.Data,
+	}
+
+	before := c.counter.n
+	err := c.writer.WriteRTP(packet)
+
+	return c.counter.n - before, err
+}
+
+// close closes the file, which the Ogg writer leaves open as the counter is not an io.Closer.
+func (c *oggContainer) close() error {
+	// NOTE: ON A STREAM, PION DOES NOT GO BACK TO FLAG THE LAST PAGE AS THE END OF THE STREAM; PLAYERS TAKE
+	// NOTE: THE END OF THE FILE FOR IT
+	return multierr.Append(c.writer.Close(), c.file.Close())
+}
+
+// Record starts recording the sink with the given label. The recording is finalised by Close, or by
+// Sinks.Close.
+func (s *Sinks) Record(label string, config RecorderConfig) (*Recorder, error) {
+	sink, err := s.GetSink(label)
+	if err != nil {
+		return nil, err
+	}
+
+	recorder, err := NewRecorder(s.ctx, sink, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record sink (id=%s); err: %w", label, err)
+	}
+
+	s.mux.Lock()
+	s.outputs = append(s.outputs, recorder)
+	s.mux.Unlock()
+
+	recorder.Start()
+	return recorder, nil
+}
+
+// AddOutput makes Close close output too, e.g. a muxer of package transcode reading some of the sinks.
+func (s *Sinks) AddOutput(output io.Closer) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.outputs = append(s.outputs, output)
+}
+
+// Close finalises every recording started with Record, stops every Forward and closes the outputs added
+// with AddOutput.
+func (s *Sinks) Close() error {
+	s.mux.Lock()
+	outputs := s.outputs
+	s.outputs = nil
+	s.mux.Unlock()
+
+	var merr error
+	for _, output := range outputs {
+		if err := output.Close(); err != nil {
+			merr = multierr.Append(merr, err)
+		}
+	}
+
+	return merr
+}
@@ -0,0 +1,53 @@
+package mediasink_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+)
+
+// assertClosesPromptly fails the test if closing output takes longer than a second.
+func assertClosesPromptly(t *testing.T, output io.Closer) {
+	t.Helper()
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- output.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close is blocked while the track is idle")
+	}
+}
+
+func TestRecorderClosesOnIdleTrack(t *testing.T) {
+	sink := connectedSink(t)
+	path := filepath.Join(t.TempDir(), "idle.ivf")
+
+	recorder, err := mediasink.NewRecorder(context.Background(), sink, mediasink.RecorderConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Start()
+
+	// NOTE: THE SOURCE STOPPED SENDING AFTER THE FIRST PACKETS, SO THE RECORDER IS WAITING FOR MEDIA
+	time.Sleep(200 * time.Millisecond)
+
+	assertClosesPromptly(t, recorder)
+
+	for _, file := range recorder.Files() {
+		if _, err := os.Stat(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package mediasink
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// TrackInfo is what a remote track can be routed to a sink by.
+type TrackInfo struct {
+	Track    *webrtc.TrackRemote
+	Receiver *webrtc.RTPReceiver
+	ID       string
+	StreamID string
+	MID      string
+	RID      string
+}
+
+// RoutePredicate reports whether a remote track is meant for a sink.
+type RoutePredicate = func(TrackInfo) bool
+
+// AutoSinkFactory is called for remote tracks no sink is registered for. It returns the label and the
+// options of the sink to create for the track, or an empty label to ignore the track. Without a track
+// option among options, the sink takes the codec of the remote track.
+type AutoSinkFactory = func(TrackInfo) (label string, options []SinkOption)
+
+// AutoSinkByTrackID is an AutoSinkFactory accepting every track, labelling its sink with the track ID.
+func AutoSinkByTrackID(info TrackInfo) (string, []SinkOption) {
+	return info.ID, nil
+}
+
+// WithTrackID routes the remote track with the given track ID to the sink. Without any route option a
+// sink receives the track whose ID is the sink's label.
+func WithTrackID(id string) SinkOption {
+	return WithRoute(func(info TrackInfo) bool { return info.ID == id })
+}
+
+// WithStreamID routes remote tracks of the given stream to the sink.
+func WithStreamID(id string) SinkOption {
+	return WithRoute(func(info TrackInfo) bool { return info.StreamID == id })
+}
+
+// WithMID routes the remote track of the transceiver with the given MID to the sink.
+func WithMID(mid string) SinkOption {
+	return WithRoute(func(info TrackInfo) bool { return info.MID == mid })
+}
+
+// WithRID routes the simulcast layer with the given RID to the sink.
+func WithRID(rid string) SinkOption {
+	return WithRoute(func(info TrackInfo) bool { return info.RID == rid })
+}
+
+// WithRoute routes remote tracks for which predicate holds to the sink. Route options add up; a track
+// has to satisfy all of them. A sink receives one track at a time: once attached, further matching
+// tracks go to the next matching sink, in the order of the sink labels. Sinks of the other kind, or
+// whose CodecMatcher rejects the track, are skipped, so an audio and a video sink can share a stream ID.
+func WithRoute(predicate RoutePredicate) SinkOption {
+	return func(sink *Sink) error {
+		if predicate == nil {
+			return errors.New("route predicate cannot be nil")
+		}
+		sink.routes = append(sink.routes, predicate)
+		return nil
+	}
+}
+
+// withDefaultCodec sets the codec of the sink, unless a track option already did.
+func withDefaultCodec(codec webrtc.RTPCodecParameters) SinkOption {
+	return func(sink *Sink) error {
+		if sink.codecCapability == nil {
+			sink.codecCapability = &codec
+		}
+		return nil
+	}
+}
+
+func (s *Sink) matches(info TrackInfo) bool {
+	for _, route := range s.routes {
+		if !route(info) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Sink) attached() bool {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.generator != nil
+}
+
+func trackInfo(pc *webrtc.PeerConnection, remote *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) TrackInfo {
+	info := TrackInfo{
+		Track:    remote,
+		Receiver: receiver,
+		ID:       remote.ID(),
+		StreamID: remote.StreamID(),
+		RID:      remote.RID(),
+	}
+
+	for _, transceiver := range pc.GetTransceivers() {
+		if transceiver.Receiver() == receiver {
+			info.MID = transceiver.Mid()
+			break
+		}
+	}
+
+	return info
+}
+
+// accept attaches the remote track to the sink if its CodecMatcher accepts the codec of the track.
+func (s *Sink) accept(info TrackInfo, writer rtcpWriter) error {
+	if err := s.matcher(info.Track.Codec(), *s.codecCapability); err != nil {
+		return err
+	}
+
+	s.setMismatch(nil)
+	s.setGenerator(info.Track, info.Receiver, writer)
+
+	return nil
+}
+
+// route attaches a remote track to its sink. The candidates are the sink labelled with the track ID, if
+// it has no route options, otherwise the sinks, in label order, of the kind of the track whose routes
+// match and which are not receiving yet; the first whose CodecMatcher accepts the track gets it. The
+// sink is picked and attached under the lock of the sinks, so that tracks arriving at once cannot claim
+// the same sink. If every candidate rejected the codec, the first of them is returned with its mismatch.
+func (s *Sinks) route(info TrackInfo, writer rtcpWriter) (string, *Sink, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	var (
+		mismatchLabel string
+		mismatchSink  *Sink
+		mismatch      error
+	)
+
+	for _, label := range s.candidates(info) {
+		sink := s.sinks[label]
+
+		err := sink.accept(info, writer)
+		if err == nil {
+			return label, sink, nil
+		}
+
+		if mismatch == nil {
+			mismatchLabel, mismatchSink, mismatch = label, sink, err
+		}
+	}
+
+	return mismatchLabel, mismatchSink, mismatch
+}
+
+// candidates lists the labels of the sinks a remote track can go to, in the order they are tried. The
+// caller holds s.mux.
+func (s *Sinks) candidates(info TrackInfo) []string {
+	if sink, exists := s.sinks[info.ID]; exists && len(sink.routes) == 0 {
+		return []string{info.ID}
+	}
+
+	labels := make([]string, 0, len(s.sinks))
+	for label, sink := range s.sinks {
+		if len(sink.routes) > 0 && sink.Kind() == info.Track.Kind() && !sink.attached() && sink.matches(info) {
+			labels = append(labels, label)
+		}
+	}
+	slices.Sort(labels)
+
+	return labels
+}
+
+// SetAutoSinkFactory sets the factory sinks are created with for remote tracks that no sink is registered
+// for. Without a factory such tracks are ignored.
+func (s *Sinks) SetAutoSinkFactory(factory AutoSinkFactory) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.autoSink = factory
+}
+
+// routeToAutoSink creates a sink with the AutoSinkFactory and attaches the remote track to it. It returns
+// no sink if there is no factory, or it declined the track.
+func (s *Sinks) routeToAutoSink(info TrackInfo, writer rtcpWriter) (string, *Sink, error) {
+	s.mux.RLock()
+	factory := s.autoSink
+	s.mux.RUnlock()
+
+	if factory == nil {
+		return "", nil, nil
+	}
+
+	label, options := factory(info)
+	if label == "" {
+		return "", nil, nil
+	}
+
+	// NOTE: CreateSink FAILS FOR A LABEL THAT EXISTS, SO A TRACK ARRIVING AT THE SAME TIME CANNOT TAKE THE NEW SINK
+	sink, err := s.CreateSink(label, append(options, withDefaultCodec(info.Track.Codec()))...)
+	if err != nil {
+		s.getLogger().Warn("failed to create sink for remote track; ignoring track", "track", info.ID, "sink", label, "err", err)
+		return "", nil, nil
+	}
+
+	s.getLogger().Info("created sink for remote track", "track", info.ID, "sink", label)
+	return label, sink, sink.accept(info, writer)
+}
@@ -0,0 +1,162 @@
+package mediasink_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4/pkg/media"
+
+	"github.com/harshabose/simple_webrtc_comm/client"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/clienttest"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+// every media source of the package uses this stream ID
+const sourceStreamID = "webrtc"
+
+// writeSamples writes samples to every source till the test ends, so that every remote track arrives
+// whichever sink it is routed to.
+func writeSamples(t *testing.T, sources ...*mediasource.Track) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	writeSamplesUntil(ctx, sources...)
+}
+
+// writeSamplesUntil writes samples to every source till ctx is done.
+func writeSamplesUntil(ctx context.Context, sources ...*mediasource.Track) {
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, source := range sources {
+					_ = source.WriteSample(media.Sample{Data: []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a}, Duration: 20 * time.Millisecond})
+				}
+			}
+		}
+	}()
+}
+
+func assertSinkReceives(t *testing.T, label string, sink *mediasink.Sink) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), clienttest.DefaultTimeout)
+	defer cancel()
+
+	if _, _, err := sink.ReadRTP(ctx); err != nil {
+		t.Fatalf("no media reached sink '%s': %v", label, err)
+	}
+}
+
+func createSource(t *testing.T, pc *client.PeerConnection, label string, options ...mediasource.TrackOption) *mediasource.Track {
+	t.Helper()
+
+	source, err := pc.CreateMediaSource(label, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return source
+}
+
+func createSink(t *testing.T, pc *client.PeerConnection, label string, options ...mediasink.SinkOption) *mediasink.Sink {
+	t.Helper()
+
+	sink, err := pc.CreateMediaSink(label, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sink
+}
+
+func TestRouteAudioAndVideoOfOneStream(t *testing.T) {
+	pair := clienttest.NewPair(t, client.WithDefaultMediaEngine())
+	offer, answer := pair.PeerConnections(t, "media")
+
+	mic := createSource(t, offer, "mic", mediasource.WithOpusTrack(48000, 2))
+	cam := createSource(t, offer, "cam", mediasource.WithVP8Track(90000))
+
+	// NOTE: THE VIDEO TRACK MATCHES THE ROUTES OF ALL THREE SINKS, BUT ONLY THE LAST HAS ITS KIND AND CODEC
+	audio := createSink(t, answer, "a", mediasink.WithOpusTrack(48000, 2), mediasink.WithStreamID(sourceStreamID))
+	h264 := createSink(t, answer, "b", mediasink.WithH264Track(90000), mediasink.WithStreamID(sourceStreamID))
+	video := createSink(t, answer, "c", mediasink.WithVP8Track(90000), mediasink.WithStreamID(sourceStreamID))
+
+	pair.Connect(t)
+	writeSamples(t, mic, cam)
+
+	assertSinkReceives(t, "a", audio)
+	assertSinkReceives(t, "c", video)
+
+	if err := h264.MismatchError(); err != nil {
+		t.Fatalf("expected the skipped sink to keep no mismatch, got %v", err)
+	}
+}
+
+func TestRouteConcurrentTracksToDistinctSinks(t *testing.T) {
+	pair := clienttest.NewPair(t, client.WithDefaultMediaEngine())
+	offer, answer := pair.PeerConnections(t, "media")
+
+	first := createSource(t, offer, "cam-1", mediasource.WithVP8Track(90000))
+	second := createSource(t, offer, "cam-2", mediasource.WithVP8Track(90000))
+
+	x := createSink(t, answer, "x", mediasink.WithVP8Track(90000), mediasink.WithStreamID(sourceStreamID))
+	y := createSink(t, answer, "y", mediasink.WithVP8Track(90000), mediasink.WithStreamID(sourceStreamID))
+
+	pair.Connect(t)
+
+	// NOTE: BOTH TRACKS START AT ONCE; IF BOTH CLAIMED ONE SINK, THE OTHER WOULD NEVER RECEIVE
+	writeSamples(t, first, second)
+
+	assertSinkReceives(t, "x", x)
+	assertSinkReceives(t, "y", y)
+}
+
+func TestRouteFallsBackToAutoSink(t *testing.T) {
+	pair := clienttest.NewPair(t, client.WithDefaultMediaEngine())
+	offer, answer := pair.PeerConnections(t, "media")
+
+	cam := createSource(t, offer, "cam", mediasource.WithVP8Track(90000))
+
+	// NOTE: THE ONLY REGISTERED SINK OF THE STREAM TAKES H264, SO THE VP8 TRACK GOES TO AN AUTO SINK
+	h264 := createSink(t, answer, "a", mediasink.WithH264Track(90000), mediasink.WithStreamID(sourceStreamID))
+	if err := answer.SetAutoSinkFactory(mediasink.AutoSinkByTrackID); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clienttest.DefaultTimeout)
+	defer cancel()
+	events := answer.Events(ctx)
+
+	pair.Connect(t)
+	writeSamples(t, cam)
+
+	for event := range events {
+		if event.Type != client.EventTrack {
+			continue
+		}
+		if event.Sink != "cam" {
+			t.Fatalf("expected the track to go to auto sink 'cam', got '%s'", event.Sink)
+		}
+		break
+	}
+
+	sink, err := answer.GetMediaSink("cam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSinkReceives(t, "cam", sink)
+
+	if err := h264.MismatchError(); err != nil {
+		t.Fatalf("expected the skipped sink to keep no mismatch, got %v", err)
+	}
+}
@@ -4,28 +4,61 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"iter"
+	"log/slog"
+	"maps"
 	"reflect"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pion/interceptor"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
 
 	"github.com/harshabose/tools/pkg/cond"
+
+	"github.com/harshabose/simple_webrtc_comm/client/internal/logger"
 )
 
 type Sink struct {
 	generator       *webrtc.TrackRemote
+	reader          *remoteReader
 	codecCapability *webrtc.RTPCodecParameters
 	rtpReceiver     *webrtc.RTPReceiver
-	mux             sync.RWMutex
-	cond            *cond.ContextCond
-	ctx             context.Context
+	matcher         CodecMatcher
+	mismatch        error
+	routes          []RoutePredicate
+	frames          *frameAssembler
+	frameMaxLate    uint16
+	frameMaxDelay   time.Duration
+	frameMux        sync.Mutex
+	jitter          *jitterBuffer
+	rtcpWriter      rtcpWriter
+	autoKeyframe    bool
+	keyframeMux     sync.Mutex
+	// stopReceiver ends the loop reading the RTCP of rtpReceiver
+	stopReceiver context.CancelFunc
+	// keyframeInterval, lastKeyframeRequest and firSequence rate limit and number keyframe requests
+	keyframeInterval    time.Duration
+	lastKeyframeRequest time.Time
+	firSequence         uint8
+	logger              *slog.Logger
+	mux                 sync.RWMutex
+	cond                *cond.ContextCond
+	ctx                 context.Context
 }
 
 func CreateSink(ctx context.Context, options ...SinkOption) (*Sink, error) {
-	sink := &Sink{ctx: ctx}
+	sink := &Sink{
+		ctx:              ctx,
+		matcher:          DefaultCodecMatcher,
+		frameMaxLate:     DefaultFrameMaxLate,
+		frameMaxDelay:    DefaultFrameMaxDelay,
+		keyframeInterval: DefaultKeyframeRequestInterval,
+		logger:           packageLogger.Get(),
+	}
 	sink.cond = cond.NewContextCond(&(sink.mux))
 
 	for _, option := range options {
@@ -41,102 +74,393 @@ func CreateSink(ctx context.Context, options ...SinkOption) (*Sink, error) {
 	return sink, nil
 }
 
-func (s *Sink) setGenerator(generator *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
+// Kind returns whether the sink receives audio or video, based on its codec.
+func (s *Sink) Kind() webrtc.RTPCodecType {
+	if strings.HasPrefix(strings.ToLower(s.codecCapability.MimeType), "audio/") {
+		return webrtc.RTPCodecTypeAudio
+	}
+
+	return webrtc.RTPCodecTypeVideo
+}
+
+// MismatchError returns why the last remote track meant for this sink was rejected by its CodecMatcher,
+// or nil if it was accepted or no track arrived yet.
+func (s *Sink) MismatchError() error {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.mismatch
+}
+
+// Codec returns the codec of the remote track the sink receives from, with the payload type that was
+// actually negotiated. Before a track is attached, it returns the codec the sink was created with.
+func (s *Sink) Codec() webrtc.RTPCodecParameters {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	if s.generator != nil {
+		return s.generator.Codec()
+	}
+
+	return *s.codecCapability
+}
+
+func (s *Sink) setMismatch(err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.mismatch = err
+}
+
+func (s *Sink) setGenerator(generator *webrtc.TrackRemote, receiver *webrtc.RTPReceiver, writer rtcpWriter) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 
 	s.generator = generator
-	s.rtpReceiver = receiver
+	s.rtcpWriter = writer
+	s.swapReceiver(receiver)
+	s.swapReader(generator)
 
 	s.cond.Broadcast()
 }
 
-func (s *Sink) readRTPReceiver(ctx context.Context, rtcpBuf []byte) error {
-	s.mux.Lock()
+// swapReader stops handing out packets of the previous remote track and starts reading the new one, if
+// any. The caller holds s.mux.
+func (s *Sink) swapReader(generator *webrtc.TrackRemote) {
+	if s.reader != nil {
+		s.reader.stop()
+		s.reader = nil
+	}
 
-	for s.rtpReceiver == nil {
-		if err := s.cond.Wait(ctx); err != nil {
-			s.mux.Unlock()
-			return err
+	if generator != nil {
+		s.reader = newRemoteReader(generator)
+		go s.reader.loop(s.ctx)
+		if s.jitter != nil {
+			go s.jitterLoop(s.reader)
 		}
 	}
-	reader := s.rtpReceiver
+}
+
+// swapReceiver starts reading the RTCP of receiver, unless it is the receiver already read, and ends the
+// loop of the previous one. The caller holds s.mux.
+func (s *Sink) swapReceiver(receiver *webrtc.RTPReceiver) {
+	if receiver == s.rtpReceiver {
+		return
+	}
+
+	if s.stopReceiver != nil {
+		s.stopReceiver()
+		s.stopReceiver = nil
+	}
+
+	s.rtpReceiver = receiver
+	if receiver != nil {
+		ctx, cancel := context.WithCancel(s.ctx)
+		s.stopReceiver = cancel
+		go s.rtpReceiverLoop(ctx, receiver)
+	}
+}
 
+// detach stops the transceiver the sink currently receives from, if any.
+func (s *Sink) detach(pc *webrtc.PeerConnection) error {
+	s.mux.Lock()
+	receiver := s.rtpReceiver
+	s.generator = nil
+	s.swapReceiver(nil)
+	s.swapReader(nil)
 	s.mux.Unlock()
 
-	if _, _, err := reader.Read(rtcpBuf); err != nil {
-		fmt.Printf("error while reading rtcp packets (err=%v)\n", err)
-		return err
+	if receiver == nil {
+		return nil
 	}
+
+	for _, transceiver := range pc.GetTransceivers() {
+		if transceiver.Receiver() != receiver {
+			continue
+		}
+		// NOTE: THE REMOTE TRACK MAY SHARE ITS TRANSCEIVER WITH A LOCAL TRACK SENT THE OTHER WAY; STOPPING IT
+		// WOULD STOP THAT TRACK AS WELL, SO IT IS ONLY STOPPED ONCE IT SENDS NOTHING
+		if sender := transceiver.Sender(); sender != nil && sender.Track() != nil {
+			return nil
+		}
+		return transceiver.Stop()
+	}
+
 	return nil
 }
 
-func (s *Sink) rtpReceiverLoop() {
+// rtpReceiverLoop reads the RTCP of receiver till ctx is done, which is once the sink moves on to another
+// receiver. Read cannot be cancelled; it returns once the transceiver is stopped or the peer connection
+// is closed.
+func (s *Sink) rtpReceiverLoop(ctx context.Context, receiver *webrtc.RTPReceiver) {
 	// THIS IS NEEDED AS interceptors (pion) do not work
+	rtcpBuf := make([]byte, 1500)
 	for {
 		select {
-		case <-s.ctx.Done():
+		case <-ctx.Done():
 			return
 		default:
-			rtcpBuf := make([]byte, 1500)
-			if err := s.readRTPReceiver(s.ctx, rtcpBuf); err != nil {
+			if _, _, err := receiver.Read(rtcpBuf); err != nil {
+				s.logger.Debug("stopped reading rtcp packets", "err", err)
 				return
 			}
 		}
 	}
 }
 
-func (s *Sink) ReadRTP(ctx context.Context) (*rtp.Packet, interceptor.Attributes, error) {
+// waitReader returns the reader of the remote track the sink receives from, waiting for one if needed.
+func (s *Sink) waitReader(ctx context.Context) (*remoteReader, error) {
 	s.cond.L.Lock()
+	defer s.cond.L.Unlock()
 
-	for s.generator == nil {
+	for s.reader == nil {
 		if err := s.cond.Wait(ctx); err != nil {
-			s.cond.L.Unlock()
+			return nil, err
+		}
+	}
+
+	return s.reader, nil
+}
+
+// replaced reports whether the sink has moved on from the remote track reader reads, e.g. as the track
+// ended and a renegotiation attached a new one; errors of reader do not matter to readers then.
+func (s *Sink) replaced(reader *remoteReader) bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.reader != reader
+}
+
+// ReadRTP reads the next RTP packet of the remote track, waiting for one to be attached if needed. With
+// WithJitterBuffer the packets come out of the jitter buffer, without attributes. It returns as soon as
+// ctx is done, even if the remote sends nothing. Once the remote track ended, the error it ended with,
+// e.g. io.EOF, is returned till another track is attached.
+func (s *Sink) ReadRTP(ctx context.Context) (*rtp.Packet, interceptor.Attributes, error) {
+	for {
+		reader, err := s.waitReader(ctx)
+		if err != nil {
 			return nil, nil, err
 		}
+
+		packet, attributes, err := s.readPacket(ctx, reader)
+		if err != nil && s.replaced(reader) {
+			continue
+		}
+
+		return packet, attributes, err
 	}
-	reader := s.generator
+}
 
-	s.cond.L.Unlock()
+func (s *Sink) readPacket(ctx context.Context, reader *remoteReader) (*rtp.Packet, interceptor.Attributes, error) {
+	if s.jitter != nil {
+		packet, err := s.jitter.pop(ctx)
+		return packet, nil, err
+	}
 
-	return reader.ReadRTP()
+	return reader.read(ctx)
+}
+
+// errTrackReplaced is returned by the reader of a remote track that was detached from the sink; readers
+// of the sink move on to the next track.
+var errTrackReplaced = errors.New("remote track was replaced")
+
+type remotePacket struct {
+	packet     *rtp.Packet
+	attributes interceptor.Attributes
+}
+
+// remoteReader reads a remote track in a goroutine of its own, as TrackRemote.ReadRTP cannot be
+// cancelled, so that readers of the sink can give up when their context is done. It holds at most one
+// packet nobody asked for yet.
+type remoteReader struct {
+	generator *webrtc.TrackRemote
+	packets   chan remotePacket
+	// err is why reading stopped; it is set before packets is closed
+	err     error
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newRemoteReader(generator *webrtc.TrackRemote) *remoteReader {
+	return &remoteReader{
+		generator: generator,
+		packets:   make(chan remotePacket),
+		stopped:   make(chan struct{}),
+	}
 }
 
+func (r *remoteReader) loop(ctx context.Context) {
+	defer close(r.packets)
+
+	for {
+		packet, attributes, err := r.generator.ReadRTP()
+		if err != nil {
+			r.err = err
+			return
+		}
+
+		select {
+		case r.packets <- remotePacket{packet: packet, attributes: attributes}:
+		case <-r.stopped:
+			r.err = errTrackReplaced
+			return
+		case <-ctx.Done():
+			r.err = ctx.Err()
+			return
+		}
+	}
+}
+
+// stop makes the reader drop what it reads from now on. The goroutine itself ends once ReadRTP returns,
+// which happens when the transceiver of the track is stopped or the peer connection is closed.
+func (r *remoteReader) stop() {
+	r.once.Do(func() {
+		close(r.stopped)
+	})
+}
+
+func (r *remoteReader) read(ctx context.Context) (*rtp.Packet, interceptor.Attributes, error) {
+	select {
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	case <-r.stopped:
+		return nil, nil, errTrackReplaced
+	case read, ok := <-r.packets:
+		if !ok {
+			return nil, nil, r.err
+		}
+		return read.packet, read.attributes, nil
+	}
+}
+
+// TrackEvent describes a remote track arriving at the sinks. Sink is the label of the sink the track is
+// meant for and Codec the codec that sink expects; both are empty when there is no such sink. Matched is
+// set when the track was attached to the sink, otherwise Err tells why the codecs did not match.
+type TrackEvent struct {
+	Track   *webrtc.TrackRemote
+	Sink    string
+	Codec   *webrtc.RTPCodecParameters
+	Matched bool
+	Err     error
+}
+
+// TrackHandler is called for every remote track, whether it was attached to a sink or not.
+type TrackHandler = func(TrackEvent)
+
 type Sinks struct {
-	sinks map[string]*Sink
-	mux   sync.RWMutex
-	ctx   context.Context
+	sinks    map[string]*Sink
+	logger   *slog.Logger
+	onTrack  TrackHandler
+	autoSink AutoSinkFactory
+	// outputs are the recordings and forwards started with Record and Forward, and those added with
+	// AddOutput; Close stops them
+	outputs []io.Closer
+	mux     sync.RWMutex
+	ctx     context.Context
+}
+
+// packageLogger is what sinks log with till WithLogger or Sinks.SetLogger says otherwise.
+var packageLogger logger.Package
+
+// SetLogger sets the logger of sinks, and of the deprecated codec comparison, that were given none; nil
+// restores slog.Default().
+func SetLogger(l *slog.Logger) {
+	packageLogger.Set(l)
 }
 
 func CreateSinks(ctx context.Context, pc *webrtc.PeerConnection) *Sinks {
 	s := &Sinks{
-		sinks: make(map[string]*Sink),
-		ctx:   ctx,
+		sinks:  make(map[string]*Sink),
+		logger: packageLogger.Get(),
+		ctx:    ctx,
 	}
 
-	s.onTrack(pc)
+	s.registerOnTrack(pc)
 	return s
 }
 
-func (s *Sinks) onTrack(pc *webrtc.PeerConnection) {
+// SetLogger sets the logger the sinks created from now on, and the handling of incoming tracks, log
+// with. Options given to CreateSink still take precedence.
+func (s *Sinks) SetLogger(logger *slog.Logger) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.logger = logger
+}
+
+// SetTrackHandler sets the handler every remote track is reported to.
+func (s *Sinks) SetTrackHandler(handler TrackHandler) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.onTrack = handler
+}
+
+func (s *Sinks) getLogger() *slog.Logger {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.logger
+}
+
+func (s *Sinks) notify(event TrackEvent) {
+	s.mux.RLock()
+	handler := s.onTrack
+	s.mux.RUnlock()
+
+	if handler != nil {
+		handler(event)
+	}
+}
+
+func (s *Sinks) registerOnTrack(pc *webrtc.PeerConnection) {
 	pc.OnTrack(func(remote *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
-		sink, err := s.GetSink(remote.ID())
-		if err != nil {
-			fmt.Printf("failed to trigger on track callback with err: %v\n", err)
+		info := trackInfo(pc, remote, receiver)
+
+		label, sink, err := s.route(info, pc.WriteRTCP)
+		if sink == nil || err != nil {
+			// NOTE: A TRACK NO REGISTERED SINK ACCEPTS FALLS BACK TO THE AUTO SINK, IF ANY
+			if autoLabel, autoSink, autoErr := s.routeToAutoSink(info, pc.WriteRTCP); autoSink != nil {
+				label, sink, err = autoLabel, autoSink, autoErr
+			}
+		}
+		if sink == nil {
+			s.getLogger().Warn("no sink for remote track; ignoring track", "track", info.ID, "stream", info.StreamID, "mid", info.MID, "rid", info.RID)
+			s.notify(TrackEvent{Track: remote})
 			return
 		}
 
-		if !CompareRTPCodecParameters(remote.Codec(), *(sink.codecCapability)) {
-			fmt.Println("sink registered codec did not match. skipping...")
+		event := TrackEvent{Track: remote, Sink: label, Codec: sink.codecCapability}
+
+		if err != nil {
+			sink.logger.Warn("codec of remote track does not match the sink; ignoring track", "err", err)
+			sink.setMismatch(err)
+			event.Err = err
+			s.notify(event)
 			return
 		}
 
-		sink.setGenerator(remote, receiver)
+		// NOTE: A DECODER CANNOT START BEFORE THE NEXT KEYFRAME, WHICH MAY BE A WHOLE GOP AWAY
+		sink.autoRequestKeyframe("track attached")
 
-		go sink.rtpReceiverLoop()
+		event.Matched = true
+		s.notify(event)
 	})
 }
 
+// Rebind registers the OnTrack handler on another peer connection and detaches every sink from its
+// previous remote track. Sinks get attached again as soon as the remote tracks arrive on the new
+// peer connection; readers blocked in ReadRTP simply wait for that.
+func (s *Sinks) Rebind(pc *webrtc.PeerConnection) {
+	s.mux.RLock()
+	for _, sink := range s.sinks {
+		sink.setGenerator(nil, nil, nil)
+	}
+	s.mux.RUnlock()
+
+	s.registerOnTrack(pc)
+}
+
 func (s *Sinks) CreateSink(label string, options ...SinkOption) (*Sink, error) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
@@ -145,30 +469,56 @@ func (s *Sinks) CreateSink(label string, options ...SinkOption) (*Sink, error) {
 		return nil, fmt.Errorf("sink with id='%s' already exists", label)
 	}
 
-	sink, err := CreateSink(s.ctx, options...)
+	sink, err := CreateSink(s.ctx, append([]SinkOption{WithLogger(s.logger)}, options...)...)
 	if err != nil {
 		return nil, err
 	}
+	sink.logger = sink.logger.With("sink", label)
 
 	s.sinks[label] = sink
 	return sink, nil
 }
 
+// RemoveSink forgets the sink and stops the transceiver it receives from. Readers blocked in ReadRTP
+// keep waiting till their context is done.
+func (s *Sinks) RemoveSink(label string, pc *webrtc.PeerConnection) error {
+	s.mux.Lock()
+	sink, exists := s.sinks[label]
+	if !exists {
+		s.mux.Unlock()
+		return fmt.Errorf("sink with id='%s' does not exist", label)
+	}
+	delete(s.sinks, label)
+	s.mux.Unlock()
+
+	if err := sink.detach(pc); err != nil {
+		return fmt.Errorf("failed to remove sink (id=%s); err: %w", label, err)
+	}
+
+	return nil
+}
+
 func (s *Sinks) GetSink(label string) (*Sink, error) {
 	s.mux.RLock()
 	defer s.mux.RUnlock()
 
 	sink, exists := s.sinks[label]
 	if !exists {
-		return nil, fmt.Errorf("ERROR: no sink set for track with id %s; ignoring track...\n", label)
+		return nil, fmt.Errorf("no sink set for track with id %s", label)
 	}
 
 	return sink, nil
 }
 
+// Sinks yields the sinks as they were when iteration started; sinks are added from OnTrack and removed
+// at any time, so the map is copied under the lock instead of being held during yield.
 func (s *Sinks) Sinks() iter.Seq2[string, *Sink] {
 	return func(yield func(string, *Sink) bool) {
-		for id, sink := range s.sinks {
+		s.mux.RLock()
+		sinks := maps.Clone(s.sinks)
+		s.mux.RUnlock()
+
+		for id, sink := range sinks {
 			if !yield(id, sink) {
 				return
 			}
@@ -176,35 +526,40 @@ func (s *Sinks) Sinks() iter.Seq2[string, *Sink] {
 	}
 }
 
+// CompareRTPCodecParameters reports whether a and b have the same payload type, MIME type, clock rate and
+// channels.
+//
+// Deprecated: sinks match codecs with their CodecMatcher, see DefaultCodecMatcher and WithCodecMatcher.
 func CompareRTPCodecParameters(a, b webrtc.RTPCodecParameters) bool {
 	identical := true
+	logger := packageLogger.Get()
 
 	if a.PayloadType != b.PayloadType {
-		fmt.Printf("PayloadType differs: %v != %v\n", a.PayloadType, b.PayloadType)
+		logger.Debug("payload type differs", "a", a.PayloadType, "b", b.PayloadType)
 		identical = false
 	}
 
 	if a.MimeType != b.MimeType {
-		fmt.Printf("MimeType differs: %s != %s\n", a.MimeType, b.MimeType)
+		logger.Debug("mime type differs", "a", a.MimeType, "b", b.MimeType)
 		identical = false
 	}
 
 	if a.ClockRate != b.ClockRate {
-		fmt.Printf("ClockRate differs: %d != %d\n", a.ClockRate, b.ClockRate)
+		logger.Debug("clock rate differs", "a", a.ClockRate, "b", b.ClockRate)
 		identical = false
 	}
 
 	if a.Channels != b.Channels {
-		fmt.Printf("Channels differs: %d != %d\n", a.Channels, b.Channels)
+		logger.Debug("channels differ", "a", a.Channels, "b", b.Channels)
 		identical = false
 	}
 
 	if a.SDPFmtpLine != b.SDPFmtpLine {
-		fmt.Printf("SDPFmtpLine differs (ignored): %s != %s\n", a.SDPFmtpLine, b.SDPFmtpLine)
+		logger.Debug("fmtp line differs (ignored)", "a", a.SDPFmtpLine, "b", b.SDPFmtpLine)
 	}
 
 	if !reflect.DeepEqual(a.RTCPFeedback, b.RTCPFeedback) {
-		fmt.Printf("RTCPFeedback differs (ignored): %v != %v\n", a.RTCPFeedback, b.RTCPFeedback)
+		logger.Debug("rtcp feedback differs (ignored)", "a", a.RTCPFeedback, "b", b.RTCPFeedback)
 	}
 
 	return identical
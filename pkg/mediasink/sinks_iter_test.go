@@ -0,0 +1,50 @@
+package mediasink
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+func TestSinksIterateWhileSinksChange(t *testing.T) {
+	s := &Sinks{sinks: make(map[string]*Sink), logger: slog.New(slog.DiscardHandler), ctx: context.Background()}
+
+	if _, err := s.CreateSink("video", WithVP8Track(90000)); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	// NOTE: LIKE AUTO SINKS CREATED FROM ONTRACK AND SINKS REMOVED AT THE SAME TIME
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 200; i++ {
+			label := fmt.Sprintf("auto-%d", i)
+			if _, err := s.CreateSink(label, WithVP8Track(90000)); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := s.RemoveSink(label, nil); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		found := false
+		for label := range s.Sinks() {
+			if label == "video" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatal("expected the sink which was never removed to be yielded")
+		}
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,74 @@
+package mediasink_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/harshabose/simple_webrtc_comm/client"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/clienttest"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+// connectedSink returns a VP8 sink that received media from its source, which then stops sending.
+func connectedSink(t *testing.T, options ...mediasink.SinkOption) *mediasink.Sink {
+	t.Helper()
+
+	pair := clienttest.NewPair(t, client.WithDefaultMediaEngine())
+	offer, answer := pair.PeerConnections(t, "media")
+
+	source, err := offer.CreateMediaSource("video", mediasource.WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := answer.CreateMediaSink("video", append([]mediasink.SinkOption{mediasink.WithVP8Track(90000)}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pair.Connect(t)
+	clienttest.AssertMediaFlows(t, source, sink)
+
+	return sink
+}
+
+func TestReadReturnsWhenContextIsDoneOnIdleTrack(t *testing.T) {
+	sink := connectedSink(t)
+
+	// NOTE: DRAIN WHAT WAS SENT BEFORE THE SOURCE STOPPED
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		_, _, err := sink.ReadRTP(ctx)
+		cancel()
+		if err != nil {
+			break
+		}
+	}
+
+	reads := map[string]func(context.Context) error{
+		"ReadRTP": func(ctx context.Context) error {
+			_, _, err := sink.ReadRTP(ctx)
+			return err
+		},
+		"ReadFrame": func(ctx context.Context) error {
+			_, err := sink.ReadFrame(ctx)
+			return err
+		},
+	}
+
+	for name, read := range reads {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		start := time.Now()
+		err := read(ctx)
+		cancel()
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("%s: expected deadline exceeded, got %v", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("%s: returned %s after its context was done", name, elapsed)
+		}
+	}
+}
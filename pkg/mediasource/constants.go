@@ -1,5 +1,11 @@
 package mediasource
 
+import (
+	"fmt"
+
+	"github.com/pion/webrtc/v4"
+)
+
 type Priority uint8
 
 const (
@@ -10,3 +16,41 @@ const (
 	Level4 Priority = 4
 	Level5 Priority = 5
 )
+
+// NOTE: THE CLIENT REGISTERS ITS CODECS WITH THESE PAYLOAD TYPES AND FMTP LINES, AND THE SINKS AND SOURCES
+// NOTE: ASK FOR THEM; KEEPING THEM IN ONE PLACE STOPS THE PACKAGES FROM DRIFTING APART.
+const (
+	H264PayloadType           webrtc.PayloadType = 102
+	H264RTXPayloadType        webrtc.PayloadType = 103
+	VP8PayloadType            webrtc.PayloadType = 96
+	VP8RTXPayloadType         webrtc.PayloadType = 97
+	VP9Profile0PayloadType    webrtc.PayloadType = 98
+	VP9Profile0RTXPayloadType webrtc.PayloadType = 99
+	VP9Profile2PayloadType    webrtc.PayloadType = 100
+	VP9Profile2RTXPayloadType webrtc.PayloadType = 101
+	AV1PayloadType            webrtc.PayloadType = 45
+	AV1RTXPayloadType         webrtc.PayloadType = 46
+	H265PayloadType           webrtc.PayloadType = 116
+	H265RTXPayloadType        webrtc.PayloadType = 117
+	OpusPayloadType           webrtc.PayloadType = 111
+)
+
+const (
+	VP9FmtpProfile0 = "profile-id=0"
+	VP9FmtpProfile2 = "profile-id=2"
+	AV1Fmtp         = "level-idx=5;profile=0;tier=0"
+	H265Fmtp        = "level-id=93;profile-id=1;tier-flag=0;tx-mode=SRST"
+)
+
+// VP9Codec returns the payload type, RTX payload type and fmtp line of a VP9 profile, which has to be 0
+// or 2.
+func VP9Codec(profile uint8) (payloadType webrtc.PayloadType, rtxPayloadType webrtc.PayloadType, fmtpLine string, err error) {
+	switch profile {
+	case 0:
+		return VP9Profile0PayloadType, VP9Profile0RTXPayloadType, VP9FmtpProfile0, nil
+	case 2:
+		return VP9Profile2PayloadType, VP9Profile2RTXPayloadType, VP9FmtpProfile2, nil
+	default:
+		return 0, 0, "", fmt.Errorf("unsupported vp9 profile %d; only profile 0 and 2 are supported", profile)
+	}
+}
@@ -2,6 +2,7 @@ package mediasource
 
 import (
 	"errors"
+	"log/slog"
 
 	"github.com/pion/webrtc/v4"
 )
@@ -13,9 +14,22 @@ type CodecID string
 const (
 	H264CodecID CodecID = "h264"
 	VP8CodecID  CodecID = "vp8"
+	VP9CodecID  CodecID = "vp9"
+	AV1CodecID  CodecID = "av1"
+	H265CodecID CodecID = "h265"
 	OpusCodecID CodecID = "opus"
 )
 
+// WithLogger sets the logger of the media source; the track label is added to it.
+func WithLogger(logger *slog.Logger) TrackOption {
+	return func(track *track) error {
+		if logger != nil {
+			track.logger = logger
+		}
+		return nil
+	}
+}
+
 func WithH264Track(clockrate uint32) TrackOption {
 	return func(track *track) error {
 		if track.codecCapability != nil {
@@ -44,6 +58,56 @@ func WithVP8Track(clockrate uint32) TrackOption {
 	}
 }
 
+// WithVP9Track sets a VP9 track of the given profile, which has to be 0 or 2.
+func WithVP9Track(clockrate uint32, profile uint8) TrackOption {
+	return func(track *track) error {
+		if track.codecCapability != nil {
+			return errors.New("multiple tracks are not supported on single media source")
+		}
+		_, _, fmtpLine, err := VP9Codec(profile)
+		if err != nil {
+			return err
+		}
+		track.codecCapability = &webrtc.RTPCodecCapability{}
+		track.codecCapability.MimeType = webrtc.MimeTypeVP9
+		track.codecCapability.ClockRate = clockrate
+		track.codecCapability.Channels = 0
+		track.codecCapability.SDPFmtpLine = fmtpLine
+
+		return nil
+	}
+}
+
+func WithAV1Track(clockrate uint32) TrackOption {
+	return func(track *track) error {
+		if track.codecCapability != nil {
+			return errors.New("multiple tracks are not supported on single media source")
+		}
+		track.codecCapability = &webrtc.RTPCodecCapability{}
+		track.codecCapability.MimeType = webrtc.MimeTypeAV1
+		track.codecCapability.ClockRate = clockrate
+		track.codecCapability.Channels = 0
+		track.codecCapability.SDPFmtpLine = AV1Fmtp
+
+		return nil
+	}
+}
+
+func WithH265Track(clockrate uint32) TrackOption {
+	return func(track *track) error {
+		if track.codecCapability != nil {
+			return errors.New("multiple tracks are not supported on single media source")
+		}
+		track.codecCapability = &webrtc.RTPCodecCapability{}
+		track.codecCapability.MimeType = webrtc.MimeTypeH265
+		track.codecCapability.ClockRate = clockrate
+		track.codecCapability.Channels = 0
+		track.codecCapability.SDPFmtpLine = H265Fmtp
+
+		return nil
+	}
+}
+
 func WithOpusTrack(samplerate uint32, channelLayout uint16) TrackOption {
 	return func(track *track) error {
 		if track.codecCapability != nil {
@@ -0,0 +1,165 @@
+package mediasource
+
+import (
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v4"
+)
+
+type RTCPEventType uint8
+
+const (
+	RTCPEventPLI RTCPEventType = iota + 1
+	RTCPEventFIR
+	RTCPEventNACK
+	RTCPEventREMB
+	RTCPEventReceiverReport
+)
+
+func (t RTCPEventType) String() string {
+	switch t {
+	case RTCPEventPLI:
+		return "pli"
+	case RTCPEventFIR:
+		return "fir"
+	case RTCPEventNACK:
+		return "nack"
+	case RTCPEventREMB:
+		return "remb"
+	case RTCPEventReceiverReport:
+		return "receiver-report"
+	default:
+		return "unknown"
+	}
+}
+
+// RTCPEvent is an RTCP packet the remote peer sent about a track. Next to the packet itself, the fields
+// relevant to its type are filled in: Lost for NACK, Bitrate for REMB and Reports for receiver reports
+// (including the reports carried by sender reports).
+type RTCPEvent struct {
+	Type    RTCPEventType
+	Packet  rtcp.Packet
+	Lost    []uint16
+	Bitrate float32
+	Reports []rtcp.ReceptionReport
+}
+
+// RTCPHandler is called for every RTCP event of a track, from the goroutine reading the RTCP of the
+// track; it should not block.
+type RTCPHandler = func(RTCPEvent)
+
+// KeyframeHandler is called when the remote peer asks for a keyframe, e.g. to call ForceKeyframe of a
+// transcode.GeneralEncoder, UpdateEncoder or Transcoder.
+type KeyframeHandler = func()
+
+// WithRTCPHandler sets the handler for the RTCP events of the track, see OnRTCP.
+func WithRTCPHandler(handler RTCPHandler) TrackOption {
+	return func(track *track) error {
+		track.onRTCP = handler
+		return nil
+	}
+}
+
+// WithKeyframeHandler sets the handler for keyframe requests of the remote peer, see OnKeyframeRequest.
+func WithKeyframeHandler(handler KeyframeHandler) TrackOption {
+	return func(track *track) error {
+		track.onKeyframe = handler
+		return nil
+	}
+}
+
+// OnRTCP sets the handler for the RTCP events of the track, replacing the previous one.
+func (track *track) OnRTCP(handler RTCPHandler) {
+	track.mux.Lock()
+	defer track.mux.Unlock()
+
+	track.onRTCP = handler
+}
+
+// OnKeyframeRequest sets the handler called for every PLI, and for every new FIR, of the remote peer,
+// replacing the previous one.
+func (track *track) OnKeyframeRequest(handler KeyframeHandler) {
+	track.mux.Lock()
+	defer track.mux.Unlock()
+
+	track.onKeyframe = handler
+}
+
+// handleRTCP turns the packets into events and calls the handlers; ssrcs are the SSRCs the track is
+// sent with.
+func (track *track) handleRTCP(packets []rtcp.Packet, ssrcs map[webrtc.SSRC]struct{}) {
+	track.mux.Lock()
+	onRTCP, onKeyframe := track.onRTCP, track.onKeyframe
+	track.mux.Unlock()
+
+	for _, packet := range packets {
+		event, keyframe, ok := track.rtcpEvent(packet, ssrcs)
+		if !ok {
+			continue
+		}
+
+		if keyframe && onKeyframe != nil {
+			onKeyframe()
+		}
+		if onRTCP != nil {
+			onRTCP(event)
+		}
+	}
+}
+
+// rtcpEvent describes the packet; keyframe is set when it asks for a keyframe.
+func (track *track) rtcpEvent(packet rtcp.Packet, ssrcs map[webrtc.SSRC]struct{}) (event RTCPEvent, keyframe bool, ok bool) {
+	event.Packet = packet
+
+	switch p := packet.(type) {
+	case *rtcp.PictureLossIndication:
+		event.Type = RTCPEventPLI
+		keyframe = true
+	case *rtcp.FullIntraRequest:
+		event.Type = RTCPEventFIR
+		keyframe = track.newFIR(p, ssrcs)
+	case *rtcp.TransportLayerNack:
+		event.Type = RTCPEventNACK
+		for _, pair := range p.Nacks {
+			event.Lost = append(event.Lost, pair.PacketList()...)
+		}
+	case *rtcp.ReceiverEstimatedMaximumBitrate:
+		event.Type = RTCPEventREMB
+		event.Bitrate = p.Bitrate
+	case *rtcp.ReceiverReport:
+		event.Type = RTCPEventReceiverReport
+		event.Reports = p.Reports
+	case *rtcp.SenderReport:
+		if len(p.Reports) == 0 {
+			return event, false, false
+		}
+		event.Type = RTCPEventReceiverReport
+		event.Reports = p.Reports
+	default:
+		return event, false, false
+	}
+
+	track.logger.Debug("received rtcp", "type", event.Type.String())
+	return event, keyframe, true
+}
+
+// newFIR reports whether the FIR asks this track for a keyframe it did not ask for before. A FIR carries
+// entries for several media sources, and is repeated with the same sequence number till the keyframe
+// arrives; only new entries for the SSRCs of this track count.
+func (track *track) newFIR(fir *rtcp.FullIntraRequest, ssrcs map[webrtc.SSRC]struct{}) bool {
+	track.mux.Lock()
+	defer track.mux.Unlock()
+
+	keyframe := false
+	for _, entry := range fir.FIR {
+		if _, ok := ssrcs[webrtc.SSRC(entry.SSRC)]; !ok {
+			continue
+		}
+
+		if !track.firSeen || entry.SequenceNumber != track.firSequence {
+			keyframe = true
+		}
+		track.firSeen, track.firSequence = true, entry.SequenceNumber
+	}
+
+	return keyframe
+}
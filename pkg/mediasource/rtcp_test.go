@@ -0,0 +1,118 @@
+package mediasource
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v4"
+)
+
+const testSSRC = 1234
+
+// handle passes the packets to the handlers of a track sent with testSSRC and returns the events and
+// the number of keyframe requests.
+func handle(t *track, packets ...rtcp.Packet) ([]RTCPEvent, int) {
+	var (
+		events    []RTCPEvent
+		keyframes int
+	)
+	t.OnRTCP(func(event RTCPEvent) {
+		events = append(events, event)
+	})
+	t.OnKeyframeRequest(func() {
+		keyframes++
+	})
+
+	t.handleRTCP(packets, map[webrtc.SSRC]struct{}{testSSRC: {}})
+
+	return events, keyframes
+}
+
+func newTestTrack() *track {
+	return &track{logger: slog.New(slog.DiscardHandler)}
+}
+
+func TestRTCPEvents(t *testing.T) {
+	tests := []struct {
+		name      string
+		packet    rtcp.Packet
+		expected  RTCPEventType
+		keyframes int
+	}{
+		{"pli", &rtcp.PictureLossIndication{MediaSSRC: testSSRC}, RTCPEventPLI, 1},
+		{"fir", &rtcp.FullIntraRequest{FIR: []rtcp.FIREntry{{SSRC: testSSRC, SequenceNumber: 1}}}, RTCPEventFIR, 1},
+		{"fir of another track", &rtcp.FullIntraRequest{FIR: []rtcp.FIREntry{{SSRC: testSSRC + 1, SequenceNumber: 1}}}, RTCPEventFIR, 0},
+		{"nack", &rtcp.TransportLayerNack{MediaSSRC: testSSRC, Nacks: []rtcp.NackPair{{PacketID: 10, LostPackets: 0b101}}}, RTCPEventNACK, 0},
+		{"remb", &rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 1_500_000, SSRCs: []uint32{testSSRC}}, RTCPEventREMB, 0},
+		{"receiver report", &rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: testSSRC, FractionLost: 12}}}, RTCPEventReceiverReport, 0},
+		{"sender report with reports", &rtcp.SenderReport{Reports: []rtcp.ReceptionReport{{SSRC: testSSRC}}}, RTCPEventReceiverReport, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, keyframes := handle(newTestTrack(), test.packet)
+
+			if len(events) != 1 || events[0].Type != test.expected {
+				t.Fatalf("expected one %s event, got %v", test.expected, events)
+			}
+			if events[0].Packet != test.packet {
+				t.Fatal("expected the event to carry its packet")
+			}
+			if keyframes != test.keyframes {
+				t.Fatalf("expected %d keyframe requests, got %d", test.keyframes, keyframes)
+			}
+		})
+	}
+}
+
+func TestRTCPEventFields(t *testing.T) {
+	events, _ := handle(newTestTrack(),
+		&rtcp.TransportLayerNack{MediaSSRC: testSSRC, Nacks: []rtcp.NackPair{{PacketID: 10, LostPackets: 0b101}}},
+		&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 1_500_000, SSRCs: []uint32{testSSRC}},
+		&rtcp.ReceiverReport{Reports: []rtcp.ReceptionReport{{SSRC: testSSRC, FractionLost: 12}}},
+	)
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(events))
+	}
+
+	lost := events[0].Lost
+	if len(lost) != 3 || lost[0] != 10 || lost[1] != 11 || lost[2] != 13 {
+		t.Fatalf("expected packets 10, 11 and 13 to be lost, got %v", lost)
+	}
+	if events[1].Bitrate != 1_500_000 {
+		t.Fatalf("expected a bitrate of 1500000, got %f", events[1].Bitrate)
+	}
+	if len(events[2].Reports) != 1 || events[2].Reports[0].FractionLost != 12 {
+		t.Fatalf("expected the reception report, got %v", events[2].Reports)
+	}
+}
+
+func TestRTCPEventsSkipOtherPackets(t *testing.T) {
+	events, keyframes := handle(newTestTrack(),
+		&rtcp.SenderReport{SSRC: testSSRC},
+		&rtcp.SourceDescription{},
+		&rtcp.Goodbye{Sources: []uint32{testSSRC}},
+	)
+
+	if len(events) != 0 || keyframes != 0 {
+		t.Fatalf("expected no events, got %v and %d keyframe requests", events, keyframes)
+	}
+}
+
+func TestRepeatedFIRRequestsOneKeyframe(t *testing.T) {
+	track := newTestTrack()
+
+	fir := func(sequenceNumber uint8) rtcp.Packet {
+		return &rtcp.FullIntraRequest{FIR: []rtcp.FIREntry{{SSRC: testSSRC, SequenceNumber: sequenceNumber}}}
+	}
+
+	// NOTE: THE REMOTE REPEATS A FIR WITH THE SAME SEQUENCE NUMBER TILL THE KEYFRAME ARRIVES
+	events, keyframes := handle(track, fir(1), fir(1), fir(1), fir(2))
+	if len(events) != 4 {
+		t.Fatalf("expected every fir to be reported, got %d events", len(events))
+	}
+	if keyframes != 2 {
+		t.Fatalf("expected a keyframe request per new sequence number, got %d", keyframes)
+	}
+}
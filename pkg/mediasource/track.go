@@ -20,7 +20,7 @@ type Track struct {
 
 func CreateTrack(ctx context.Context, label string, peerConnection *webrtc.PeerConnection, options ...TrackOption) (*Track, error) {
 	track := &Track{
-		track: &track{},
+		track: &track{logger: packageLogger.Get()},
 		ctx:   ctx,
 	}
 
@@ -29,6 +29,7 @@ func CreateTrack(ctx context.Context, label string, peerConnection *webrtc.PeerC
 			return nil, err
 		}
 	}
+	track.logger = track.logger.With("track", label)
 
 	if track.codecCapability == nil {
 		return nil, errors.New("no track capabilities given")
@@ -39,13 +40,12 @@ func CreateTrack(ctx context.Context, label string, peerConnection *webrtc.PeerC
 		return nil, err
 	}
 	track.consumer = consumer
+	track.local = consumer
 
-	if track.rtpSender, err = peerConnection.AddTrack(consumer); err != nil {
+	if err := track.rebind(ctx, peerConnection); err != nil {
 		return nil, err
 	}
 
-	go track.rtpSenderLoop()
-
 	return track, nil
 }
 
@@ -53,22 +53,6 @@ func (track *Track) GetPriority() Priority {
 	return track.priority
 }
 
-func (track *Track) rtpSenderLoop() {
-	// THIS IS NEEDED AS interceptors (pion) doesnt work
-	for {
-		select {
-		case <-track.ctx.Done():
-			return
-		default:
-			rtcpBuf := make([]byte, 1500)
-			if _, _, err := track.rtpSender.Read(rtcpBuf); err != nil {
-				// fmt.Println("error while reading rtcp packets")
-				continue
-			}
-		}
-	}
-}
-
 func (track *Track) WriteSample(sample media.Sample) error {
 	if err := track.consumer.WriteSample(sample); err != nil {
 		return err
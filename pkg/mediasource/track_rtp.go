@@ -3,7 +3,9 @@ package mediasource
 import (
 	"context"
 	"errors"
-	"fmt"
+	"io"
+	"log/slog"
+	"sync"
 
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v4"
@@ -14,7 +16,75 @@ import (
 type track struct {
 	codecCapability *webrtc.RTPCodecCapability
 	rtpSender       *webrtc.RTPSender
+	local           webrtc.TrackLocal
 	priority        Priority
+	onRTCP          RTCPHandler
+	onKeyframe      KeyframeHandler
+	// firSeen and firSequence remember the last FIR, which the remote repeats till it is served; guarded
+	// by mux as they are updated from the RTCP loop of every sender of the track
+	firSeen     bool
+	firSequence uint8
+	logger      *slog.Logger
+	mux         sync.Mutex
+}
+
+// rebind adds the same local track to another peer connection, e.g. after the previous one was torn down.
+func (track *track) rebind(ctx context.Context, pc *webrtc.PeerConnection) error {
+	sender, err := pc.AddTrack(track.local)
+	if err != nil {
+		return err
+	}
+	track.rtpSender = sender
+
+	// NOTE: THE REMOTE OF THE NEW PEER CONNECTION NUMBERS ITS FIRS FROM SCRATCH
+	track.mux.Lock()
+	track.firSeen = false
+	track.mux.Unlock()
+
+	// NOTE: THE SSRCS ARE READ BEFORE NEGOTIATION CAN CONFIGURE THE SENDER CONCURRENTLY
+	ssrcs := make(map[webrtc.SSRC]struct{})
+	for _, encoding := range sender.GetParameters().Encodings {
+		ssrcs[encoding.SSRC] = struct{}{}
+	}
+
+	go track.rtpSenderLoop(ctx, sender, ssrcs)
+
+	return nil
+}
+
+// detach removes the track from the peer connection; pion stops the RTPSender, which also ends rtpSenderLoop.
+func (track *track) detach(pc *webrtc.PeerConnection) error {
+	if track.rtpSender == nil {
+		return nil
+	}
+
+	if err := pc.RemoveTrack(track.rtpSender); err != nil {
+		return err
+	}
+	track.rtpSender = nil
+
+	return nil
+}
+
+// rtpSenderLoop handles the RTCP the remote sends about the track; ssrcs are the SSRCs sender sends with.
+func (track *track) rtpSenderLoop(ctx context.Context, sender *webrtc.RTPSender, ssrcs map[webrtc.SSRC]struct{}) {
+	// THIS IS NEEDED AS interceptors (pion) doesnt work
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			packets, _, err := sender.ReadRTCP()
+			if err != nil {
+				if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+					return
+				}
+				continue
+			}
+
+			track.handleRTCP(packets, ssrcs)
+		}
+	}
 }
 
 type RTPTrack struct {
@@ -25,7 +95,7 @@ type RTPTrack struct {
 
 func CreateRTPTrack(ctx context.Context, label string, pc *webrtc.PeerConnection, options ...TrackOption) (*RTPTrack, error) {
 	track := &RTPTrack{
-		track: &track{},
+		track: &track{logger: packageLogger.Get()},
 		ctx:   ctx,
 	}
 
@@ -34,6 +104,7 @@ func CreateRTPTrack(ctx context.Context, label string, pc *webrtc.PeerConnection
 			return nil, err
 		}
 	}
+	track.logger = track.logger.With("track", label)
 
 	if track.codecCapability == nil {
 		return nil, errors.New("no track capabilities given")
@@ -44,13 +115,12 @@ func CreateRTPTrack(ctx context.Context, label string, pc *webrtc.PeerConnection
 		return nil, err
 	}
 	track.consumer = consumer
+	track.local = consumer
 
-	if track.rtpSender, err = pc.AddTrack(consumer); err != nil {
+	if err := track.rebind(ctx, pc); err != nil {
 		return nil, err
 	}
 
-	go track.rtpSenderLoop()
-
 	return track, nil
 }
 
@@ -58,28 +128,12 @@ func (track *RTPTrack) GetPriority() Priority {
 	return track.priority
 }
 
-func (track *RTPTrack) rtpSenderLoop() {
-	// THIS IS NEEDED AS interceptors (pion) doesnt work
-	for {
-		select {
-		case <-track.ctx.Done():
-			return
-		default:
-			rtcpBuf := make([]byte, 1500)
-			if _, _, err := track.rtpSender.Read(rtcpBuf); err != nil {
-				// fmt.Println("error while reading rtcp packets")
-				continue
-			}
-		}
-	}
-}
-
 func (track *RTPTrack) WriteRTP(packet *rtp.Packet) error {
 	if packet == nil {
 		return nil
 	}
 	if err := track.consumer.WriteRTP(packet); err != nil {
-		fmt.Printf("error while writing samples to track (id: ); err; %v. Continuing...", err)
+		track.logger.Warn("error while writing rtp packet to track; continuing", "err", err)
 	}
 
 	return nil
@@ -5,30 +5,57 @@ import (
 	"errors"
 	"fmt"
 	"iter"
+	"log/slog"
+	"sync"
 
 	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/internal/logger"
 )
 
 type Tracks struct {
 	tracks  map[string]*Track
 	tracks2 map[string]*RTPTrack
+	logger  *slog.Logger
+	mux     sync.RWMutex
 	ctx     context.Context
 }
 
+// packageLogger is what tracks log with till WithLogger or Tracks.SetLogger says otherwise.
+var packageLogger logger.Package
+
+// SetLogger sets the logger of media sources that were given none; nil restores slog.Default().
+func SetLogger(l *slog.Logger) {
+	packageLogger.Set(l)
+}
+
 func CreateTracks(ctx context.Context) *Tracks {
 	return &Tracks{
 		tracks:  make(map[string]*Track),
 		tracks2: make(map[string]*RTPTrack),
+		logger:  packageLogger.Get(),
 		ctx:     ctx,
 	}
 }
 
+// SetLogger sets the logger the tracks created from now on log with. Options given to CreateTrack
+// still take precedence.
+func (tracks *Tracks) SetLogger(logger *slog.Logger) {
+	tracks.mux.Lock()
+	defer tracks.mux.Unlock()
+
+	tracks.logger = logger
+}
+
 func (tracks *Tracks) CreateTrack(label string, peerConnection *webrtc.PeerConnection, options ...TrackOption) (*Track, error) {
+	tracks.mux.Lock()
+	defer tracks.mux.Unlock()
+
 	if _, exists := tracks.tracks[label]; exists {
 		return nil, fmt.Errorf("track with id = '%s' already exists", label)
 	}
 
-	track, err := CreateTrack(tracks.ctx, label, peerConnection, options...)
+	track, err := CreateTrack(tracks.ctx, label, peerConnection, append([]TrackOption{WithLogger(tracks.logger)}, options...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -38,11 +65,14 @@ func (tracks *Tracks) CreateTrack(label string, peerConnection *webrtc.PeerConne
 }
 
 func (tracks *Tracks) CreateRTPTrack(label string, peerConnection *webrtc.PeerConnection, options ...TrackOption) (*RTPTrack, error) {
+	tracks.mux.Lock()
+	defer tracks.mux.Unlock()
+
 	if _, exists := tracks.tracks2[label]; exists {
 		return nil, fmt.Errorf("track with id = '%s' already exists", label)
 	}
 
-	track, err := CreateRTPTrack(tracks.ctx, label, peerConnection, options...)
+	track, err := CreateRTPTrack(tracks.ctx, label, peerConnection, append([]TrackOption{WithLogger(tracks.logger)}, options...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +81,44 @@ func (tracks *Tracks) CreateRTPTrack(label string, peerConnection *webrtc.PeerCo
 	return track, nil
 }
 
+func (tracks *Tracks) RemoveTrack(id string, peerConnection *webrtc.PeerConnection) error {
+	tracks.mux.Lock()
+	defer tracks.mux.Unlock()
+
+	track, exists := tracks.tracks[id]
+	if !exists {
+		return errors.New("track does not exits")
+	}
+
+	if err := track.detach(peerConnection); err != nil {
+		return fmt.Errorf("failed to remove track (id=%s); err: %w", id, err)
+	}
+
+	delete(tracks.tracks, id)
+	return nil
+}
+
+func (tracks *Tracks) RemoveRTPTrack(id string, peerConnection *webrtc.PeerConnection) error {
+	tracks.mux.Lock()
+	defer tracks.mux.Unlock()
+
+	track, exists := tracks.tracks2[id]
+	if !exists {
+		return errors.New("track does not exits")
+	}
+
+	if err := track.detach(peerConnection); err != nil {
+		return fmt.Errorf("failed to remove track (id=%s); err: %w", id, err)
+	}
+
+	delete(tracks.tracks2, id)
+	return nil
+}
+
 func (tracks *Tracks) GetTrack(id string) (*Track, error) {
+	tracks.mux.RLock()
+	defer tracks.mux.RUnlock()
+
 	track, exists := tracks.tracks[id]
 	if !exists {
 		return nil, errors.New("track does not exits")
@@ -61,6 +128,9 @@ func (tracks *Tracks) GetTrack(id string) (*Track, error) {
 }
 
 func (tracks *Tracks) GetRTPTrack(id string) (*RTPTrack, error) {
+	tracks.mux.RLock()
+	defer tracks.mux.RUnlock()
+
 	track, exists := tracks.tracks2[id]
 	if !exists {
 		return nil, errors.New("track does not exits")
@@ -71,6 +141,9 @@ func (tracks *Tracks) GetRTPTrack(id string) (*RTPTrack, error) {
 
 func (tracks *Tracks) Tracks() iter.Seq2[string, *Track] {
 	return func(yield func(string, *Track) bool) {
+		tracks.mux.RLock()
+		defer tracks.mux.RUnlock()
+
 		for id, track := range tracks.tracks {
 			if !yield(id, track) {
 				return
@@ -81,6 +154,9 @@ func (tracks *Tracks) Tracks() iter.Seq2[string, *Track] {
 
 func (tracks *Tracks) RTPTracks() iter.Seq2[string, *RTPTrack] {
 	return func(yield func(string, *RTPTrack) bool) {
+		tracks.mux.RLock()
+		defer tracks.mux.RUnlock()
+
 		for id, track := range tracks.tracks2 {
 			if !yield(id, track) {
 				return
@@ -88,3 +164,24 @@ func (tracks *Tracks) RTPTracks() iter.Seq2[string, *RTPTrack] {
 		}
 	}
 }
+
+// Rebind re-adds every track to the given peer connection. This is used when the underlying
+// peer connection is recreated; the Track values handed out earlier remain valid.
+func (tracks *Tracks) Rebind(peerConnection *webrtc.PeerConnection) error {
+	tracks.mux.RLock()
+	defer tracks.mux.RUnlock()
+
+	for id, track := range tracks.tracks {
+		if err := track.rebind(tracks.ctx, peerConnection); err != nil {
+			return fmt.Errorf("failed to rebind track (id=%s); err: %w", id, err)
+		}
+	}
+
+	for id, track := range tracks.tracks2 {
+		if err := track.rebind(tracks.ctx, peerConnection); err != nil {
+			return fmt.Errorf("failed to rebind track (id=%s); err: %w", id, err)
+		}
+	}
+
+	return nil
+}
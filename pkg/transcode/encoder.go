@@ -5,8 +5,8 @@ package transcode
 import (
 	"context"
 	"errors"
-	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/asticode/go-astiav"
@@ -24,6 +24,7 @@ type GeneralEncoder struct {
 	encoderSettings codecSettings
 	sps             []byte
 	pps             []byte
+	forceKeyframe   atomic.Bool
 
 	once   sync.Once
 	wg     sync.WaitGroup
@@ -63,7 +64,7 @@ func CreateGeneralEncoder(ctx context.Context, codecID astiav.CodecID, canProduc
 	}
 
 	if encoder.encoderSettings == nil {
-		fmt.Println("warn: no encoder settings are provided")
+		packageLogger.Get().Warn("no encoder settings are provided")
 	}
 
 	encoder.encoderContext.SetFlags(astiav.NewCodecContextFlags(astiav.CodecContextFlagGlobalHeader))
@@ -108,7 +109,19 @@ loop1:
 			if err != nil {
 				continue
 			}
-			if err := e.encoderContext.SendFrame(frame); err != nil {
+			keyframe := e.forceKeyframe.Swap(false)
+			if keyframe {
+				frame.SetPictureType(astiav.PictureTypeI)
+			}
+			err = e.encoderContext.SendFrame(frame)
+			if keyframe {
+				// NOTE: FRAMES ARE POOLED; THE NEXT USER OF THIS ONE MUST NOT GET A KEYFRAME BY ACCIDENT
+				frame.SetPictureType(astiav.PictureTypeNone)
+			}
+			if err != nil {
+				if keyframe {
+					e.forceKeyframe.Store(true)
+				}
 				e.producer.PutBack(frame)
 				if !errors.Is(err, astiav.ErrEagain) {
 					continue loop1
@@ -225,6 +238,12 @@ func (e *GeneralEncoder) SetEncoderCodecSettings(settings codecSettings) error {
 	})
 }
 
+// ForceKeyframe makes the encoder emit the next frame as a keyframe instead of waiting for the GOP to end.
+func (e *GeneralEncoder) ForceKeyframe() error {
+	e.forceKeyframe.Store(true)
+	return nil
+}
+
 func (e *GeneralEncoder) GetCurrentBitrate() (int64, error) {
 	g, ok := e.encoderSettings.(CanGetCurrentBitrate)
 	if !ok {
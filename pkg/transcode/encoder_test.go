@@ -0,0 +1,194 @@
+//go:build cgo_enabled
+
+package transcode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astiav"
+)
+
+const (
+	testWidth  = 64
+	testHeight = 64
+)
+
+// testFrameProducer hands the encoder the frames the test sends, and describes them as 64x64 YUV420P
+// video at 30 fps.
+type testFrameProducer struct {
+	frames chan *astiav.Frame
+}
+
+func newTestFrameProducer() *testFrameProducer {
+	return &testFrameProducer{frames: make(chan *astiav.Frame)}
+}
+
+func (p *testFrameProducer) GetFrame(ctx context.Context) (*astiav.Frame, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case frame := <-p.frames:
+		return frame, nil
+	}
+}
+
+// PutBack does nothing; the frames are freed when the test ends.
+func (p *testFrameProducer) PutBack(*astiav.Frame) {}
+
+func (p *testFrameProducer) MediaType() astiav.MediaType {
+	return astiav.MediaTypeVideo
+}
+
+func (p *testFrameProducer) FrameRate() astiav.Rational {
+	return astiav.NewRational(30, 1)
+}
+
+func (p *testFrameProducer) TimeBase() astiav.Rational {
+	return astiav.NewRational(1, 30)
+}
+
+func (p *testFrameProducer) Height() int {
+	return testHeight
+}
+
+func (p *testFrameProducer) Width() int {
+	return testWidth
+}
+
+func (p *testFrameProducer) PixelFormat() astiav.PixelFormat {
+	return astiav.PixelFormatYuv420P
+}
+
+func (p *testFrameProducer) SampleAspectRatio() astiav.Rational {
+	return astiav.NewRational(1, 1)
+}
+
+func (p *testFrameProducer) ColorSpace() astiav.ColorSpace {
+	return astiav.ColorSpaceUnspecified
+}
+
+func (p *testFrameProducer) ColorRange() astiav.ColorRange {
+	return astiav.ColorRangeUnspecified
+}
+
+func (p *testFrameProducer) SampleRate() int {
+	return 0
+}
+
+func (p *testFrameProducer) SampleFormat() astiav.SampleFormat {
+	return astiav.SampleFormatNone
+}
+
+func (p *testFrameProducer) ChannelLayout() astiav.ChannelLayout {
+	return astiav.ChannelLayout{}
+}
+
+// send hands the encoder a black frame with the given timestamp.
+func (p *testFrameProducer) send(t *testing.T, pts int64) {
+	t.Helper()
+
+	frame := astiav.AllocFrame()
+	t.Cleanup(frame.Free)
+
+	frame.SetWidth(testWidth)
+	frame.SetHeight(testHeight)
+	frame.SetPixelFormat(astiav.PixelFormatYuv420P)
+	if err := frame.AllocBuffer(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := frame.ImageFillBlack(); err != nil {
+		t.Fatal(err)
+	}
+	frame.SetPts(pts)
+
+	select {
+	case p.frames <- frame:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the encoder did not take the frame")
+	}
+}
+
+// testCodecSettings are the encoder options as they are, e.g. {"preset": "ultrafast"}.
+type testCodecSettings map[string]string
+
+func (s testCodecSettings) ForEach(fn func(string, string) error) error {
+	for key, value := range s {
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasIDR reports whether the Annex-B access unit has a NAL unit of an IDR picture.
+func hasIDR(data []byte) bool {
+	for i := 0; i+3 < len(data); i++ {
+		if data[i] == 0 && data[i+1] == 0 && data[i+2] == 1 && data[i+3]&0x1F == 5 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestForceKeyframeMakesNextFrameIDR(t *testing.T) {
+	if astiav.FindEncoder(astiav.CodecIDH264) == nil {
+		t.Skip("no h264 encoder")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	producer := newTestFrameProducer()
+	// NOTE: WITHOUT LOOKAHEAD OR B FRAMES EVERY FRAME COMES OUT AS SOON AS IT GOES IN, AND THE GOP OUTLASTS THE TEST
+	settings := testCodecSettings{"preset": "ultrafast", "tune": "zerolatency", "g": "1000", "bf": "0"}
+	encoder, err := CreateGeneralEncoder(ctx, astiav.CodecIDH264, producer, WithCodecSettings(settings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder.Start()
+	defer encoder.Close()
+
+	next := func(pts int64) *astiav.Packet {
+		t.Helper()
+
+		producer.send(t, pts)
+
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		packet, err := encoder.GetPacket(ctx)
+		if err != nil {
+			t.Fatalf("no packet for frame %d: %v", pts, err)
+		}
+		t.Cleanup(func() {
+			encoder.PutBack(packet)
+		})
+
+		return packet
+	}
+
+	if packet := next(0); !packet.Flags().Has(astiav.PacketFlagKey) {
+		t.Fatal("expected the first frame to be a keyframe")
+	}
+	for pts := int64(1); pts < 5; pts++ {
+		if packet := next(pts); packet.Flags().Has(astiav.PacketFlagKey) {
+			t.Fatalf("expected frame %d to not be a keyframe", pts)
+		}
+	}
+
+	if err := encoder.ForceKeyframe(); err != nil {
+		t.Fatal(err)
+	}
+
+	packet := next(5)
+	if !packet.Flags().Has(astiav.PacketFlagKey) || !hasIDR(packet.Data()) {
+		t.Fatal("expected the frame after ForceKeyframe to be an IDR frame")
+	}
+
+	// NOTE: THE FORCED PICTURE TYPE IS RESET, SO ONLY ONE KEYFRAME IS FORCED
+	if packet := next(6); packet.Flags().Has(astiav.PacketFlagKey) {
+		t.Fatal("expected only the frame after ForceKeyframe to be forced")
+	}
+}
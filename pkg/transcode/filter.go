@@ -4,7 +4,6 @@ package transcode
 
 import (
 	"context"
-	"fmt"
 	"sync"
 	"time"
 
@@ -109,7 +108,7 @@ func CreateGeneralFilter(ctx context.Context, canProduceMediaFrame CanProduceMed
 	filter.input.SetNext(nil)
 
 	if filter.content == "" {
-		fmt.Println(WarnNoFilterContent)
+		packageLogger.Get().Warn(WarnNoFilterContent.Error())
 	}
 
 	if err = filter.graph.Parse(filter.content, filter.input, filter.output); err != nil {
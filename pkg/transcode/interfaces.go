@@ -1,7 +1,11 @@
 package transcode
 
 import (
+	"log/slog"
+
 	"github.com/harshabose/tools/pkg/buffer"
+
+	"github.com/harshabose/simple_webrtc_comm/client/internal/logger"
 )
 
 type CanSetBuffer[T any] interface {
@@ -32,6 +36,11 @@ type CanAdaptFPS interface {
 	AdaptFPS(uint8) error
 }
 
+// CanForceKeyframe makes the next encoded frame a keyframe, e.g. when the remote peer sent a PLI or FIR.
+type CanForceKeyframe interface {
+	ForceKeyframe() error
+}
+
 type CanGetCurrentFPS interface {
 	GetCurrentFPS() (uint8, error)
 }
@@ -45,3 +54,11 @@ type UpdateBitrateCallBack func(bps int64) error
 type CanGetUpdateBitrateCallBack interface {
 	OnUpdateBitrate() UpdateBitrateCallBack
 }
+
+// packageLogger is what the demuxers, encoders, filters, muxers and sample pumps of the package log with.
+var packageLogger logger.Package
+
+// SetLogger sets the logger of the package; nil restores slog.Default().
+func SetLogger(l *slog.Logger) {
+	packageLogger.Set(l)
+}
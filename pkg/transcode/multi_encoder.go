@@ -220,6 +220,10 @@ func (u *MultiUpdateEncoder) cutoff(bps int64) int64 {
 	return bps
 }
 
+func (u *MultiUpdateEncoder) ForceKeyframe() error {
+	return u.active.Load().encoder.ForceKeyframe()
+}
+
 func (u *MultiUpdateEncoder) GetParameterSets() (sps []byte, pps []byte, err error) {
 	return u.active.Load().encoder.GetParameterSets()
 }
@@ -0,0 +1,330 @@
+//go:build cgo_enabled
+
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astiav"
+	"github.com/pion/webrtc/v4/pkg/media"
+
+	"github.com/harshabose/mediapipe/pkg/consumers"
+)
+
+type SamplePumpOption = func(*SamplePump) error
+
+// WithPumpTimeBase sets the time base of the packet timestamps, for producers that cannot describe it.
+func WithPumpTimeBase(timeBase astiav.Rational) SamplePumpOption {
+	return func(pump *SamplePump) error {
+		if timeBase.Num() <= 0 || timeBase.Den() <= 0 {
+			return errors.New("invalid time base")
+		}
+		pump.timeBase = timeBase
+		return nil
+	}
+}
+
+// WithPumpFrameDuration sets the duration of samples whose duration cannot be derived from the packets,
+// e.g. when the next packet has no timestamp; the default follows the frame rate of the producer, or
+// 1/30 s.
+func WithPumpFrameDuration(duration time.Duration) SamplePumpOption {
+	return func(pump *SamplePump) error {
+		if duration <= 0 {
+			return errors.New("frame duration must be positive")
+		}
+		pump.frameDuration = duration
+		return nil
+	}
+}
+
+// WithPumpLogger sets the logger of the pump.
+func WithPumpLogger(logger *slog.Logger) SamplePumpOption {
+	return func(pump *SamplePump) error {
+		pump.logger = logger
+		return nil
+	}
+}
+
+// SamplePumpStats counts what a SamplePump did since it was started. Errors counts the failures of the
+// producer to hand out a packet. Bitrate is the payload bitrate over the last second.
+type SamplePumpStats struct {
+	Packets uint64
+	Samples uint64
+	Bytes   uint64
+	Dropped uint64
+	Errors  uint64
+	Bitrate float64
+}
+
+const (
+	// pumpMinBackoff and pumpMaxBackoff bound how long the pump waits after the producer failed, doubling
+	// from one to the other while the failures go on
+	pumpMinBackoff = 10 * time.Millisecond
+	pumpMaxBackoff = time.Second
+)
+
+// SamplePump moves encoded packets, e.g. of a Transcoder, into a track that takes samples, like
+// mediasource.Track. A packet that does not declare its duration is held back till the next one arrives,
+// as its duration is the time to the next timestamp, in the time base of the producer. When the producer
+// has parameter sets (H.264 with a global header), they are prepended to every keyframe so that
+// receivers can start decoding at any keyframe. The pump stops once the context of the producer ended;
+// other failures of the producer are counted and retried with a backoff.
+type SamplePump struct {
+	producer      CanProduceMediaPacket
+	track         consumers.CanConsumePionSamplePacket
+	timeBase      astiav.Rational
+	frameDuration time.Duration
+
+	// pending is the sample waiting for the timestamp of the next packet to know its duration
+	pending    *media.Sample
+	pendingPTS int64
+	// lastDuration is the duration of the last sample written, which the held back sample gets on Close
+	lastDuration time.Duration
+
+	stats       SamplePumpStats
+	windowStart time.Time
+	windowBytes uint64
+
+	logger *slog.Logger
+	mux    sync.Mutex
+	once   sync.Once
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func CreateSamplePump(ctx context.Context, producer CanProduceMediaPacket, track consumers.CanConsumePionSamplePacket, options ...SamplePumpOption) (*SamplePump, error) {
+	ctx2, cancel2 := context.WithCancel(ctx)
+
+	pump := &SamplePump{
+		producer:      producer,
+		track:         track,
+		frameDuration: time.Second / 30,
+		logger:        packageLogger.Get(),
+		ctx:           ctx2,
+		cancel:        cancel2,
+	}
+
+	if describer, ok := producer.(CanDescribeTimeBase); ok {
+		pump.timeBase = describer.TimeBase()
+	}
+	if describer, ok := producer.(CanDescribeFrameRate); ok {
+		if rate := describer.FrameRate(); rate.Num() > 0 && rate.Den() > 0 {
+			pump.frameDuration = time.Duration(float64(time.Second) * float64(rate.Den()) / float64(rate.Num()))
+		}
+	}
+
+	for _, option := range options {
+		if err := option(pump); err != nil {
+			cancel2()
+			return nil, err
+		}
+	}
+
+	if pump.timeBase.Num() <= 0 || pump.timeBase.Den() <= 0 {
+		cancel2()
+		return nil, errors.New("time base of the packets is unknown; use WithPumpTimeBase")
+	}
+
+	return pump, nil
+}
+
+func (p *SamplePump) Start() {
+	p.wg.Add(1)
+	go p.loop()
+}
+
+// Close stops the pump and writes the sample still held back, so that the last packet is not lost.
+func (p *SamplePump) Close() {
+	p.once.Do(func() {
+		p.cancel()
+		p.wg.Wait()
+
+		p.flush()
+	})
+}
+
+// flush writes the sample held back; no timestamp follows it, so it lasts as long as the sample before
+// it, or a frame of the producer if there was none.
+func (p *SamplePump) flush() {
+	if p.pending == nil {
+		return
+	}
+
+	p.pending.Duration = p.lastDuration
+	if p.pending.Duration <= 0 {
+		p.pending.Duration = p.frameDuration
+	}
+
+	p.write(*p.pending)
+	p.pending = nil
+}
+
+// Stats returns the counters of the pump.
+func (p *SamplePump) Stats() SamplePumpStats {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	return p.stats
+}
+
+func (p *SamplePump) loop() {
+	defer p.wg.Done()
+
+	p.mux.Lock()
+	p.windowStart = time.Now()
+	p.mux.Unlock()
+
+	var backoff time.Duration
+	for {
+		packet, err := p.producer.GetPacket(p.ctx)
+		if err != nil {
+			if p.ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				// NOTE: THE CONTEXT OF THE PRODUCER ENDED; NO PACKET WILL EVER COME
+				p.logger.Info("producer is done; stopping sample pump", "err", err)
+				return
+			}
+
+			backoff = min(max(2*backoff, pumpMinBackoff), pumpMaxBackoff)
+			p.countError()
+			p.logger.Debug("error while getting packet; backing off", "err", err, "backoff", backoff)
+
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			continue
+		}
+		backoff = 0
+
+		sample, pts, ok := p.sample(packet)
+		p.producer.PutBack(packet)
+
+		if !ok {
+			p.count(0, true)
+			continue
+		}
+
+		p.push(sample, pts)
+	}
+}
+
+// sample turns the packet into a sample, with the duration the packet declares, if any, and returns its
+// timestamp; the packet can be put back afterward.
+func (p *SamplePump) sample(packet *astiav.Packet) (media.Sample, int64, bool) {
+	data := packet.Data()
+	if len(data) == 0 {
+		return media.Sample{}, astiav.NoPtsValue, false
+	}
+
+	if packet.Flags().Has(astiav.PacketFlagKey) {
+		data = p.withParameterSets(data)
+	}
+
+	pts := packet.Pts()
+	if pts == astiav.NoPtsValue {
+		pts = packet.Dts()
+	}
+
+	var duration time.Duration
+	if packet.Duration() > 0 {
+		duration = p.toDuration(packet.Duration())
+	}
+
+	return media.Sample{
+		Data:     data,
+		Duration: duration,
+	}, pts, true
+}
+
+// push writes the sample held back, now that the timestamp of the sample after it is known, and then
+// sample itself, or holds it back if it has no duration.
+func (p *SamplePump) push(sample media.Sample, pts int64) {
+	if p.pending != nil {
+		if pts != astiav.NoPtsValue && p.pendingPTS != astiav.NoPtsValue && pts > p.pendingPTS {
+			p.pending.Duration = p.toDuration(pts - p.pendingPTS)
+		} else {
+			p.pending.Duration = p.frameDuration
+		}
+
+		p.write(*p.pending)
+		p.pending = nil
+	}
+
+	if sample.Duration > 0 {
+		p.write(sample)
+		return
+	}
+
+	p.pending, p.pendingPTS = &sample, pts
+}
+
+func (p *SamplePump) write(sample media.Sample) {
+	if err := p.track.WriteSample(sample); err != nil {
+		p.logger.Debug("failed to write sample; dropping", "err", err)
+		p.count(0, true)
+		return
+	}
+
+	p.lastDuration = sample.Duration
+	p.count(len(sample.Data), false)
+}
+
+func (p *SamplePump) toDuration(ts int64) time.Duration {
+	return time.Duration(float64(ts) * float64(time.Second) * float64(p.timeBase.Num()) / float64(p.timeBase.Den()))
+}
+
+// withParameterSets prepends the SPS and PPS of the producer, unless the keyframe already has them.
+func (p *SamplePump) withParameterSets(data []byte) []byte {
+	g, ok := p.producer.(CanGetParameterSets)
+	if !ok {
+		return data
+	}
+
+	sps, pps, err := g.GetParameterSets()
+	if err != nil || len(sps) == 0 || len(pps) == 0 || bytes.Contains(data, sps) {
+		return data
+	}
+
+	withSets := make([]byte, 0, len(sps)+len(pps)+len(data))
+	withSets = append(withSets, sps...)
+	withSets = append(withSets, pps...)
+
+	return append(withSets, data...)
+}
+
+func (p *SamplePump) count(size int, dropped bool) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.stats.Packets++
+	if dropped {
+		p.stats.Dropped++
+	} else {
+		p.stats.Samples++
+		p.stats.Bytes += uint64(size)
+		p.windowBytes += uint64(size)
+	}
+
+	if elapsed := time.Since(p.windowStart); elapsed >= time.Second {
+		p.stats.Bitrate = float64(p.windowBytes*8) / elapsed.Seconds()
+		p.windowStart = time.Now()
+		p.windowBytes = 0
+	}
+}
+
+func (p *SamplePump) countError() {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.stats.Errors++
+}
@@ -0,0 +1,304 @@
+//go:build cgo_enabled
+
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astiav"
+	"github.com/pion/webrtc/v4/pkg/media"
+)
+
+var (
+	testSPS = []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0xc0, 0x1f}
+	testPPS = []byte{0x00, 0x00, 0x00, 0x01, 0x68, 0xce, 0x3c, 0x80}
+	testIDR = []byte{0x00, 0x00, 0x00, 0x01, 0x65, 0x88, 0x84, 0x00}
+	testP   = []byte{0x00, 0x00, 0x00, 0x01, 0x41, 0x9a, 0x02, 0x00}
+)
+
+// testPacketProducer hands the pump the packets the test sends, in a 90 kHz time base, and reports
+// every packet put back.
+type testPacketProducer struct {
+	packets  chan *astiav.Packet
+	putBacks chan *astiav.Packet
+}
+
+func newTestPacketProducer() *testPacketProducer {
+	return &testPacketProducer{packets: make(chan *astiav.Packet), putBacks: make(chan *astiav.Packet, 16)}
+}
+
+func (p *testPacketProducer) GetPacket(ctx context.Context) (*astiav.Packet, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case packet := <-p.packets:
+		return packet, nil
+	}
+}
+
+func (p *testPacketProducer) PutBack(packet *astiav.Packet) {
+	p.putBacks <- packet
+}
+
+func (p *testPacketProducer) TimeBase() astiav.Rational {
+	return astiav.NewRational(1, 90000)
+}
+
+func (p *testPacketProducer) GetParameterSets() ([]byte, []byte, error) {
+	return testSPS, testPPS, nil
+}
+
+// send hands the pump a packet and waits till the pump put it back.
+func (p *testPacketProducer) send(t *testing.T, data []byte, pts int64, duration int64, keyframe bool) {
+	t.Helper()
+
+	packet := astiav.AllocPacket()
+	t.Cleanup(packet.Free)
+
+	if len(data) > 0 {
+		if err := packet.FromData(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	packet.SetPts(pts)
+	packet.SetDts(pts)
+	packet.SetDuration(duration)
+	if keyframe {
+		packet.SetFlags(astiav.NewPacketFlags(astiav.PacketFlagKey))
+	}
+
+	select {
+	case p.packets <- packet:
+	case <-time.After(time.Second):
+		t.Fatal("the pump did not take the packet")
+	}
+
+	select {
+	case putBack := <-p.putBacks:
+		if putBack != packet {
+			t.Fatal("expected the pump to put back the packet it took")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the pump did not put the packet back")
+	}
+}
+
+// testSampleTrack records the samples written to it; it fails every write while failing is set.
+type testSampleTrack struct {
+	samples chan media.Sample
+	failing atomic.Bool
+}
+
+func newTestSampleTrack() *testSampleTrack {
+	return &testSampleTrack{samples: make(chan media.Sample, 16)}
+}
+
+func (t *testSampleTrack) WriteSample(sample media.Sample) error {
+	if t.failing.Load() {
+		return errors.New("track is closed")
+	}
+	t.samples <- sample
+	return nil
+}
+
+func (t *testSampleTrack) next(tb testing.TB) media.Sample {
+	tb.Helper()
+
+	select {
+	case sample := <-t.samples:
+		return sample
+	case <-time.After(time.Second):
+		tb.Fatal("no sample was written")
+		return media.Sample{}
+	}
+}
+
+func (t *testSampleTrack) assertNone(tb testing.TB) {
+	tb.Helper()
+
+	select {
+	case sample := <-t.samples:
+		tb.Fatalf("expected no sample yet, got one of %d bytes", len(sample.Data))
+	default:
+	}
+}
+
+func startTestPump(t *testing.T, options ...SamplePumpOption) (*SamplePump, *testPacketProducer, *testSampleTrack) {
+	t.Helper()
+
+	producer, track := newTestPacketProducer(), newTestSampleTrack()
+
+	pump, err := CreateSamplePump(context.Background(), producer, track, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pump.Start()
+	t.Cleanup(pump.Close)
+
+	return pump, producer, track
+}
+
+func assertDuration(t *testing.T, sample media.Sample, expected time.Duration) {
+	t.Helper()
+
+	if diff := sample.Duration - expected; diff < -time.Microsecond || diff > time.Microsecond {
+		t.Fatalf("expected a duration of %s, got %s", expected, sample.Duration)
+	}
+}
+
+func TestSamplePumpDurationFromTimestamps(t *testing.T) {
+	pump, producer, track := startTestPump(t)
+
+	// NOTE: WITHOUT A DURATION, A SAMPLE IS HELD BACK TILL THE TIMESTAMP OF THE NEXT ONE IS KNOWN
+	producer.send(t, testP, 0, 0, false)
+	track.assertNone(t)
+
+	producer.send(t, testP, 3000, 0, false)
+	assertDuration(t, track.next(t), time.Second/30)
+
+	producer.send(t, testP, 9000, 0, false)
+	assertDuration(t, track.next(t), time.Second/15)
+
+	// NOTE: A DURATION OF ITS OWN IS TAKEN AS IS, AFTER THE SAMPLE HELD BACK
+	producer.send(t, testP, 12000, 1500, false)
+	assertDuration(t, track.next(t), time.Second/30)
+	assertDuration(t, track.next(t), time.Second/60)
+
+	pump.Close()
+	if stats := pump.Stats(); stats.Samples != 4 || stats.Bytes != uint64(4*len(testP)) {
+		t.Fatalf("expected 4 samples of %d bytes, got %+v", 4*len(testP), stats)
+	}
+}
+
+func TestSamplePumpWritesHeldBackSampleOnClose(t *testing.T) {
+	pump, producer, track := startTestPump(t)
+
+	producer.send(t, testP, 0, 0, false)
+	producer.send(t, testP, 3000, 0, false)
+	assertDuration(t, track.next(t), time.Second/30)
+
+	pump.Close()
+
+	// NOTE: NOTHING FOLLOWS THE LAST SAMPLE, SO IT LASTS AS LONG AS THE ONE BEFORE IT
+	assertDuration(t, track.next(t), time.Second/30)
+
+	if stats := pump.Stats(); stats.Samples != 2 || stats.Bytes != uint64(2*len(testP)) {
+		t.Fatalf("expected the held back sample to be counted, got %+v", stats)
+	}
+}
+
+func TestSamplePumpWritesOnlySampleOnCloseWithFrameDuration(t *testing.T) {
+	pump, producer, track := startTestPump(t, WithPumpFrameDuration(40*time.Millisecond))
+
+	producer.send(t, testP, 0, 0, false)
+	track.assertNone(t)
+
+	pump.Close()
+	assertDuration(t, track.next(t), 40*time.Millisecond)
+}
+
+func TestSamplePumpPrependsParameterSetsToKeyframes(t *testing.T) {
+	withSets := bytes.Join([][]byte{testSPS, testPPS, testIDR}, nil)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		keyframe bool
+		expected []byte
+	}{
+		{"keyframe", testIDR, true, withSets},
+		{"keyframe with parameter sets", withSets, true, withSets},
+		{"delta frame", testP, false, testP},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, producer, track := startTestPump(t)
+
+			producer.send(t, test.data, 0, 3000, test.keyframe)
+			if sample := track.next(t); !bytes.Equal(sample.Data, test.expected) {
+				t.Fatalf("expected %x, got %x", test.expected, sample.Data)
+			}
+		})
+	}
+}
+
+func TestSamplePumpPutsBackEveryPacket(t *testing.T) {
+	pump, producer, track := startTestPump(t)
+
+	// NOTE: SEND FAILS THE TEST UNLESS THE PUMP PUTS THE PACKET BACK
+	producer.send(t, nil, 0, 3000, false)
+	producer.send(t, testP, 0, 3000, false)
+	track.next(t)
+
+	track.failing.Store(true)
+	producer.send(t, testP, 3000, 3000, false)
+
+	// NOTE: THE PACKET IS PUT BACK BEFORE ITS SAMPLE IS WRITTEN; CLOSING WAITS FOR THE WRITE
+	pump.Close()
+	if stats := pump.Stats(); stats.Packets != 3 || stats.Samples != 1 || stats.Dropped != 2 {
+		t.Fatalf("expected 3 packets of which 2 were dropped, got %+v", stats)
+	}
+}
+
+// failingPacketProducer fails every GetPacket with err and counts the calls.
+type failingPacketProducer struct {
+	*testPacketProducer
+	err   error
+	calls atomic.Int64
+}
+
+func (p *failingPacketProducer) GetPacket(context.Context) (*astiav.Packet, error) {
+	p.calls.Add(1)
+	return nil, p.err
+}
+
+func startFailingPump(t *testing.T, failure error) (*SamplePump, *failingPacketProducer) {
+	t.Helper()
+
+	producer := &failingPacketProducer{testPacketProducer: newTestPacketProducer(), err: failure}
+
+	pump, err := CreateSamplePump(context.Background(), producer, newTestSampleTrack())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pump.Start()
+	t.Cleanup(pump.Close)
+
+	return pump, producer
+}
+
+func TestSamplePumpStopsWhenProducerIsDone(t *testing.T) {
+	pump, producer := startFailingPump(t, context.Canceled)
+
+	time.Sleep(100 * time.Millisecond)
+	pump.Close()
+
+	if calls := producer.calls.Load(); calls != 1 {
+		t.Fatalf("expected the pump to stop after the producer was done, got %d calls", calls)
+	}
+	if stats := pump.Stats(); stats.Errors != 0 {
+		t.Fatalf("expected the end of the producer not to count as an error, got %+v", stats)
+	}
+}
+
+func TestSamplePumpBacksOffOnProducerErrors(t *testing.T) {
+	pump, producer := startFailingPump(t, errors.New("buffer is closed"))
+
+	// NOTE: WAITS OF 10, 20, 40, 80 AND 160 MS; A SPINNING PUMP WOULD CALL MILLIONS OF TIMES
+	time.Sleep(300 * time.Millisecond)
+	pump.Close()
+
+	calls := producer.calls.Load()
+	if calls < 2 || calls > 10 {
+		t.Fatalf("expected the pump to back off between failures, got %d calls", calls)
+	}
+	if stats := pump.Stats(); stats.Errors != uint64(calls) {
+		t.Fatalf("expected every failure to be counted, got %d of %d", stats.Errors, calls)
+	}
+}
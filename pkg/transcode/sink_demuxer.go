@@ -0,0 +1,221 @@
+//go:build cgo_enabled
+
+package transcode
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astiav"
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/tools/pkg/buffer"
+)
+
+// SinkDemuxer turns the frames of a mediasink.Sink into packets, so that a GeneralDecoder can decode a
+// remote track directly. After a frame was lost, video frames are dropped till the next keyframe.
+type SinkDemuxer struct {
+	sink            *mediasink.Sink
+	codecParameters *astiav.CodecParameters
+	clockRate       int
+
+	buffer buffer.BufferWithGenerator[*astiav.Packet]
+
+	once   sync.Once
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func CreateSinkDemuxer(ctx context.Context, sink *mediasink.Sink, options ...DemuxerOption) (*SinkDemuxer, error) {
+	ctx2, cancel2 := context.WithCancel(ctx)
+
+	codec := sink.Codec()
+
+	codecID, mediaType, err := codecIDFromMimeType(codec.MimeType)
+	if err != nil {
+		cancel2()
+		return nil, err
+	}
+
+	demuxer := &SinkDemuxer{
+		sink:            sink,
+		codecParameters: astiav.AllocCodecParameters(),
+		clockRate:       int(codec.ClockRate),
+		ctx:             ctx2,
+		cancel:          cancel2,
+	}
+
+	if demuxer.codecParameters == nil {
+		cancel2()
+		return nil, fmt.Errorf("error allocating astiav.CodecParameters (%w)", ErrorGeneralAllocate)
+	}
+
+	demuxer.codecParameters.SetCodecID(codecID)
+	demuxer.codecParameters.SetMediaType(mediaType)
+	if mediaType == astiav.MediaTypeAudio {
+		demuxer.codecParameters.SetSampleRate(int(codec.ClockRate))
+		demuxer.codecParameters.SetChannelLayout(astiav.ChannelLayoutStereo)
+		if codec.Channels == 1 {
+			demuxer.codecParameters.SetChannelLayout(astiav.ChannelLayoutMono)
+		}
+	}
+
+	for _, option := range options {
+		if err := option(demuxer); err != nil {
+			demuxer.close()
+			return nil, err
+		}
+	}
+
+	if demuxer.buffer == nil {
+		demuxer.buffer = buffer.NewChannelBufferWithGenerator(ctx, buffer.CreatePacketPool(), 256, 1)
+	}
+
+	return demuxer, nil
+}
+
+func codecIDFromMimeType(mimeType string) (astiav.CodecID, astiav.MediaType, error) {
+	switch strings.ToLower(mimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		return astiav.CodecIDH264, astiav.MediaTypeVideo, nil
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		return astiav.CodecIDVp8, astiav.MediaTypeVideo, nil
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		return astiav.CodecIDVp9, astiav.MediaTypeVideo, nil
+	case strings.ToLower(webrtc.MimeTypeAV1):
+		return astiav.CodecIDAv1, astiav.MediaTypeVideo, nil
+	case strings.ToLower(webrtc.MimeTypeOpus):
+		return astiav.CodecIDOpus, astiav.MediaTypeAudio, nil
+	default:
+		return astiav.CodecIDNone, astiav.MediaTypeUnknown, fmt.Errorf("%w: %s", ErrorUnsupportedMedia, mimeType)
+	}
+}
+
+func (d *SinkDemuxer) Start() {
+	d.wg.Add(1)
+	go d.loop()
+}
+
+func (d *SinkDemuxer) Close() {
+	d.once.Do(func() {
+		if d.cancel != nil {
+			d.cancel()
+		}
+
+		d.wg.Wait()
+
+		d.close()
+	})
+}
+
+func (d *SinkDemuxer) loop() {
+	defer d.wg.Done()
+
+	waitForKeyframe := false
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		default:
+			// NOTE: READFRAME MOVES ON TO A REPLACING TRACK BY ITSELF; ITS ERRORS (ErrNoDepacketizer, io.EOF
+			// NOTE: OF AN ENDED TRACK, A DONE CONTEXT) DO NOT GO AWAY BY READING AGAIN
+			frame, err := d.sink.ReadFrame(d.ctx)
+			if err != nil {
+				if d.ctx.Err() == nil {
+					packageLogger.Get().Warn("stopped demuxing sink", "err", err)
+				}
+				return
+			}
+
+			if d.MediaType() == astiav.MediaTypeVideo {
+				if frame.Lost {
+					waitForKeyframe = true
+				}
+				if waitForKeyframe && !frame.Keyframe {
+					continue
+				}
+				waitForKeyframe = false
+			}
+
+			packet := d.buffer.Get()
+			if err := d.fillPacket(packet, frame); err != nil {
+				d.buffer.Put(packet)
+				continue
+			}
+
+			if err := d.pushPacket(packet); err != nil {
+				d.buffer.Put(packet)
+				continue
+			}
+		}
+	}
+}
+
+func (d *SinkDemuxer) fillPacket(packet *astiav.Packet, frame *mediasink.Frame) error {
+	if err := packet.FromData(frame.Data); err != nil {
+		return err
+	}
+
+	pts := int64(frame.PTS.Seconds() * float64(d.clockRate))
+	packet.SetPts(pts)
+	packet.SetDts(pts)
+	packet.SetDuration(int64(frame.Duration.Seconds() * float64(d.clockRate)))
+
+	if frame.Keyframe {
+		packet.SetFlags(packet.Flags().Add(astiav.PacketFlagKey))
+	}
+
+	return nil
+}
+
+func (d *SinkDemuxer) pushPacket(packet *astiav.Packet) error {
+	ctx, cancel := context.WithTimeout(d.ctx, 50*time.Millisecond)
+	defer cancel()
+
+	return d.buffer.Push(ctx, packet)
+}
+
+func (d *SinkDemuxer) GetPacket(ctx context.Context) (*astiav.Packet, error) {
+	return d.buffer.Pop(ctx)
+}
+
+func (d *SinkDemuxer) PutBack(packet *astiav.Packet) {
+	d.buffer.Put(packet)
+}
+
+func (d *SinkDemuxer) close() {
+	if d.codecParameters != nil {
+		d.codecParameters.Free()
+	}
+}
+
+func (d *SinkDemuxer) SetBuffer(buffer buffer.BufferWithGenerator[*astiav.Packet]) {
+	d.buffer = buffer
+}
+
+func (d *SinkDemuxer) GetCodecParameters() *astiav.CodecParameters {
+	return d.codecParameters
+}
+
+func (d *SinkDemuxer) MediaType() astiav.MediaType {
+	return d.codecParameters.MediaType()
+}
+
+func (d *SinkDemuxer) CodecID() astiav.CodecID {
+	return d.codecParameters.CodecID()
+}
+
+// FrameRate is unknown for remote tracks; decoders take it from the bitstream.
+func (d *SinkDemuxer) FrameRate() astiav.Rational {
+	return astiav.NewRational(0, 1)
+}
+
+func (d *SinkDemuxer) TimeBase() astiav.Rational {
+	return astiav.NewRational(1, d.clockRate)
+}
@@ -0,0 +1,385 @@
+//go:build cgo_enabled
+
+package transcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astiav"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/tools/pkg/multierr"
+)
+
+// SinkMuxer records several sinks, e.g. the audio and video of a peer, into one MKV or MP4 file; the
+// format follows the extension of config.Path. Rotation works like it does for mediasink.Recorder;
+// with a video sink, segments start at its keyframes. The header of a segment needs the size of every
+// video stream, so recording starts once every video sink delivered a keyframe carrying it (SPS and PPS
+// for H.264). Every sink is read with ReadFrame, so nothing else should read them while recording.
+// Register the muxer with client.PeerConnection.AddMediaSinkOutput to finalise it when the peer
+// connection closes.
+type SinkMuxer struct {
+	config  mediasink.RecorderConfig
+	streams []*muxerStream
+	// keyStream is the stream whose keyframes segments start at; -1 if there is no video
+	keyStream int
+
+	formatContext *astiav.FormatContext
+	ioContext     *astiav.IOContext
+	segment       int
+	segmentOpened time.Time
+	segmentSize   int64
+	files         []string
+
+	logger *slog.Logger
+	mux    sync.Mutex
+	once   sync.Once
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type muxerStream struct {
+	sink      *mediasink.Sink
+	mimeType  string
+	codecID   astiav.CodecID
+	mediaType astiav.MediaType
+	clockRate int
+	channels  uint16
+	stream    *astiav.Stream
+	// parameters of a video stream, from its last keyframe that carried them
+	parameters *mediasink.VideoParameters
+
+	// the frame PTS of every sink counts from its own first frame; within a segment, a stream starts
+	// at the time its first frame arrived after the segment was opened
+	started bool
+	start   time.Duration
+	offset  time.Duration
+}
+
+// segmentTime is the time of the frame since the segment was opened.
+func (s *muxerStream) segmentTime(frame *mediasink.Frame, opened time.Time) time.Duration {
+	if !s.started {
+		s.started = true
+		s.start = frame.PTS
+		s.offset = time.Since(opened)
+	}
+
+	return frame.PTS - s.start + s.offset
+}
+
+func CreateSinkMuxer(ctx context.Context, config mediasink.RecorderConfig, sinks ...*mediasink.Sink) (*SinkMuxer, error) {
+	if config.Path == "" {
+		return nil, errors.New("muxer needs a path")
+	}
+	if len(sinks) == 0 {
+		return nil, errors.New("muxer needs at least one sink")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory for %s: %w", config.Path, err)
+	}
+
+	ctx2, cancel2 := context.WithCancel(ctx)
+
+	muxer := &SinkMuxer{
+		config:    config,
+		keyStream: -1,
+		logger:    packageLogger.Get().With("recording", config.Path),
+		ctx:       ctx2,
+		cancel:    cancel2,
+	}
+
+	for i, sink := range sinks {
+		codec := sink.Codec()
+
+		codecID, mediaType, err := codecIDFromMimeType(codec.MimeType)
+		if err != nil {
+			cancel2()
+			return nil, err
+		}
+
+		if mediaType == astiav.MediaTypeVideo && muxer.keyStream < 0 {
+			muxer.keyStream = i
+		}
+
+		muxer.streams = append(muxer.streams, &muxerStream{
+			sink:      sink,
+			mimeType:  codec.MimeType,
+			codecID:   codecID,
+			mediaType: mediaType,
+			clockRate: int(codec.ClockRate),
+			channels:  codec.Channels,
+		})
+	}
+
+	return muxer, nil
+}
+
+func (m *SinkMuxer) segmentPath() string {
+	if m.config.MaxDuration <= 0 && m.config.MaxSize <= 0 {
+		return m.config.Path
+	}
+
+	extension := filepath.Ext(m.config.Path)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(m.config.Path, extension), m.segment, extension)
+}
+
+func (m *SinkMuxer) Start() {
+	for index := range m.streams {
+		m.wg.Add(1)
+		go m.loop(index)
+	}
+}
+
+// Close stops the recording and writes the trailer of the current segment.
+func (m *SinkMuxer) Close() error {
+	var err error
+	m.once.Do(func() {
+		m.cancel()
+		m.wg.Wait()
+
+		m.mux.Lock()
+		defer m.mux.Unlock()
+
+		err = m.closeSegment()
+	})
+
+	return err
+}
+
+// Files returns the files written so far, the current segment last.
+func (m *SinkMuxer) Files() []string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	return append([]string(nil), m.files...)
+}
+
+func (m *SinkMuxer) loop(index int) {
+	defer m.wg.Done()
+
+	stream := m.streams[index]
+	for {
+		frame, err := stream.sink.ReadFrame(m.ctx)
+		if err != nil {
+			if m.ctx.Err() == nil {
+				m.logger.Warn("stopped recording stream", "stream", index, "err", err)
+			}
+			return
+		}
+
+		if err := m.write(index, frame); err != nil {
+			m.logger.Warn("failed to record frame", "stream", index, "err", err)
+		}
+	}
+}
+
+func (m *SinkMuxer) openSegment() error {
+	path := m.segmentPath()
+
+	formatContext, err := astiav.AllocOutputFormatContext(nil, "", path)
+	if err != nil {
+		return fmt.Errorf("error allocating output format context for %s: %w", path, err)
+	}
+	if formatContext == nil {
+		return ErrorAllocateFormatContext
+	}
+
+	for _, s := range m.streams {
+		stream := formatContext.NewStream(nil)
+		if stream == nil {
+			formatContext.Free()
+			return fmt.Errorf("error adding stream to %s (%w)", path, ErrorGeneralAllocate)
+		}
+
+		if err := s.setParameters(stream.CodecParameters()); err != nil {
+			formatContext.Free()
+			return fmt.Errorf("error setting stream parameters of %s: %w", path, err)
+		}
+		stream.SetTimeBase(astiav.NewRational(1, s.clockRate))
+		s.stream = stream
+	}
+
+	if !formatContext.OutputFormat().Flags().Has(astiav.IOFormatFlagNofile) {
+		ioContext, err := astiav.OpenIOContext(path, astiav.NewIOContextFlags(astiav.IOContextFlagWrite), nil, nil)
+		if err != nil {
+			formatContext.Free()
+			return fmt.Errorf("error opening %s: %w", path, err)
+		}
+		formatContext.SetPb(ioContext)
+		m.ioContext = ioContext
+	}
+
+	if err := formatContext.WriteHeader(nil); err != nil {
+		if m.ioContext != nil {
+			_ = m.ioContext.Close()
+			m.ioContext = nil
+		}
+		formatContext.Free()
+		return fmt.Errorf("error writing header of %s: %w", path, err)
+	}
+
+	m.formatContext = formatContext
+	m.segmentOpened = time.Now()
+	m.segmentSize = 0
+	for _, s := range m.streams {
+		s.started = false
+	}
+	m.files = append(m.files, path)
+	m.logger.Info("recording segment", "file", path)
+
+	return nil
+}
+
+func (s *muxerStream) setParameters(parameters *astiav.CodecParameters) error {
+	parameters.SetCodecID(s.codecID)
+	parameters.SetMediaType(s.mediaType)
+
+	var extradata []byte
+	switch s.mediaType {
+	case astiav.MediaTypeVideo:
+		parameters.SetWidth(s.parameters.Width)
+		parameters.SetHeight(s.parameters.Height)
+		extradata = s.parameters.Extradata
+	case astiav.MediaTypeAudio:
+		parameters.SetSampleRate(s.clockRate)
+		parameters.SetChannelLayout(astiav.ChannelLayoutStereo)
+		if s.channels == 1 {
+			parameters.SetChannelLayout(astiav.ChannelLayoutMono)
+		}
+		if s.codecID == astiav.CodecIDOpus {
+			channels := s.channels
+			if channels == 0 {
+				channels = 2
+			}
+			extradata = mediasink.OpusHead(channels)
+		}
+	}
+
+	if len(extradata) == 0 {
+		return nil
+	}
+
+	return parameters.SetExtraData(extradata)
+}
+
+// updateParameters keeps the parameters of the last keyframe of a video stream that carried them.
+func (s *muxerStream) updateParameters(frame *mediasink.Frame) error {
+	if s.mediaType != astiav.MediaTypeVideo || !frame.Keyframe {
+		return nil
+	}
+
+	parameters, err := mediasink.ParseVideoParameters(s.mimeType, frame.Data)
+	if errors.Is(err, mediasink.ErrNoVideoParameters) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.parameters = &parameters
+	return nil
+}
+
+// configured reports whether the parameters of every video stream are known.
+func (m *SinkMuxer) configured() bool {
+	for _, s := range m.streams {
+		if s.mediaType == astiav.MediaTypeVideo && s.parameters == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *SinkMuxer) closeSegment() error {
+	if m.formatContext == nil {
+		return nil
+	}
+
+	var merr error
+	if err := m.formatContext.WriteTrailer(); err != nil {
+		merr = multierr.Append(merr, err)
+	}
+	if m.ioContext != nil {
+		if err := m.ioContext.Close(); err != nil {
+			merr = multierr.Append(merr, err)
+		}
+		m.ioContext = nil
+	}
+	m.formatContext.Free()
+	m.formatContext = nil
+
+	return merr
+}
+
+func (m *SinkMuxer) write(index int, frame *mediasink.Frame) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	s := m.streams[index]
+	if err := s.updateParameters(frame); err != nil {
+		m.logger.Debug("failed to read video parameters of keyframe", "stream", index, "err", err)
+	}
+
+	startsSegment := m.keyStream < 0 || (index == m.keyStream && frame.Keyframe)
+
+	if m.formatContext != nil && startsSegment && m.shouldRotate(s.segmentTime(frame, m.segmentOpened)) {
+		if err := m.closeSegment(); err != nil {
+			m.logger.Warn("failed to finalise segment", "err", err)
+		}
+		m.segment++
+	}
+
+	if m.formatContext == nil {
+		// NOTE: A SEGMENT THAT DOES NOT START WITH A KEYFRAME CANNOT BE DECODED; OTHER STREAMS WAIT FOR IT
+		if !startsSegment || !m.configured() {
+			return nil
+		}
+		if err := m.openSegment(); err != nil {
+			return err
+		}
+	}
+
+	packet := astiav.AllocPacket()
+	if packet == nil {
+		return fmt.Errorf("error allocating packet (%w)", ErrorGeneralAllocate)
+	}
+	defer packet.Free()
+
+	if err := packet.FromData(frame.Data); err != nil {
+		return err
+	}
+
+	pts := int64(s.segmentTime(frame, m.segmentOpened).Seconds() * float64(s.clockRate))
+	packet.SetPts(pts)
+	packet.SetDts(pts)
+	packet.SetDuration(int64(frame.Duration.Seconds() * float64(s.clockRate)))
+	packet.SetStreamIndex(s.stream.Index())
+	if frame.Keyframe {
+		packet.SetFlags(packet.Flags().Add(astiav.PacketFlagKey))
+	}
+	// the muxer may have changed the time base of the stream while writing the header
+	packet.RescaleTs(astiav.NewRational(1, s.clockRate), s.stream.TimeBase())
+
+	m.segmentSize += int64(len(frame.Data))
+
+	return m.formatContext.WriteInterleavedFrame(packet)
+}
+
+func (m *SinkMuxer) shouldRotate(elapsed time.Duration) bool {
+	if m.config.MaxDuration > 0 && elapsed >= m.config.MaxDuration {
+		return true
+	}
+
+	return m.config.MaxSize > 0 && m.segmentSize >= m.config.MaxSize
+}
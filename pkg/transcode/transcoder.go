@@ -101,6 +101,25 @@ func (t *Transcoder) AdaptBitrate(bps int64) error {
 	return ErrorInterfaceMismatch
 }
 
+// TimeBase is the time base of the packets of the encoder, or 0/1 if the encoder cannot describe it.
+func (t *Transcoder) TimeBase() astiav.Rational {
+	d, ok := t.encoder.(CanDescribeTimeBase)
+	if ok {
+		return d.TimeBase()
+	}
+
+	return astiav.NewRational(0, 1)
+}
+
+func (t *Transcoder) ForceKeyframe() error {
+	f, ok := t.encoder.(CanForceKeyframe)
+	if ok {
+		return f.ForceKeyframe()
+	}
+
+	return ErrorInterfaceMismatch
+}
+
 func (t *Transcoder) GetCurrentBitrate() (int64, error) {
 	e, ok := t.encoder.(CanGetCurrentBitrate)
 	if ok {
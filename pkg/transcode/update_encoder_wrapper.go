@@ -164,6 +164,34 @@ func (u *UpdateEncoder) AdaptBitrate(bps int64) error {
 	return nil
 }
 
+// TimeBase is the time base of the packets of the current encoder, or 0/1 if it cannot describe it.
+// Rebuilt encoders keep the time base.
+func (u *UpdateEncoder) TimeBase() astiav.Rational {
+	u.cond.L.Lock()
+	defer u.cond.L.Unlock()
+
+	d, ok := u.encoder.(CanDescribeTimeBase)
+	if !ok {
+		return astiav.NewRational(0, 1)
+	}
+
+	return d.TimeBase()
+}
+
+// ForceKeyframe forces a keyframe on the current encoder. An encoder rebuilt by AdaptBitrate starts with
+// a keyframe anyway.
+func (u *UpdateEncoder) ForceKeyframe() error {
+	u.cond.L.Lock()
+	defer u.cond.L.Unlock()
+
+	f, ok := u.encoder.(CanForceKeyframe)
+	if !ok {
+		return ErrorInterfaceMismatch
+	}
+
+	return f.ForceKeyframe()
+}
+
 func (u *UpdateEncoder) cutoff(bps int64) int64 {
 	if bps > u.config.MaxBitrate {
 		bps = u.config.MaxBitrate
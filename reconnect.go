@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+type ReconnectKind string
+
+const (
+	ReconnectICERestart ReconnectKind = "ice-restart"
+	ReconnectRecreate   ReconnectKind = "recreate"
+)
+
+type ReconnectConfig struct {
+	// InitialBackoff is waited before the first attempt; it is multiplied by Multiplier after each failed
+	// attempt, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+
+	// ICERestartAttempts is the number of ICE restarts tried before falling back to recreating the
+	// peer connection.
+	ICERestartAttempts int
+
+	// MaxAttempts bounds the attempts per outage; 0 retries until the peer connection is closed.
+	MaxAttempts int
+
+	// DisconnectedTimeout is how long the 'disconnected' state is tolerated before reconnecting, as it
+	// often recovers on its own. 'failed' is acted upon immediately.
+	DisconnectedTimeout time.Duration
+
+	// AttemptTimeout bounds an attempt, signaling included, till the 'connected' state is reached. The
+	// signaling of a signal which is not a ContextSignal cannot be stopped and is left running.
+	AttemptTimeout time.Duration
+}
+
+var DefaultReconnectConfig = ReconnectConfig{
+	InitialBackoff:      500 * time.Millisecond,
+	MaxBackoff:          30 * time.Second,
+	Multiplier:          2,
+	ICERestartAttempts:  2,
+	MaxAttempts:         0,
+	DisconnectedTimeout: 5 * time.Second,
+	AttemptTimeout:      15 * time.Second,
+}
+
+func (c ReconnectConfig) validate() error {
+	if c.InitialBackoff <= 0 || c.MaxBackoff < c.InitialBackoff {
+		return errors.New("reconnect config: backoff needs to be positive and max backoff not less than initial backoff")
+	}
+
+	if c.Multiplier < 1 {
+		return errors.New("reconnect config: multiplier cannot be less than 1")
+	}
+
+	if c.ICERestartAttempts < 0 || c.MaxAttempts < 0 {
+		return errors.New("reconnect config: attempts cannot be negative")
+	}
+
+	if c.AttemptTimeout <= 0 {
+		return errors.New("reconnect config: attempt timeout needs to be positive")
+	}
+
+	return nil
+}
+
+// ReconnectEvent is reported once for every reconnect attempt. Err is nil when the attempt brought the
+// peer connection back to 'connected'.
+type ReconnectEvent struct {
+	Label   string
+	Attempt int
+	Kind    ReconnectKind
+	Backoff time.Duration
+	Err     error
+}
+
+type OnReconnectEvent = func(ReconnectEvent)
+
+type supervisor struct {
+	client  *Client
+	pc      *PeerConnection
+	config  ReconnectConfig
+	onEvent OnReconnectEvent
+}
+
+func newSupervisor(client *Client, pc *PeerConnection, config ReconnectConfig, onEvent OnReconnectEvent) *supervisor {
+	return &supervisor{
+		client:  client,
+		pc:      pc,
+		config:  config,
+		onEvent: onEvent,
+	}
+}
+
+func (s *supervisor) loop() {
+	for {
+		if err := s.pc.WaitTill(s.pc.ctx, func(state webrtc.PeerConnectionState, _ webrtc.ICEConnectionState) bool {
+			return state != webrtc.PeerConnectionStateFailed && state != webrtc.PeerConnectionStateDisconnected
+		}); err != nil {
+			return
+		}
+
+		if s.pc.GetState() == webrtc.PeerConnectionStateDisconnected && s.recovers() {
+			continue
+		}
+
+		if err := s.reconnect(); err != nil {
+			s.pc.logger.Error("reconnect supervisor gave up", "err", err)
+			return
+		}
+	}
+}
+
+// recovers reports whether the peer connection leaves the 'disconnected' state for a healthy one
+// within DisconnectedTimeout.
+func (s *supervisor) recovers() bool {
+	ctx, cancel := context.WithTimeout(s.pc.ctx, s.config.DisconnectedTimeout)
+	defer cancel()
+
+	if err := s.pc.WaitTill(ctx, func(state webrtc.PeerConnectionState, _ webrtc.ICEConnectionState) bool {
+		return state == webrtc.PeerConnectionStateDisconnected
+	}); err != nil {
+		return false
+	}
+
+	return s.pc.GetState() == webrtc.PeerConnectionStateConnected
+}
+
+func (s *supervisor) reconnect() error {
+	backoff := s.config.InitialBackoff
+
+	for attempt := 1; s.config.MaxAttempts == 0 || attempt <= s.config.MaxAttempts; attempt++ {
+		select {
+		case <-s.pc.ctx.Done():
+			return s.pc.ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		kind := ReconnectICERestart
+		if attempt > s.config.ICERestartAttempts {
+			kind = ReconnectRecreate
+		}
+
+		err := s.attempt(kind)
+		if s.onEvent != nil {
+			s.onEvent(ReconnectEvent{
+				Label:   s.pc.label,
+				Attempt: attempt,
+				Kind:    kind,
+				Backoff: backoff,
+				Err:     err,
+			})
+		}
+
+		if err == nil {
+			return nil
+		}
+
+		backoff = time.Duration(float64(backoff) * s.config.Multiplier)
+		if backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+
+	return fmt.Errorf("no successful reconnect after %d attempts", s.config.MaxAttempts)
+}
+
+func (s *supervisor) attempt(kind ReconnectKind) error {
+	ctx, cancel := context.WithTimeout(s.pc.ctx, s.config.AttemptTimeout)
+	defer cancel()
+
+	switch kind {
+	case ReconnectICERestart:
+		if err := s.pc.restartICE(ctx); err != nil {
+			return err
+		}
+	case ReconnectRecreate:
+		if err := s.client.recreatePeerConnection(s.pc); err != nil {
+			return err
+		}
+
+		category, signal := s.pc.getSignal()
+		if err := connectSignal(ctx, signal, category, s.pc); err != nil {
+			return err
+		}
+		s.pc.markNegotiated()
+	}
+
+	return s.pc.WaitTill(ctx, func(state webrtc.PeerConnectionState, _ webrtc.ICEConnectionState) bool {
+		return state != webrtc.PeerConnectionStateConnected
+	})
+}
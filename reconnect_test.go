@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/ice/v4"
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/datachannel"
+)
+
+// loopbackSettings gathers host candidates on the loopback interface only and notices a lost peer within
+// a second.
+func loopbackSettings() *webrtc.SettingEngine {
+	settings := &webrtc.SettingEngine{}
+	settings.SetIncludeLoopbackCandidate(true)
+	settings.SetIPFilter(func(ip net.IP) bool {
+		return ip.IsLoopback()
+	})
+	settings.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeUDP4})
+	settings.SetICEMulticastDNSMode(ice.MulticastDNSModeDisabled)
+	settings.SetICETimeouts(500*time.Millisecond, time.Second, 100*time.Millisecond)
+
+	return settings
+}
+
+// connectSignals connects both clients, each over its side of the signal, like a deployment would.
+func connectSignals(t *testing.T, offer *Client, answer *Client, offerSignal BaseSignal, answerSignal BaseSignal) {
+	t.Helper()
+
+	answered := make(chan error, 1)
+	go func() {
+		answered <- answer.Connect("reconnect", answerSignal)
+	}()
+
+	if err := offer.Connect("reconnect", offerSignal); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-answered; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSupervisorEscalatesFromICERestartToRecreate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	events := make(chan ReconnectEvent, 16)
+	config := ReconnectConfig{
+		InitialBackoff:      100 * time.Millisecond,
+		MaxBackoff:          100 * time.Millisecond,
+		Multiplier:          1,
+		ICERestartAttempts:  1,
+		MaxAttempts:         2,
+		DisconnectedTimeout: 100 * time.Millisecond,
+		AttemptTimeout:      2 * time.Second,
+	}
+
+	offer, err := NewClient(ctx, nil, nil, loopbackSettings(), WithReconnectSupervisor(config, func(event ReconnectEvent) {
+		events <- event
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offer.Close()
+
+	answer, err := NewClient(ctx, nil, nil, loopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer answer.Close()
+
+	offerPC, err := offer.CreatePeerConnection("pc", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	answerPC, err := answer.CreatePeerConnection("pc", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	negotiated := datachannel.WithDataChannelInit(&webrtc.DataChannelInit{Negotiated: &datachannel.NegotiatedTrue, ID: &datachannel.IDOne})
+	if _, err := offerPC.CreateDataChannel("control", negotiated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := answerPC.CreateDataChannel("control", negotiated); err != nil {
+		t.Fatal(err)
+	}
+
+	offerSignal, answerSignal := NewLoopbackSignals(ctx)
+	defer offerSignal.Close()
+	defer answerSignal.Close()
+
+	connectSignals(t, offer, answer, offerSignal, answerSignal)
+
+	// NOTE: THE ANSWERING SIDE STAYS IN THE ROOM BUT CANNOT ANSWER, SO THE ICE RESTART NEVER COMPLETES SIGNALING
+	if err := answerPC.GetPeerConnection().Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var event ReconnectEvent
+	select {
+	case event = <-events:
+	case <-ctx.Done():
+		t.Fatal("no reconnect attempt after the remote peer connection closed")
+	}
+	if event.Kind != ReconnectICERestart || event.Err == nil {
+		t.Fatalf("expected a failed ICE restart, got %+v", event)
+	}
+
+	if err := answer.recreatePeerConnection(answerPC); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		_ = answer.Connect("reconnect", answerSignal)
+	}()
+
+	select {
+	case event = <-events:
+	case <-ctx.Done():
+		t.Fatal("no recreate attempt after the ICE restart failed")
+	}
+	if event.Kind != ReconnectRecreate || event.Err != nil {
+		t.Fatalf("expected a successful recreate, got %+v", event)
+	}
+
+	if state := offerPC.GetState(); state != webrtc.PeerConnectionStateConnected {
+		t.Fatalf("expected the recreated peer connection to be connected, got %s", state)
+	}
+}
+
+func TestICERestartStopsWithItsContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	offer, err := NewClient(ctx, nil, nil, loopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offer.Close()
+
+	answer, err := NewClient(ctx, nil, nil, loopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer answer.Close()
+
+	negotiated := datachannel.WithDataChannelInit(&webrtc.DataChannelInit{Negotiated: &datachannel.NegotiatedTrue, ID: &datachannel.IDOne})
+	for _, client := range []*Client{offer, answer} {
+		pc, err := client.CreatePeerConnection("pc", webrtc.Configuration{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pc.CreateDataChannel("control", negotiated); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	offerSignal, answerSignal := NewLoopbackSignals(ctx)
+	defer offerSignal.Close()
+	defer answerSignal.Close()
+
+	connectSignals(t, offer, answer, offerSignal, answerSignal)
+
+	answerPC, err := answer.GetPeerConnection("pc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: THE OFFERING SIDE NEVER RESTARTS, SO NO OFFER ARRIVES
+	attempt, cancelAttempt := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancelAttempt()
+
+	restarted := make(chan error, 1)
+	go func() {
+		restarted <- answerPC.restartICE(attempt)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if !answerPC.tryLockNegotiation() {
+		t.Fatal("the negotiation lock is held while waiting for the restart offer")
+	}
+	answerPC.unlockNegotiation()
+
+	select {
+	case err := <-restarted:
+		if err == nil {
+			t.Fatal("expected the restart to fail without an offer")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the restart kept running after its context was done")
+	}
+
+	if _, err := answerSignal.sessions.get("reconnect", "pc"); err == nil {
+		t.Fatal("expected the session of the failed restart to be removed")
+	}
+}
@@ -0,0 +1,289 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+// connectedMediaPair connects a peer connection of two clients with a source of the offering side and a
+// sink of the answering side for every label of toAnswer, and the other way around for toOffer.
+func connectedMediaPair(ctx context.Context, t *testing.T, toAnswer []string, toOffer []string) (*PeerConnection, *PeerConnection) {
+	t.Helper()
+
+	offer, err := NewClient(ctx, nil, nil, loopbackSettings(), WithDefaultMediaEngine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(offer.Close)
+
+	answer, err := NewClient(ctx, nil, nil, loopbackSettings(), WithDefaultMediaEngine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(answer.Close)
+
+	offerPC, err := offer.CreatePeerConnection("media", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	answerPC, err := answer.CreatePeerConnection("media", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, direction := range []struct {
+		labels []string
+		from   *PeerConnection
+		to     *PeerConnection
+	}{{toAnswer, offerPC, answerPC}, {toOffer, answerPC, offerPC}} {
+		for _, label := range direction.labels {
+			if _, err := direction.from.CreateMediaSource(label, mediasource.WithVP8Track(90000)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := direction.to.CreateMediaSink(label, mediasink.WithVP8Track(90000)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	offerSignal, answerSignal := NewLoopbackSignals(ctx)
+	t.Cleanup(func() {
+		_ = offerSignal.Close()
+		_ = answerSignal.Close()
+	})
+
+	connectSignals(t, offer, answer, offerSignal, answerSignal)
+
+	for _, pc := range []*PeerConnection{offerPC, answerPC} {
+		if err := pc.WaitTill(ctx, func(state webrtc.PeerConnectionState, _ webrtc.ICEConnectionState) bool {
+			return state != webrtc.PeerConnectionStateConnected
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return offerPC, answerPC
+}
+
+// assertMediaFlows writes samples to the source of label on from till its sink on to reads a packet.
+func assertMediaFlows(ctx context.Context, t *testing.T, from *PeerConnection, to *PeerConnection, label string) {
+	t.Helper()
+
+	source, err := from.GetMediaSource(label)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := to.GetMediaSink(label)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				_ = source.WriteSample(media.Sample{Data: []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a}, Duration: 20 * time.Millisecond})
+			}
+		}
+	}()
+
+	if _, _, err := sink.ReadRTP(ctx); err != nil {
+		t.Fatalf("expected media of %s to flow; err: %v", label, err)
+	}
+}
+
+// transceiverMid returns the mid of the transceiver of pc which matches.
+func transceiverMid(t *testing.T, pc *PeerConnection, match func(*webrtc.RTPTransceiver) bool) string {
+	t.Helper()
+
+	for _, transceiver := range pc.GetPeerConnection().GetTransceivers() {
+		if match(transceiver) {
+			return transceiver.Mid()
+		}
+	}
+
+	t.Fatalf("no matching transceiver (pc=%s)", pc.GetLabel())
+	return ""
+}
+
+// awaitInactive waits till the answer each side applied last has the media section of mid rejected or
+// inactive.
+func awaitInactive(ctx context.Context, t *testing.T, offer *PeerConnection, answer *PeerConnection, mid string) {
+	t.Helper()
+
+	inactive := func(description *webrtc.SessionDescription) bool {
+		if description == nil {
+			return false
+		}
+		// NOTE: UNMARSHAL CACHES THE PARSED SDP IN THE DESCRIPTION, WHICH PION SHARES; A COPY IS PARSED INSTEAD
+		parsed, err := (&webrtc.SessionDescription{Type: description.Type, SDP: description.SDP}).Unmarshal()
+		if err != nil {
+			return false
+		}
+
+		for _, section := range parsed.MediaDescriptions {
+			if value, _ := section.Attribute("mid"); value != mid {
+				continue
+			}
+			_, isInactive := section.Attribute(webrtc.RTPTransceiverDirectionInactive.String())
+			return section.MediaName.Port.Value == 0 || isInactive
+		}
+
+		return false
+	}
+
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !inactive(offer.GetPeerConnection().CurrentRemoteDescription()) || !inactive(answer.GetPeerConnection().CurrentLocalDescription()) {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("expected the media section %s to be inactive on both sides", mid)
+		case <-ticker.C:
+		}
+	}
+}
+
+func TestRemoveMediaAfterConnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	offer, answer := connectedMediaPair(ctx, t, []string{"video", "screen", "slides"}, []string{"camera"})
+
+	for _, label := range []string{"video", "screen", "slides"} {
+		assertMediaFlows(ctx, t, offer, answer, label)
+	}
+	assertMediaFlows(ctx, t, answer, offer, "camera")
+
+	sourceMid := func(label string) string {
+		return transceiverMid(t, offer, func(transceiver *webrtc.RTPTransceiver) bool {
+			return transceiver.Sender() != nil && transceiver.Sender().Track() != nil && transceiver.Sender().Track().ID() == label
+		})
+	}
+	camera := transceiverMid(t, offer, func(transceiver *webrtc.RTPTransceiver) bool {
+		return transceiver.Receiver() != nil && transceiver.Receiver().Track() != nil && transceiver.Receiver().Track().ID() == "camera"
+	})
+
+	// NOTE: THE TRACK OF THE ANSWERING SIDE ARRIVES ON A TRANSCEIVER OF ONE OF THE SOURCES
+	var shared, removed, remaining string
+	for _, label := range []string{"video", "screen", "slides"} {
+		switch {
+		case sourceMid(label) == camera:
+			shared = label
+		case removed == "":
+			removed = label
+		default:
+			remaining = label
+		}
+	}
+	if shared == "" {
+		t.Fatal("expected the camera to share the transceiver of a source")
+	}
+
+	// NOTE: THE ANSWERING SIDE CANNOT OFFER THE REMOVAL, SO IT KEEPS ITS SINK
+	if err := answer.RemoveMediaSink(remaining); !errors.Is(err, ErrRenegotiationNotOfferer) {
+		t.Fatalf("expected the answering side to refuse removing the sink, got %v", err)
+	}
+	if _, err := answer.GetMediaSink(remaining); err != nil {
+		t.Fatal("expected the refused sink to be kept")
+	}
+
+	mid := sourceMid(removed)
+	if err := offer.RemoveMediaSource(removed); err != nil {
+		t.Fatal(err)
+	}
+	awaitInactive(ctx, t, offer, answer, mid)
+
+	// NOTE: THE TRANSCEIVER OF THE CAMERA KEEPS RECEIVING ONCE IT SENDS NOTHING, AND STOPS WITH THE SINK
+	if err := offer.RemoveMediaSource(shared); err != nil {
+		t.Fatal(err)
+	}
+	assertMediaFlows(ctx, t, answer, offer, "camera")
+
+	if err := offer.RemoveMediaSink("camera"); err != nil {
+		t.Fatal(err)
+	}
+	awaitInactive(ctx, t, offer, answer, camera)
+
+	assertMediaFlows(ctx, t, offer, answer, remaining)
+}
+
+func TestRemoveMediaSinkKeepsTransceiverOfSource(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	offer, answer := connectedMediaPair(ctx, t, []string{"video"}, []string{"camera"})
+	assertMediaFlows(ctx, t, answer, offer, "camera")
+
+	if err := offer.RemoveMediaSink("camera"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := offer.GetMediaSink("camera"); err == nil {
+		t.Fatal("expected the sink to be forgotten")
+	}
+
+	// NOTE: THE VIDEO IS SENT ON THE TRANSCEIVER THE CAMERA ARRIVED ON
+	assertMediaFlows(ctx, t, offer, answer, "video")
+}
+
+func TestFailedRenegotiationIsRolledBack(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	offer, answer := connectedMediaPair(ctx, t, []string{"video"}, nil)
+	assertMediaFlows(ctx, t, offer, answer, "video")
+
+	// NOTE: WHILE THE ANSWERING SIDE NEGOTIATES, IT DROPS THE OFFER, SO THE OFFERING SIDE NEVER GETS AN ANSWER
+	if err := answer.lockNegotiation(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	renegotiateCtx, renegotiateCancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer renegotiateCancel()
+
+	if err := offer.Renegotiate(renegotiateCtx); err == nil {
+		t.Fatal("expected the renegotiation without an answer to fail")
+	}
+	answer.unlockNegotiation()
+
+	if state := offer.GetPeerConnection().SignalingState(); state != webrtc.SignalingStateStable {
+		t.Fatalf("expected the pending local offer to be rolled back, got %s", state)
+	}
+	assertMediaFlows(ctx, t, offer, answer, "video")
+
+	// NOTE: A REMOTE OFFER THAT IS NEVER ANSWERED IS ROLLED BACK AS WELL
+	pending, err := offer.GetPeerConnection().CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := answer.GetPeerConnection().SetRemoteDescription(pending); err != nil {
+		t.Fatal(err)
+	}
+	answer.rollback()
+
+	if state := answer.GetPeerConnection().SignalingState(); state != webrtc.SignalingStateStable {
+		t.Fatalf("expected the pending remote offer to be rolled back, got %s", state)
+	}
+
+	// NOTE: THE SESSION KEEPS RENEGOTIATING AFTER BOTH ROLLBACKS
+	if err := offer.Renegotiate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertMediaFlows(ctx, t, offer, answer, "video")
+}
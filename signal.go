@@ -1,16 +1,28 @@
 package client
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pion/webrtc/v4"
+)
 
 const (
-	FieldOffer           = "offer"
-	FieldAnswer          = "answer"
-	FieldSDP             = "sdp"
-	FieldUpdatedAt       = "updated-at"
-	FieldStatus          = "status"
-	FieldStatusPending   = "pending"
-	FieldStatusConnected = "connected"
-	FieldCreatedAt       = "created-at"
+	FieldOffer            = "offer"
+	FieldAnswer           = "answer"
+	FieldSDP              = "sdp"
+	FieldUpdatedAt        = "updated-at"
+	FieldStatus           = "status"
+	FieldStatusPending    = "pending"
+	FieldStatusConnected  = "connected"
+	FieldCreatedAt        = "created-at"
+	FieldOfferCandidates  = "offer-candidates"
+	FieldAnswerCandidates = "answer-candidates"
+	FieldCandidate        = "candidate"
+	FieldSDPMid           = "sdp-mid"
+	FieldSDPMLineIndex    = "sdp-mline-index"
+	FieldUsernameFragment = "username-fragment"
 )
 
 type (
@@ -18,8 +30,136 @@ type (
 		Connect(string, *PeerConnection) error
 		Close() error
 	}
-	ForOffer  func(ctx context.Context) (string, error)
-	OnAnswer  func(ctx context.Context, sdp string) error
-	OnOffer   func(ctx context.Context, sdp string) error
-	ForAnswer func(ctx context.Context) (string, error)
+	// RenegotiationSignal is implemented by signals that can run further offer/answer exchanges on an
+	// already connected PeerConnection, e.g. after media sources or sinks were added or removed.
+	RenegotiationSignal interface {
+		BaseSignal
+		Renegotiate(context.Context, *PeerConnection) error
+	}
+	// ContextSignal is implemented by signals whose Connect can be bounded by a context; the exchange stops
+	// once either the context or the signal is done. The reconnect supervisor uses it to end attempts
+	// that time out.
+	ContextSignal interface {
+		BaseSignal
+		ConnectContext(context.Context, string, *PeerConnection) error
+	}
+	ForOffer     func(ctx context.Context) (string, error)
+	OnAnswer     func(ctx context.Context, sdp string) error
+	OnOffer      func(ctx context.Context, sdp string) error
+	ForAnswer    func(ctx context.Context) (string, error)
+	OnCandidate  func(ctx context.Context, candidate webrtc.ICECandidateInit) error
+	ForCandidate func(ctx context.Context) (webrtc.ICECandidateInit, error)
 )
+
+type signalConfig struct {
+	trickle      bool
+	onCandidate  OnCandidate
+	forCandidate ForCandidate
+	encoding     FileEncoding
+}
+
+type SignalOption = func(*signalConfig)
+
+// WithTrickleICE makes the signal send its SDP as soon as the local description is set and exchange
+// ICE candidates as they are gathered, instead of waiting for webrtc.GatheringCompletePromise.
+func WithTrickleICE() SignalOption {
+	return func(config *signalConfig) {
+		config.trickle = true
+	}
+}
+
+// WithCandidateCallbacks enables trickle ICE on the generic signals. onCandidate is called for every local
+// candidate and forCandidate is polled for remote candidates until it returns an error. The context given
+// to both is done once the session is replaced by the next Connect of the peer connection, or the signal
+// is closed.
+func WithCandidateCallbacks(onCandidate OnCandidate, forCandidate ForCandidate) SignalOption {
+	return func(config *signalConfig) {
+		config.trickle = true
+		config.onCandidate = onCandidate
+		config.forCandidate = forCandidate
+	}
+}
+
+// connectSignal connects pc over signal till ctx is done. A signal which is not a ContextSignal cannot be
+// stopped; its Connect is left running once ctx is done.
+func connectSignal(ctx context.Context, signal BaseSignal, category string, pc *PeerConnection) error {
+	if signal, ok := signal.(ContextSignal); ok {
+		return signal.ConnectContext(ctx, category, pc)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- signal.Connect(category, pc)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("signaling did not complete (label=%s); err: %w", pc.label, ctx.Err())
+	case err := <-done:
+		return err
+	}
+}
+
+// exchangeContext returns the context of one exchange of a signal, done once either ctx or the context
+// of the signal is done.
+func exchangeContext(ctx context.Context, signalCtx context.Context) (context.Context, context.CancelFunc) {
+	ctx2, cancel2 := context.WithCancel(ctx)
+	stop := context.AfterFunc(signalCtx, cancel2)
+
+	return ctx2, func() {
+		stop()
+		cancel2()
+	}
+}
+
+func newSignalConfig(options ...SignalOption) signalConfig {
+	config := signalConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+
+	return config
+}
+
+// candidateSessions tracks the candidate goroutines of the sessions of a signal, one session per peer
+// connection, so that connecting a peer connection again does not leave the candidate receiver of its
+// previous session behind.
+type candidateSessions struct {
+	cancels map[string]context.CancelFunc
+	mux     sync.Mutex
+}
+
+// start returns the context of a new session of pc, ending its previous one. The context is also done
+// once the underlying connection of pc is recreated or closed, or when the sessions are closed.
+func (s *candidateSessions) start(ctx context.Context, category string, pc *PeerConnection) context.Context {
+	ctx2, cancel2 := context.WithCancel(ctx)
+	stop := context.AfterFunc(pc.connectionContext(), cancel2)
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.cancels == nil {
+		s.cancels = make(map[string]context.CancelFunc)
+	}
+
+	key := category + "/" + pc.label
+	if cancel, exists := s.cancels[key]; exists {
+		cancel()
+	}
+	s.cancels[key] = func() {
+		stop()
+		cancel2()
+	}
+
+	return ctx2
+}
+
+func (s *candidateSessions) close() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for key, cancel := range s.cancels {
+		cancel()
+		delete(s.cancels, key)
+	}
+}
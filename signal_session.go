@@ -0,0 +1,335 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/tools/pkg/multierr"
+)
+
+// messageTransport carries WebSocketMessage envelopes between the two sides of a messageSession.
+type messageTransport interface {
+	write(ctx context.Context, msg WebSocketMessage) error
+	read(ctx context.Context) (WebSocketMessage, error)
+	close() error
+}
+
+// messageSession runs offer/answer exchanges and trickles candidates over a messageTransport. It is
+// shared by the websocket and loopback signals.
+type messageSession struct {
+	transport messageTransport
+	pc        *PeerConnection
+	role      string
+	category  string
+	trickle   bool
+	messages  chan WebSocketMessage
+
+	// established is set once the first offer/answer exchange completed; from then on offers from the
+	// remote are renegotiations and are answered by the session itself
+	established atomic.Bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newMessageSession(ctx context.Context, transport messageTransport, role, category string, trickle bool, pc *PeerConnection) *messageSession {
+	ctx2, cancel2 := context.WithCancel(ctx)
+
+	session := &messageSession{
+		transport: transport,
+		pc:        pc,
+		role:      role,
+		category:  category,
+		trickle:   trickle,
+		messages:  make(chan WebSocketMessage, 8),
+		ctx:       ctx2,
+		cancel:    cancel2,
+	}
+
+	go session.readLoop()
+
+	return session
+}
+
+func (s *messageSession) send(ctx context.Context, msg WebSocketMessage) error {
+	msg.Category = s.category
+	msg.Label = s.pc.label
+
+	if err := s.transport.write(ctx, msg); err != nil {
+		return fmt.Errorf("error while writing '%s' message (label=%s); err: %w", msg.Type, s.pc.label, err)
+	}
+
+	return nil
+}
+
+// sendCandidate returns a local candidate handler which trickles candidates over this session.
+func (s *messageSession) sendCandidate() func(webrtc.ICECandidateInit) {
+	return func(candidate webrtc.ICECandidateInit) {
+		if err := s.send(s.ctx, WebSocketMessage{Type: WebSocketMessageCandidate, Candidate: &candidate}); err != nil {
+			s.pc.logger.Warn("error while sending local candidate", "err", err)
+		}
+	}
+}
+
+// readLoop applies remote candidates as they arrive and hands every other message to receive.
+func (s *messageSession) readLoop() {
+	defer close(s.messages)
+
+	for {
+		msg, err := s.transport.read(s.ctx)
+		if err != nil {
+			return
+		}
+
+		if msg.Type == WebSocketMessageCandidate {
+			if msg.Candidate == nil {
+				continue
+			}
+			if err := s.pc.addRemoteCandidate(*msg.Candidate); err != nil {
+				s.pc.logger.Warn("error while adding remote candidate", "err", err)
+			}
+			continue
+		}
+
+		if msg.Type == WebSocketMessageOffer && s.established.Load() {
+			go s.answerRenegotiation(msg.SDP)
+			continue
+		}
+
+		select {
+		case s.messages <- msg:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// offer runs one offer/answer exchange with this session as the offering side.
+func (s *messageSession) offer(ctx context.Context) error {
+	if s.trickle {
+		s.pc.setLocalCandidateHandler(s.sendCandidate())
+	}
+
+	offer, err := s.pc.createOffer()
+	if err != nil {
+		return fmt.Errorf("error while creating offer: %w", err)
+	}
+
+	if err := s.pc.GetPeerConnection().SetLocalDescription(offer); err != nil {
+		return fmt.Errorf("error while setting local sdp: %w", err)
+	}
+
+	if err := s.pc.waitForICEGathering(ctx, s.trickle); err != nil {
+		return err
+	}
+
+	if err := s.send(ctx, WebSocketMessage{
+		Type: WebSocketMessageOffer,
+		SDP:  s.pc.GetPeerConnection().LocalDescription().SDP,
+	}); err != nil {
+		return err
+	}
+
+	s.pc.logger.Info("offer sent; waiting for answer")
+
+	answer, err := s.receive(ctx, WebSocketMessageAnswer)
+	if err != nil {
+		return fmt.Errorf("failed to get answer (pc=%s); err: %w", s.pc.label, err)
+	}
+
+	return s.pc.setRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeAnswer,
+		SDP:  answer.SDP,
+	})
+}
+
+// answer runs one offer/answer exchange with this session as the answering side.
+func (s *messageSession) answer(ctx context.Context, sdp string) error {
+	if err := s.pc.setRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeOffer,
+		SDP:  sdp,
+	}); err != nil {
+		return err
+	}
+
+	if s.trickle {
+		s.pc.setLocalCandidateHandler(s.sendCandidate())
+	}
+
+	answer, err := s.pc.GetPeerConnection().CreateAnswer(nil)
+	if err != nil {
+		return fmt.Errorf("error while creating answer: %w", err)
+	}
+
+	if err := s.pc.GetPeerConnection().SetLocalDescription(answer); err != nil {
+		return fmt.Errorf("error while setting local sdp: %w", err)
+	}
+
+	if err := s.pc.waitForICEGathering(ctx, s.trickle); err != nil {
+		return err
+	}
+
+	return s.send(ctx, WebSocketMessage{
+		Type: WebSocketMessageAnswer,
+		SDP:  s.pc.GetPeerConnection().LocalDescription().SDP,
+	})
+}
+
+// connectOffer runs the first exchange of the session as the offering side, holding the negotiation lock
+// of the peer connection for it.
+func (s *messageSession) connectOffer(ctx context.Context) error {
+	if err := s.pc.lockNegotiation(ctx); err != nil {
+		return err
+	}
+	defer s.pc.unlockNegotiation()
+
+	return s.offer(ctx)
+}
+
+// connectAnswer runs the first exchange of the session as the answering side. The negotiation lock is
+// only taken once the offer arrived.
+func (s *messageSession) connectAnswer(ctx context.Context) error {
+	offer, err := s.receive(ctx, WebSocketMessageOffer)
+	if err != nil {
+		return fmt.Errorf("failed to get offer (pc=%s); err: %w", s.pc.label, err)
+	}
+
+	s.pc.logger.Info("found offer; creating answer")
+
+	if err := s.pc.lockNegotiation(ctx); err != nil {
+		return err
+	}
+	defer s.pc.unlockNegotiation()
+
+	return s.answer(ctx, offer.SDP)
+}
+
+// answerRenegotiation answers an offer the remote sent over an established session.
+func (s *messageSession) answerRenegotiation(sdp string) {
+	// NOTE: ONLY THE OFFERING SIDE RENEGOTIATES (SEE ErrRenegotiationNotOfferer), SO OFFERS DO NOT COLLIDE.
+	// NOTE: AN OFFER ARRIVING WHILE THIS SIDE STILL ANSWERS THE PREVIOUS ONE IS DROPPED.
+	if !s.pc.tryLockNegotiation() {
+		s.pc.logger.Warn("dropping remote offer as a renegotiation is already in progress")
+		return
+	}
+	defer s.pc.unlockNegotiation()
+
+	if err := s.answer(s.ctx, sdp); err != nil {
+		s.pc.logger.Error("error while answering renegotiation offer", "err", err)
+		s.pc.rollback()
+	}
+}
+
+// receive blocks until a message of the given type arrives. Messages of other types are skipped.
+func (s *messageSession) receive(ctx context.Context, msgType string) (WebSocketMessage, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return WebSocketMessage{}, ctx.Err()
+		case msg, ok := <-s.messages:
+			if !ok {
+				return msg, fmt.Errorf("signal session closed (label=%s)", s.pc.label)
+			}
+
+			if msg.Type == WebSocketMessageError {
+				return msg, errors.New(msg.Error)
+			}
+
+			if msg.Type == msgType {
+				return msg, nil
+			}
+		}
+	}
+}
+
+func (s *messageSession) close() error {
+	s.cancel()
+	return s.transport.close()
+}
+
+// messageSessions is the registry of the sessions of a signal, one per category and peer connection
+// label. kind names the signal in errors.
+type messageSessions struct {
+	kind     string
+	sessions map[string]*messageSession
+	mux      sync.Mutex
+}
+
+func newMessageSessions(kind string) *messageSessions {
+	return &messageSessions{
+		kind:     kind,
+		sessions: make(map[string]*messageSession),
+	}
+}
+
+// connect registers session, replacing and closing the previous session of its peer connection, and
+// runs its first offer/answer exchange. A session whose exchange fails is removed and closed.
+func (r *messageSessions) connect(session *messageSession, exchange func(*messageSession) error) error {
+	r.add(session)
+
+	if err := exchange(session); err != nil {
+		r.remove(session)
+		return err
+	}
+
+	session.established.Store(true)
+	return nil
+}
+
+func (r *messageSessions) add(session *messageSession) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	key := webSocketRoomKey(session.category, session.pc.label)
+	if old, exists := r.sessions[key]; exists {
+		_ = old.close()
+	}
+
+	r.sessions[key] = session
+}
+
+// remove closes session and unregisters it, if it is still the session of its peer connection.
+func (r *messageSessions) remove(session *messageSession) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	key := webSocketRoomKey(session.category, session.pc.label)
+	if r.sessions[key] == session {
+		delete(r.sessions, key)
+	}
+
+	_ = session.close()
+}
+
+// get returns the established session of the peer connection.
+func (r *messageSessions) get(category, label string) (*messageSession, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	session, exists := r.sessions[webSocketRoomKey(category, label)]
+	if !exists || !session.established.Load() {
+		return nil, fmt.Errorf("no established %s session (category=%s; pc=%s)", r.kind, category, label)
+	}
+
+	return session, nil
+}
+
+func (r *messageSessions) close() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	var merr error
+	for key, session := range r.sessions {
+		if err := session.close(); err != nil {
+			merr = multierr.Append(merr, err)
+		}
+		delete(r.sessions, key)
+	}
+
+	return merr
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+func TestCandidateSessionsEndPreviousSession(t *testing.T) {
+	connectionCtx, connectionCancel := context.WithCancel(context.Background())
+	pc := &PeerConnection{label: "pc", connectionCtx: connectionCtx, connectionCancel: connectionCancel}
+
+	var sessions candidateSessions
+	first := sessions.start(context.Background(), "category", pc)
+	second := sessions.start(context.Background(), "category", pc)
+	other := sessions.start(context.Background(), "other", pc)
+
+	if first.Err() == nil {
+		t.Fatal("expected the first session to end when the peer connection connects again")
+	}
+	if second.Err() != nil || other.Err() != nil {
+		t.Fatal("expected the current sessions to be running")
+	}
+
+	// NOTE: RECREATING THE UNDERLYING CONNECTION CANCELS ITS CONTEXT
+	connectionCancel()
+	<-second.Done()
+	<-other.Done()
+
+	third := sessions.start(context.Background(), "category", &PeerConnection{label: "pc", connectionCtx: context.Background()})
+	sessions.close()
+	if third.Err() == nil {
+		t.Fatal("expected closing the sessions to end them")
+	}
+}
+
+func TestMessageSessionsCloseFailedSession(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	client, err := NewClient(context.Background(), nil, nil, loopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	pc, err := client.CreatePeerConnection("pc", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, answer := NewLoopbackSignals(ctx)
+	defer answer.Close()
+
+	// NOTE: NO OFFER EVER ARRIVES
+	if err := answer.Connect("category", pc); err == nil {
+		t.Fatal("expected connect to fail without an offer")
+	}
+
+	answer.sessions.mux.Lock()
+	sessions := len(answer.sessions.sessions)
+	answer.sessions.mux.Unlock()
+	if sessions != 0 {
+		t.Fatalf("expected the failed session to be removed, got %d sessions", sessions)
+	}
+
+	room := answer.hub.rooms[webSocketRoomKey("category", "pc")]
+	room.mux.Lock()
+	_, registered := room.peers[WebSocketRoleAnswer]
+	room.mux.Unlock()
+	if registered {
+		t.Fatal("expected the transport of the failed session to be closed")
+	}
+}
+
+func TestGenericSignalSendsCandidatesInSession(t *testing.T) {
+	client, err := NewClient(context.Background(), nil, nil, loopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	pc, err := client.CreatePeerConnection("pc", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := make(chan context.Context, 1)
+	onCandidate := func(ctx context.Context, _ webrtc.ICECandidateInit) error {
+		sent <- ctx
+		return nil
+	}
+
+	signal := NewGenericOfferSignal(context.Background(), nil, nil, WithCandidateCallbacks(onCandidate, nil))
+	if _, err := signal.Offer(pc); err != nil {
+		t.Fatal(err)
+	}
+
+	pc.candidateMux.Lock()
+	handler := pc.candidateHandler
+	pc.candidateMux.Unlock()
+	handler(webrtc.ICECandidateInit{Candidate: "candidate"})
+
+	session := <-sent
+	if session.Err() != nil {
+		t.Fatal("expected the session of the offer to be running")
+	}
+
+	if err := signal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if session.Err() == nil {
+		t.Fatal("expected closing the signal to end the context local candidates are sent with")
+	}
+}
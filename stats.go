@@ -2,7 +2,6 @@ package client
 
 import (
 	"context"
-	"fmt"
 	"sync"
 	"time"
 )
@@ -59,7 +58,7 @@ func (g *StatsGetter) loop1(interval time.Duration) {
 
 				for _, s := range stats {
 					if err := pc.stat.Consume(s); err != nil {
-						fmt.Printf("error while gathering stats; (err: %v)\n", err)
+						pc.logger.Debug("error while gathering stats", "err", err)
 						continue
 					}
 				}
@@ -0,0 +1,46 @@
+package client
+
+import "context"
+
+// WebSocketAnswerSignal implements BaseSignal over a WebSocketSignalServer (answer side). It answers the
+// renegotiations of the remote WebSocketOfferSignal, but does not offer any itself, see
+// ErrRenegotiationNotOfferer.
+type WebSocketAnswerSignal struct {
+	url      string
+	sessions *messageSessions
+	ctx      context.Context
+	signalConfig
+}
+
+func CreateWebSocketAnswerSignal(ctx context.Context, url string, options ...SignalOption) *WebSocketAnswerSignal {
+	return &WebSocketAnswerSignal{
+		url:          url,
+		sessions:     newMessageSessions("websocket"),
+		ctx:          ctx,
+		signalConfig: newSignalConfig(options...),
+	}
+}
+
+func (signal *WebSocketAnswerSignal) Connect(category string, pc *PeerConnection) error {
+	return signal.ConnectContext(signal.ctx, category, pc)
+}
+
+// ConnectContext implements ContextSignal; ctx bounds the exchange, the session itself lives as long as
+// the signal.
+func (signal *WebSocketAnswerSignal) ConnectContext(ctx context.Context, category string, pc *PeerConnection) error {
+	ctx, cancel := exchangeContext(ctx, signal.ctx)
+	defer cancel()
+
+	session, err := dialWebSocketSession(ctx, signal.ctx, signal.url, WebSocketRoleAnswer, category, signal.trickle, pc)
+	if err != nil {
+		return err
+	}
+
+	return signal.sessions.connect(session, func(session *messageSession) error {
+		return session.connectAnswer(ctx)
+	})
+}
+
+func (signal *WebSocketAnswerSignal) Close() error {
+	return signal.sessions.close()
+}
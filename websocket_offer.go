@@ -0,0 +1,58 @@
+package client
+
+import "context"
+
+// WebSocketOfferSignal implements BaseSignal over a WebSocketSignalServer (offer side).
+// A single signal can be used for many peer connections; each gets its own websocket session.
+// It also implements RenegotiationSignal; the session stays open after Connect and carries
+// renegotiations started by either side.
+type WebSocketOfferSignal struct {
+	url      string
+	sessions *messageSessions
+	ctx      context.Context
+	signalConfig
+}
+
+func CreateWebSocketOfferSignal(ctx context.Context, url string, options ...SignalOption) *WebSocketOfferSignal {
+	return &WebSocketOfferSignal{
+		url:          url,
+		sessions:     newMessageSessions("websocket"),
+		ctx:          ctx,
+		signalConfig: newSignalConfig(options...),
+	}
+}
+
+func (signal *WebSocketOfferSignal) Connect(category string, pc *PeerConnection) error {
+	return signal.ConnectContext(signal.ctx, category, pc)
+}
+
+// ConnectContext implements ContextSignal; ctx bounds the exchange, the session itself lives as long as
+// the signal.
+func (signal *WebSocketOfferSignal) ConnectContext(ctx context.Context, category string, pc *PeerConnection) error {
+	ctx, cancel := exchangeContext(ctx, signal.ctx)
+	defer cancel()
+
+	session, err := dialWebSocketSession(ctx, signal.ctx, signal.url, WebSocketRoleOffer, category, signal.trickle, pc)
+	if err != nil {
+		return err
+	}
+
+	return signal.sessions.connect(session, func(session *messageSession) error {
+		return session.connectOffer(ctx)
+	})
+}
+
+func (signal *WebSocketOfferSignal) Renegotiate(ctx context.Context, pc *PeerConnection) error {
+	category, _ := pc.getSignal()
+
+	session, err := signal.sessions.get(category, pc.label)
+	if err != nil {
+		return err
+	}
+
+	return session.offer(ctx)
+}
+
+func (signal *WebSocketOfferSignal) Close() error {
+	return signal.sessions.close()
+}
@@ -0,0 +1,265 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	internallogger "github.com/harshabose/simple_webrtc_comm/client/internal/logger"
+)
+
+// WebSocketSignalServer is a small reference relay for WebSocketOfferSignal and WebSocketAnswerSignal.
+// It implements http.Handler, so it can be mounted on any mux or served with httptest.NewServer.
+// Messages sent before the other role has registered are queued and flushed when it does.
+type WebSocketSignalServer struct {
+	rooms  map[string]*webSocketRoom
+	logger *slog.Logger
+
+	mux    sync.Mutex
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type webSocketRoom struct {
+	peers   map[string]*webSocketPeer
+	pending map[string][]WebSocketMessage
+	mux     sync.Mutex
+}
+
+// webSocketPeer is a registered connection. Messages for it are queued under the mutex of its room and
+// written by its own goroutine, so that a slow peer does not block the room.
+type webSocketPeer struct {
+	conn   *websocket.Conn
+	queue  []WebSocketMessage
+	notify chan struct{}
+}
+
+// NewWebSocketSignalServer creates a server that logs with logger; a nil logger discards its logs.
+func NewWebSocketSignalServer(ctx context.Context, logger *slog.Logger) *WebSocketSignalServer {
+	ctx2, cancel2 := context.WithCancel(ctx)
+
+	return &WebSocketSignalServer{
+		rooms:  make(map[string]*webSocketRoom),
+		logger: internallogger.OrDiscard(logger),
+		ctx:    ctx2,
+		cancel: cancel2,
+	}
+}
+
+func (s *WebSocketSignalServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		s.logger.Warn("websocket signal server: error while accepting connection", "err", err)
+		return
+	}
+	defer func() {
+		_ = conn.CloseNow()
+	}()
+
+	var register WebSocketMessage
+	if err := wsjson.Read(s.ctx, conn, &register); err != nil {
+		return
+	}
+
+	if err := validateWebSocketRegister(register); err != nil {
+		_ = wsjson.Write(s.ctx, conn, WebSocketMessage{Type: WebSocketMessageError, Error: err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	key := webSocketRoomKey(register.Category, register.Label)
+	room, peer := s.join(key, register.Role, conn)
+	defer s.leave(key, register.Role, peer)
+
+	go room.send(ctx, register.Role, peer)
+
+	for {
+		var msg WebSocketMessage
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			return
+		}
+
+		msg.Role = register.Role
+		msg.Category = register.Category
+		msg.Label = register.Label
+
+		room.forward(otherWebSocketRole(register.Role), msg)
+	}
+}
+
+func validateWebSocketRegister(msg WebSocketMessage) error {
+	if msg.Type != WebSocketMessageRegister {
+		return fmt.Errorf("expected '%s' message but got '%s'", WebSocketMessageRegister, msg.Type)
+	}
+
+	if msg.Role != WebSocketRoleOffer && msg.Role != WebSocketRoleAnswer {
+		return fmt.Errorf("unknown role '%s'", msg.Role)
+	}
+
+	if msg.Category == "" || msg.Label == "" {
+		return errors.New("category and label are required")
+	}
+
+	return nil
+}
+
+func otherWebSocketRole(role string) string {
+	if role == WebSocketRoleOffer {
+		return WebSocketRoleAnswer
+	}
+	return WebSocketRoleOffer
+}
+
+// join registers conn for the role. s.mux is held till the peer is in the room, so that a concurrent
+// leave cannot delete the room in between.
+func (s *WebSocketSignalServer) join(key, role string, conn *websocket.Conn) (*webSocketRoom, *webSocketPeer) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	room, exists := s.rooms[key]
+	if !exists {
+		room = &webSocketRoom{
+			peers:   make(map[string]*webSocketPeer),
+			pending: make(map[string][]WebSocketMessage),
+		}
+		s.rooms[key] = room
+	}
+
+	room.mux.Lock()
+	defer room.mux.Unlock()
+
+	if old, exists := room.peers[role]; exists {
+		// NOTE: THE CLOSE HANDSHAKE CAN TAKE SECONDS; IT IS NOT WAITED FOR UNDER THE LOCKS
+		go func() {
+			_ = old.conn.Close(websocket.StatusPolicyViolation, "replaced by a newer session")
+		}()
+	}
+
+	// NOTE: ANYTHING QUEUED FROM A PREVIOUS SESSION OF THIS ROLE IS STALE NOW
+	delete(room.pending, otherWebSocketRole(role))
+
+	peer := &webSocketPeer{
+		conn:   conn,
+		queue:  room.pending[role],
+		notify: make(chan struct{}, 1),
+	}
+	delete(room.pending, role)
+	room.peers[role] = peer
+	peer.signal()
+
+	return room, peer
+}
+
+func (s *WebSocketSignalServer) leave(key, role string, peer *webSocketPeer) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	room, exists := s.rooms[key]
+	if !exists {
+		return
+	}
+
+	room.mux.Lock()
+	defer room.mux.Unlock()
+
+	room.remove(role, peer)
+
+	if len(room.peers) == 0 {
+		delete(s.rooms, key)
+	}
+}
+
+// forward queues msg for the peer of the role, or keeps it pending till that role registers.
+func (room *webSocketRoom) forward(role string, msg WebSocketMessage) {
+	room.mux.Lock()
+	defer room.mux.Unlock()
+
+	if peer, exists := room.peers[role]; exists {
+		peer.queue = append(peer.queue, msg)
+		peer.signal()
+		return
+	}
+
+	room.pending[role] = append(room.pending[role], msg)
+}
+
+// remove unregisters peer, if it is still the one of the role, keeping what was not written to it
+// pending. room.mux needs to be held.
+func (room *webSocketRoom) remove(role string, peer *webSocketPeer) {
+	if room.peers[role] != peer {
+		return
+	}
+
+	delete(room.peers, role)
+	if len(peer.queue) > 0 {
+		room.pending[role] = append(peer.queue, room.pending[role]...)
+		peer.queue = nil
+	}
+}
+
+// send writes the queue of peer till ctx is done. If a write fails, the peer is removed from the room and
+// its connection closed.
+func (room *webSocketRoom) send(ctx context.Context, role string, peer *webSocketPeer) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-peer.notify:
+		}
+
+		for {
+			// NOTE: THE MESSAGE LEAVES THE QUEUE BEFORE IT IS WRITTEN, SO THAT A REMOVE DURING THE WRITE DOES NOT
+			// KEEP IT PENDING FOR THE NEXT SESSION OF THE ROLE AS WELL
+			room.mux.Lock()
+			if len(peer.queue) == 0 {
+				room.mux.Unlock()
+				break
+			}
+			msg := peer.queue[0]
+			peer.queue = peer.queue[1:]
+			room.mux.Unlock()
+
+			if err := room.write(ctx, peer.conn, msg); err != nil {
+				room.mux.Lock()
+				peer.queue = append([]WebSocketMessage{msg}, peer.queue...)
+				room.remove(role, peer)
+				room.mux.Unlock()
+
+				_ = peer.conn.CloseNow()
+				return
+			}
+		}
+	}
+}
+
+func (room *webSocketRoom) write(ctx context.Context, conn *websocket.Conn, msg WebSocketMessage) error {
+	ctx2, cancel2 := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel2()
+
+	return wsjson.Write(ctx2, conn, msg)
+}
+
+func (peer *webSocketPeer) signal() {
+	select {
+	case peer.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *WebSocketSignalServer) Close() {
+	s.once.Do(func() {
+		if s.cancel != nil {
+			s.cancel()
+		}
+	})
+}
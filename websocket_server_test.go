@@ -0,0 +1,246 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/datachannel"
+)
+
+func newWebSocketSignalServer(t *testing.T) string {
+	t.Helper()
+
+	server := NewWebSocketSignalServer(context.Background(), nil)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		server.Close()
+		httpServer.Close()
+	})
+
+	return "ws" + strings.TrimPrefix(httpServer.URL, "http")
+}
+
+func registerWebSocket(ctx context.Context, t *testing.T, url, role string) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wsjson.Write(ctx, conn, WebSocketMessage{Type: WebSocketMessageRegister, Role: role, Category: "test", Label: "pc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	return conn
+}
+
+func TestWebSocketSignalServerConnectsPeerConnections(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	url := newWebSocketSignalServer(t)
+
+	offer, err := NewClient(ctx, nil, nil, loopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offer.Close()
+
+	answer, err := NewClient(ctx, nil, nil, loopbackSettings())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer answer.Close()
+
+	negotiated := datachannel.WithDataChannelInit(&webrtc.DataChannelInit{Negotiated: &datachannel.NegotiatedTrue, ID: &datachannel.IDOne})
+	for _, c := range []*Client{offer, answer} {
+		pc, err := c.CreatePeerConnection("pc", webrtc.Configuration{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pc.CreateDataChannel("control", negotiated); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	offerSignal := CreateWebSocketOfferSignal(ctx, url, WithTrickleICE())
+	defer offerSignal.Close()
+	answerSignal := CreateWebSocketAnswerSignal(ctx, url, WithTrickleICE())
+	defer answerSignal.Close()
+
+	connectSignals(t, offer, answer, offerSignal, answerSignal)
+
+	pc, err := offer.GetPeerConnection("pc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pc.WaitTill(ctx, func(state webrtc.PeerConnectionState, _ webrtc.ICEConnectionState) bool {
+		return state != webrtc.PeerConnectionStateConnected
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebSocketSignalServerFlushesPendingMessages(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	url := newWebSocketSignalServer(t)
+
+	offer := registerWebSocket(ctx, t, url, WebSocketRoleOffer)
+	defer offer.CloseNow()
+
+	if err := wsjson.Write(ctx, offer, WebSocketMessage{Type: WebSocketMessageOffer, SDP: "pending"}); err != nil {
+		t.Fatal(err)
+	}
+
+	answer := registerWebSocket(ctx, t, url, WebSocketRoleAnswer)
+	defer answer.CloseNow()
+
+	var msg WebSocketMessage
+	if err := wsjson.Read(ctx, answer, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != WebSocketMessageOffer || msg.SDP != "pending" || msg.Role != WebSocketRoleOffer {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+func TestWebSocketSignalServerRelaysAcrossRejoins(t *testing.T) {
+	url := newWebSocketSignalServer(t)
+
+	// NOTE: THE PEERS OF ONE ROUND LEAVE WHILE THOSE OF THE NEXT JOIN THE SAME ROOM
+	for round := 0; round < 20; round++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+
+		offer := registerWebSocket(ctx, t, url, WebSocketRoleOffer)
+		answer := registerWebSocket(ctx, t, url, WebSocketRoleAnswer)
+
+		if err := wsjson.Write(ctx, offer, WebSocketMessage{Type: WebSocketMessageOffer, SDP: "offer"}); err != nil {
+			t.Fatal(err)
+		}
+
+		var msg WebSocketMessage
+		if err := wsjson.Read(ctx, answer, &msg); err != nil {
+			t.Fatalf("round %d: answer did not receive the offer: %v", round, err)
+		}
+
+		_ = offer.CloseNow()
+		_ = answer.CloseNow()
+		cancel()
+	}
+}
+
+func TestWebSocketSignalServerKeepsJoinedPeersInTheirRoom(t *testing.T) {
+	server := NewWebSocketSignalServer(context.Background(), nil)
+	defer server.Close()
+
+	var wg sync.WaitGroup
+	for _, role := range []string{WebSocketRoleOffer, WebSocketRoleAnswer} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 10000; i++ {
+				room, peer := server.join("test/pc", role, nil)
+
+				server.mux.Lock()
+				registered := server.rooms["test/pc"] == room
+				server.mux.Unlock()
+				if !registered {
+					t.Errorf("%s joined a room that was already deleted", role)
+					return
+				}
+
+				server.leave("test/pc", role, peer)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(server.rooms) != 0 {
+		t.Fatalf("expected every room to be deleted, got %d", len(server.rooms))
+	}
+}
+
+// webSocketPair returns both ends of a websocket connection.
+func webSocketPair(ctx context.Context, t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+
+	accepted := make(chan *websocket.Conn, 1)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		accepted <- conn
+		<-ctx.Done()
+	}))
+	t.Cleanup(httpServer.Close)
+
+	client, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(httpServer.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.CloseNow()
+	})
+
+	server := <-accepted
+	t.Cleanup(func() {
+		_ = server.CloseNow()
+	})
+
+	return server, client
+}
+
+func TestWebSocketRoomDoesNotKeepMessageWrittenDuringRemove(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	server, client := webSocketPair(ctx, t)
+	client.SetReadLimit(-1)
+
+	// NOTE: THE MESSAGE OUTGROWS THE SOCKET BUFFERS, SO ITS WRITE BLOCKS TILL THE CLIENT READS
+	msg := WebSocketMessage{Type: WebSocketMessageOffer, SDP: strings.Repeat("v", 16<<20)}
+	room := &webSocketRoom{peers: make(map[string]*webSocketPeer), pending: make(map[string][]WebSocketMessage)}
+	peer := &webSocketPeer{conn: server, queue: []WebSocketMessage{msg}, notify: make(chan struct{}, 1)}
+	room.peers[WebSocketRoleAnswer] = peer
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		room.send(ctx, WebSocketRoleAnswer, peer)
+	}()
+	peer.signal()
+
+	time.Sleep(200 * time.Millisecond)
+	room.mux.Lock()
+	room.remove(WebSocketRoleAnswer, peer)
+	room.mux.Unlock()
+
+	var received WebSocketMessage
+	if err := wsjson.Read(ctx, client, &received); err != nil {
+		t.Fatal(err)
+	}
+	if len(received.SDP) != len(msg.SDP) {
+		t.Fatal("expected the client to receive the whole offer")
+	}
+
+	cancel()
+	<-sent
+
+	// NOTE: THE OFFER WAS DELIVERED; THE NEXT SESSION OF THE ROLE MUST NOT GET IT AGAIN
+	if pending := len(room.pending[WebSocketRoleAnswer]); pending != 0 {
+		t.Fatalf("expected the delivered offer not to be pending, got %d messages", pending)
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/pion/webrtc/v4"
+)
+
+const (
+	WebSocketMessageRegister  = "register"
+	WebSocketMessageOffer     = "offer"
+	WebSocketMessageAnswer    = "answer"
+	WebSocketMessageCandidate = "candidate"
+	WebSocketMessageError     = "error"
+
+	WebSocketRoleOffer  = "offer"
+	WebSocketRoleAnswer = "answer"
+)
+
+// WebSocketMessage is the JSON envelope exchanged between the websocket signals and WebSocketSignalServer.
+// Every session starts with a register message; the server pairs the offer and answer roles registered
+// under the same category and PeerConnection label and relays every later message between them.
+type WebSocketMessage struct {
+	Type      string                   `json:"type"`
+	Role      string                   `json:"role,omitempty"`
+	Category  string                   `json:"category"`
+	Label     string                   `json:"label"`
+	SDP       string                   `json:"sdp,omitempty"`
+	Candidate *webrtc.ICECandidateInit `json:"candidate,omitempty"`
+	Error     string                   `json:"error,omitempty"`
+}
+
+func webSocketRoomKey(category, label string) string {
+	return category + "/" + label
+}
+
+func dialWebSocketSession(ctx context.Context, signalCtx context.Context, url, role, category string, trickle bool, pc *PeerConnection) (*messageSession, error) {
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error while dialing websocket signal server (url=%s); err: %w", url, err)
+	}
+
+	transport := &webSocketTransport{conn: conn}
+	if err := transport.write(ctx, WebSocketMessage{Type: WebSocketMessageRegister, Role: role, Category: category, Label: pc.label}); err != nil {
+		_ = conn.CloseNow()
+		return nil, fmt.Errorf("error while registering with websocket signal server (label=%s); err: %w", pc.label, err)
+	}
+
+	return newMessageSession(signalCtx, transport, role, category, trickle, pc), nil
+}
+
+type webSocketTransport struct {
+	conn *websocket.Conn
+}
+
+func (t *webSocketTransport) write(ctx context.Context, msg WebSocketMessage) error {
+	return wsjson.Write(ctx, t.conn, msg)
+}
+
+func (t *webSocketTransport) read(ctx context.Context) (WebSocketMessage, error) {
+	var msg WebSocketMessage
+	err := wsjson.Read(ctx, t.conn, &msg)
+	return msg, err
+}
+
+func (t *webSocketTransport) close() error {
+	return t.conn.Close(websocket.StatusNormalClosure, "")
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// WHEPSignal implements BaseSignal by playing from a WHEP endpoint. It behaves like WHIPSignal except that
+// a peer connection without transceivers gets a recvonly transceiver for each of its media sinks before
+// the offer is created, as WHEP offers only receive media.
+type WHEPSignal struct {
+	httpSignal
+}
+
+func CreateWHEPSignal(ctx context.Context, endpoint, token string, options ...SignalOption) *WHEPSignal {
+	return &WHEPSignal{
+		httpSignal: newHTTPSignal(ctx, endpoint, token, options...),
+	}
+}
+
+func (signal *WHEPSignal) Connect(category string, pc *PeerConnection) error {
+	return signal.ConnectContext(signal.ctx, category, pc)
+}
+
+// ConnectContext implements ContextSignal; ctx bounds the exchange, not the session resource.
+func (signal *WHEPSignal) ConnectContext(ctx context.Context, category string, pc *PeerConnection) error {
+	if len(pc.GetPeerConnection().GetTransceivers()) == 0 {
+		for label, sink := range pc.MediaSinks() {
+			if _, err := pc.GetPeerConnection().AddTransceiverFromKind(sink.Kind(), webrtc.RTPTransceiverInit{
+				Direction: webrtc.RTPTransceiverDirectionRecvonly,
+			}); err != nil {
+				return fmt.Errorf("error while adding recvonly transceiver for sink (id=%s); err: %w", label, err)
+			}
+		}
+	}
+
+	return signal.connect(ctx, category, pc)
+}
+
+// Close deletes the session resource of every peer connection connected with this signal.
+func (signal *WHEPSignal) Close() error {
+	return signal.close()
+}
@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+)
+
+// WHIPSignal implements BaseSignal by publishing to a WHIP endpoint (RFC 9725). Every peer connection
+// connected with it gets its own session resource on the endpoint; token, if not empty, is sent as a
+// bearer token. With WithTrickleICE, local candidates are PATCHed to the session resource.
+type WHIPSignal struct {
+	httpSignal
+}
+
+func CreateWHIPSignal(ctx context.Context, endpoint, token string, options ...SignalOption) *WHIPSignal {
+	return &WHIPSignal{
+		httpSignal: newHTTPSignal(ctx, endpoint, token, options...),
+	}
+}
+
+func (signal *WHIPSignal) Connect(category string, pc *PeerConnection) error {
+	return signal.ConnectContext(signal.ctx, category, pc)
+}
+
+// ConnectContext implements ContextSignal; ctx bounds the exchange, not the session resource.
+func (signal *WHIPSignal) ConnectContext(ctx context.Context, category string, pc *PeerConnection) error {
+	return signal.connect(ctx, category, pc)
+}
+
+// Close deletes the session resource of every peer connection connected with this signal.
+func (signal *WHIPSignal) Close() error {
+	return signal.close()
+}
@@ -0,0 +1,411 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// OnHTTPPeerConnection is called by HTTPSignalHandler with the peer connection created for a new offer,
+// before the offer is applied. This is where media sources (WHEP) or media sinks (WHIP) are created; an
+// error rejects the offer, with 403 Forbidden if it wraps ErrHTTPSignalForbidden and 500 otherwise.
+type OnHTTPPeerConnection = func(pc *PeerConnection, r *http.Request) error
+
+// ErrHTTPSignalForbidden is what an OnHTTPPeerConnection wraps to reject a request it does not authorize.
+var ErrHTTPSignalForbidden = errors.New("forbidden")
+
+// HTTPSignalHandler lets a Client act as a WHIP or WHEP endpoint. A POSTed offer creates a peer connection
+// in the client, labeled with the id of its session resource, which is returned as basePath/<id>; basePath
+// is the path the endpoint is reachable at, which can differ from the request path behind
+// http.StripPrefix or a reverse proxy. PATCH adds trickled candidates to it, or restarts ICE when it
+// carries new ICE credentials, and DELETE closes it. It implements http.Handler and can be served with
+// httptest.NewServer.
+type HTTPSignalHandler struct {
+	client           *Client
+	config           webrtc.Configuration
+	basePath         string
+	token            string
+	onPeerConnection OnHTTPPeerConnection
+	sessions         map[string]*httpResource
+	mux              sync.Mutex
+}
+
+// httpResource is a session resource of HTTPSignalHandler; etag changes with every ICE restart.
+type httpResource struct {
+	pc   *PeerConnection
+	etag string
+}
+
+// httpSessionFailedTimeout is how long a session whose peer connection failed is kept, so that the
+// remote can restart ICE on it.
+const httpSessionFailedTimeout = 30 * time.Second
+
+func NewHTTPSignalHandler(client *Client, config webrtc.Configuration, basePath, token string, onPeerConnection OnHTTPPeerConnection) *HTTPSignalHandler {
+	return &HTTPSignalHandler{
+		client:           client,
+		config:           config,
+		basePath:         basePath,
+		token:            token,
+		onPeerConnection: onPeerConnection,
+		sessions:         make(map[string]*httpResource),
+	}
+}
+
+func (h *HTTPSignalHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		http.Error(w, "invalid or missing bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		h.create(w, r)
+	case http.MethodPatch:
+		h.trickle(w, r)
+	case http.MethodDelete:
+		h.delete(w, r)
+	default:
+		w.Header().Set("Allow", strings.Join([]string{http.MethodPost, http.MethodPatch, http.MethodDelete}, ", "))
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *HTTPSignalHandler) authorized(r *http.Request) bool {
+	if h.token == "" {
+		return true
+	}
+
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get(headerAuthorization)), []byte("Bearer "+h.token)) == 1
+}
+
+func (h *HTTPSignalHandler) create(w http.ResponseWriter, r *http.Request) {
+	if !hasContentType(r, ContentTypeSDP) {
+		http.Error(w, fmt.Sprintf("expected content type '%s'", ContentTypeSDP), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offer, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := newResourceID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pc, err := h.client.CreatePeerConnection(id, h.config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	answer, status, err := h.answer(pc, r, string(offer))
+	if err != nil {
+		_ = h.client.ClosePeerConnectionIfExists(id)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	etag, err := newETag()
+	if err != nil {
+		_ = h.client.ClosePeerConnectionIfExists(id)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.mux.Lock()
+	h.sessions[id] = &httpResource{pc: pc, etag: etag}
+	h.mux.Unlock()
+
+	go h.expire(id, pc)
+
+	w.Header().Set(headerContentType, ContentTypeSDP)
+	w.Header().Set(headerLocation, path.Join("/", h.basePath, id))
+	w.Header().Set(headerETag, etag)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(answer))
+}
+
+func (h *HTTPSignalHandler) answer(pc *PeerConnection, r *http.Request, offer string) (string, int, error) {
+	if h.onPeerConnection != nil {
+		if err := h.onPeerConnection(pc, r); err != nil {
+			if errors.Is(err, ErrHTTPSignalForbidden) {
+				return "", http.StatusForbidden, err
+			}
+			return "", http.StatusInternalServerError, err
+		}
+	}
+
+	if err := pc.setRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeOffer,
+		SDP:  offer,
+	}); err != nil {
+		return "", http.StatusBadRequest, err
+	}
+
+	answer, err := pc.GetPeerConnection().CreateAnswer(nil)
+	if err != nil {
+		return "", http.StatusInternalServerError, fmt.Errorf("error while creating answer: %w", err)
+	}
+
+	if err := pc.GetPeerConnection().SetLocalDescription(answer); err != nil {
+		return "", http.StatusInternalServerError, fmt.Errorf("error while setting local sdp: %w", err)
+	}
+
+	// NOTE: THE ANSWER CARRIES ALL CANDIDATES; ONLY THE CLIENT TRICKLES
+	if err := pc.waitForICEGathering(r.Context(), false); err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+
+	return pc.GetPeerConnection().LocalDescription().SDP, http.StatusCreated, nil
+}
+
+func (h *HTTPSignalHandler) trickle(w http.ResponseWriter, r *http.Request) {
+	id, resource, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+	pc := resource.pc
+
+	if !hasContentType(r, ContentTypeTrickleICEFrag) {
+		http.Error(w, fmt.Sprintf("expected content type '%s'", ContentTypeTrickleICEFrag), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	frag, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	match := r.Header.Get(headerIfMatch)
+	if h.restarts(pc, frag) {
+		// NOTE: AN ICE RESTART IS ONLY ACCEPTED WITH If-Match "*", SEE RFC 9725, SECTION 4.4.1
+		if match != "*" {
+			http.Error(w, "ice restart requires If-Match \"*\"", http.StatusPreconditionRequired)
+			return
+		}
+
+		h.restart(w, r, id, pc, frag)
+		return
+	}
+
+	if match != "" && match != "*" && match != h.etag(id) {
+		http.Error(w, "etag does not match the session", http.StatusPreconditionFailed)
+		return
+	}
+
+	for _, candidate := range unmarshalTrickleICEFrag(frag) {
+		if err := pc.addRemoteCandidate(candidate); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// restarts reports whether frag carries ICE credentials other than those of the remote description of pc.
+func (h *HTTPSignalHandler) restarts(pc *PeerConnection, frag []byte) bool {
+	ufrag, pwd := iceCredentials(frag)
+	if ufrag == "" && pwd == "" {
+		return false
+	}
+
+	remote := pc.GetPeerConnection().CurrentRemoteDescription()
+	if remote == nil {
+		return false
+	}
+
+	currentUfrag, currentPwd := iceCredentials([]byte(remote.SDP))
+	return ufrag != currentUfrag || pwd != currentPwd
+}
+
+// restart applies the new ICE credentials and candidates of frag as a restart offer and responds with the
+// ICE credentials and candidates of the answer, under a new ETag.
+func (h *HTTPSignalHandler) restart(w http.ResponseWriter, r *http.Request, id string, pc *PeerConnection, frag []byte) {
+	if err := pc.lockNegotiation(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	defer pc.unlockNegotiation()
+
+	etag, err := newETag()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	local, status, err := h.restartAnswer(r.Context(), pc, frag)
+	if err != nil {
+		// NOTE: WITHOUT ROLLING BACK, THE RESOURCE WOULD STAY IN have-remote-offer AND FAIL EVERY LATER PATCH
+		pc.rollback()
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	h.mux.Lock()
+	if resource, exists := h.sessions[id]; exists {
+		resource.etag = etag
+	}
+	h.mux.Unlock()
+
+	w.Header().Set(headerContentType, ContentTypeTrickleICEFrag)
+	w.Header().Set(headerETag, etag)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(marshalTrickleICEFrag(local, unmarshalTrickleICEFrag([]byte(local.SDP))))
+}
+
+// restartAnswer sets the restart offer of frag and returns the local answer to it. On an error, the
+// caller rolls the offer back.
+func (h *HTTPSignalHandler) restartAnswer(ctx context.Context, pc *PeerConnection, frag []byte) (*webrtc.SessionDescription, int, error) {
+	ufrag, pwd := iceCredentials(frag)
+	remote := pc.GetPeerConnection().CurrentRemoteDescription()
+
+	if err := pc.setRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeOffer,
+		SDP:  restartedDescription(remote.SDP, ufrag, pwd),
+	}); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	for _, candidate := range unmarshalTrickleICEFrag(frag) {
+		if err := pc.addRemoteCandidate(candidate); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+
+	answer, err := pc.GetPeerConnection().CreateAnswer(nil)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("error while creating answer: %w", err)
+	}
+
+	if err := pc.GetPeerConnection().SetLocalDescription(answer); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("error while setting local sdp: %w", err)
+	}
+
+	// NOTE: LIKE THE ANSWER TO THE OFFER, THE RESPONSE CARRIES ALL CANDIDATES
+	if err := pc.waitForICEGathering(ctx, false); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return pc.GetPeerConnection().LocalDescription(), http.StatusOK, nil
+}
+
+func (h *HTTPSignalHandler) delete(w http.ResponseWriter, r *http.Request) {
+	id, _, ok := h.lookup(w, r)
+	if !ok {
+		return
+	}
+
+	h.remove(id)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *HTTPSignalHandler) lookup(w http.ResponseWriter, r *http.Request) (string, *httpResource, bool) {
+	id := path.Base(r.URL.Path)
+
+	h.mux.Lock()
+	resource, exists := h.sessions[id]
+	h.mux.Unlock()
+
+	if !exists {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return "", nil, false
+	}
+
+	return id, resource, true
+}
+
+func (h *HTTPSignalHandler) etag(id string) string {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+
+	if resource, exists := h.sessions[id]; exists {
+		return resource.etag
+	}
+
+	return ""
+}
+
+func (h *HTTPSignalHandler) remove(id string) {
+	h.mux.Lock()
+	_, exists := h.sessions[id]
+	delete(h.sessions, id)
+	h.mux.Unlock()
+
+	if !exists {
+		return
+	}
+
+	if err := h.client.ClosePeerConnectionIfExists(id); err != nil {
+		h.client.logger.Error("error while closing peer connection of session", "pc", id, "err", err)
+	}
+}
+
+// expire removes the session once its peer connection gets closed, or stays failed for longer than
+// httpSessionFailedTimeout, e.g. when the remote went away without sending DELETE.
+func (h *HTTPSignalHandler) expire(id string, pc *PeerConnection) {
+	defer h.remove(id)
+
+	for {
+		if err := pc.WaitTill(pc.ctx, func(state webrtc.PeerConnectionState, _ webrtc.ICEConnectionState) bool {
+			return state != webrtc.PeerConnectionStateFailed && state != webrtc.PeerConnectionStateClosed
+		}); err != nil || pc.GetState() != webrtc.PeerConnectionStateFailed {
+			return
+		}
+
+		if !h.recovers(pc) {
+			return
+		}
+	}
+}
+
+// recovers reports whether the failed peer connection leaves the 'failed' state, by an ICE restart of the
+// remote, within httpSessionFailedTimeout.
+func (h *HTTPSignalHandler) recovers(pc *PeerConnection) bool {
+	ctx, cancel := context.WithTimeout(pc.ctx, httpSessionFailedTimeout)
+	defer cancel()
+
+	return pc.WaitTill(ctx, func(state webrtc.PeerConnectionState, _ webrtc.ICEConnectionState) bool {
+		return state == webrtc.PeerConnectionStateFailed
+	}) == nil
+}
+
+func hasContentType(r *http.Request, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get(headerContentType))
+	return err == nil && mediaType == contentType
+}
+
+func newResourceID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func newETag() (string, error) {
+	tag, err := newResourceID()
+	if err != nil {
+		return "", err
+	}
+
+	return `"` + tag + `"`, nil
+}
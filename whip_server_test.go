@@ -0,0 +1,387 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v4"
+	"github.com/pion/webrtc/v4/pkg/media"
+
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasink"
+	"github.com/harshabose/simple_webrtc_comm/client/pkg/mediasource"
+)
+
+const httpSignalToken = "secret"
+
+// newHTTPSignalEndpoint serves a handler of a new media client at /api/whip behind http.StripPrefix, as a
+// reverse proxy would, and returns the endpoint URL.
+func newHTTPSignalEndpoint(ctx context.Context, t *testing.T, onPeerConnection OnHTTPPeerConnection) (string, *HTTPSignalHandler) {
+	t.Helper()
+
+	server, err := NewClient(ctx, nil, nil, loopbackSettings(), WithDefaultMediaEngine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+
+	handler := NewHTTPSignalHandler(server, webrtc.Configuration{}, "/api/whip", httpSignalToken, onPeerConnection)
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/whip", http.StripPrefix("/api", handler))
+	mux.Handle("/api/whip/", http.StripPrefix("/api", handler))
+
+	httpServer := httptest.NewServer(mux)
+	t.Cleanup(httpServer.Close)
+
+	return httpServer.URL + "/api/whip", handler
+}
+
+func newMediaPeerConnection(ctx context.Context, t *testing.T) *PeerConnection {
+	t.Helper()
+
+	c, err := NewClient(ctx, nil, nil, loopbackSettings(), WithDefaultMediaEngine())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+
+	pc, err := c.CreatePeerConnection("media", webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pc
+}
+
+// assertHTTPMediaFlows writes samples to source till sink reads an RTP packet.
+func assertHTTPMediaFlows(ctx context.Context, t *testing.T, source *mediasource.Track, sink *mediasink.Sink) {
+	t.Helper()
+
+	ctx2, cancel2 := context.WithCancel(ctx)
+	defer cancel2()
+
+	go func() {
+		ticker := time.NewTicker(20 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx2.Done():
+				return
+			case <-ticker.C:
+				_ = source.WriteSample(media.Sample{Data: []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a}, Duration: 20 * time.Millisecond})
+			}
+		}
+	}()
+
+	if _, _, err := sink.ReadRTP(ctx2); err != nil {
+		t.Fatalf("no media reached the sink: %v", err)
+	}
+}
+
+func sessionCount(handler *HTTPSignalHandler) int {
+	handler.mux.Lock()
+	defer handler.mux.Unlock()
+
+	return len(handler.sessions)
+}
+
+// sessionID returns the id of the only session of handler.
+func sessionID(t *testing.T, handler *HTTPSignalHandler) string {
+	t.Helper()
+
+	handler.mux.Lock()
+	defer handler.mux.Unlock()
+
+	if len(handler.sessions) != 1 {
+		t.Fatalf("expected one session, got %d", len(handler.sessions))
+	}
+
+	for id := range handler.sessions {
+		return id
+	}
+
+	return ""
+}
+
+func TestWHIPPublishesToHTTPSignalHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sinks := make(chan *mediasink.Sink, 1)
+	endpoint, handler := newHTTPSignalEndpoint(ctx, t, func(pc *PeerConnection, _ *http.Request) error {
+		sink, err := pc.CreateMediaSink("video", mediasink.WithVP8Track(90000))
+		if err != nil {
+			return err
+		}
+		sinks <- sink
+		return nil
+	})
+
+	pc := newMediaPeerConnection(ctx, t)
+	source, err := pc.CreateMediaSource("video", mediasource.WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signal := CreateWHIPSignal(ctx, endpoint, httpSignalToken, WithTrickleICE())
+	if err := signal.Connect("whip", pc); err != nil {
+		t.Fatal(err)
+	}
+
+	assertHTTPMediaFlows(ctx, t, source, <-sinks)
+
+	if count := sessionCount(handler); count != 1 {
+		t.Fatalf("expected one session, got %d", count)
+	}
+
+	// NOTE: THE SESSION RESOURCE IS BELOW THE PUBLIC PATH, NOT THE ONE THE HANDLER SAW
+	if err := signal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if count := sessionCount(handler); count != 0 {
+		t.Fatalf("expected the session to be deleted, got %d", count)
+	}
+}
+
+func TestWHEPPlaysFromHTTPSignalHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sources := make(chan *mediasource.Track, 1)
+	endpoint, _ := newHTTPSignalEndpoint(ctx, t, func(pc *PeerConnection, _ *http.Request) error {
+		source, err := pc.CreateMediaSource("video", mediasource.WithVP8Track(90000))
+		if err != nil {
+			return err
+		}
+		sources <- source
+		return nil
+	})
+
+	pc := newMediaPeerConnection(ctx, t)
+	sink, err := pc.CreateMediaSink("video", mediasink.WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signal := CreateWHEPSignal(ctx, endpoint, httpSignalToken)
+	defer signal.Close()
+
+	if err := signal.Connect("whep", pc); err != nil {
+		t.Fatal(err)
+	}
+
+	assertHTTPMediaFlows(ctx, t, <-sources, sink)
+}
+
+func TestHTTPSignalHandlerRejectsInvalidToken(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	endpoint, handler := newHTTPSignalEndpoint(ctx, t, nil)
+
+	for _, token := range []string{"", "secreT", httpSignalToken + "x"} {
+		pc := newMediaPeerConnection(ctx, t)
+		if _, err := pc.CreateMediaSource("video", mediasource.WithVP8Track(90000)); err != nil {
+			t.Fatal(err)
+		}
+
+		err := CreateWHIPSignal(ctx, endpoint, token).Connect("whip", pc)
+		if err == nil || !strings.Contains(err.Error(), "401") {
+			t.Fatalf("expected token '%s' to be rejected, got %v", token, err)
+		}
+	}
+
+	if count := sessionCount(handler); count != 0 {
+		t.Fatalf("expected no session, got %d", count)
+	}
+}
+
+func TestWHIPRestartsICEOnTheSessionResource(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sinks := make(chan *mediasink.Sink, 1)
+	endpoint, handler := newHTTPSignalEndpoint(ctx, t, func(pc *PeerConnection, _ *http.Request) error {
+		sink, err := pc.CreateMediaSink("video", mediasink.WithVP8Track(90000))
+		if err != nil {
+			return err
+		}
+		sinks <- sink
+		return nil
+	})
+
+	pc := newMediaPeerConnection(ctx, t)
+	source, err := pc.CreateMediaSource("video", mediasource.WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signal := CreateWHIPSignal(ctx, endpoint, httpSignalToken, WithTrickleICE())
+	defer signal.Close()
+
+	if err := signal.Connect("whip", pc); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := <-sinks
+	assertHTTPMediaFlows(ctx, t, source, sink)
+
+	id := sessionID(t, handler)
+	ufrag, _ := iceCredentials([]byte(pc.GetPeerConnection().CurrentRemoteDescription().SDP))
+
+	// NOTE: CONNECTING THE LIVE PEER CONNECTION AGAIN RESTARTS ICE
+	if err := signal.ConnectContext(ctx, "whip", pc); err != nil {
+		t.Fatal(err)
+	}
+
+	if restarted := sessionID(t, handler); restarted != id {
+		t.Fatalf("expected the ice restart to keep session '%s', got '%s'", id, restarted)
+	}
+	if restarted, _ := iceCredentials([]byte(pc.GetPeerConnection().CurrentRemoteDescription().SDP)); restarted == ufrag {
+		t.Fatal("expected new ice credentials of the server")
+	}
+
+	assertHTTPMediaFlows(ctx, t, source, sink)
+}
+
+func TestWHIPPostsAgainAfterRecreate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sinks := make(chan *mediasink.Sink, 2)
+	endpoint, handler := newHTTPSignalEndpoint(ctx, t, func(pc *PeerConnection, _ *http.Request) error {
+		sink, err := pc.CreateMediaSink("video", mediasink.WithVP8Track(90000))
+		if err != nil {
+			return err
+		}
+		sinks <- sink
+		return nil
+	})
+
+	pc := newMediaPeerConnection(ctx, t)
+	source, err := pc.CreateMediaSource("video", mediasource.WithVP8Track(90000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signal := CreateWHIPSignal(ctx, endpoint, httpSignalToken)
+	defer signal.Close()
+
+	if err := signal.Connect("whip", pc); err != nil {
+		t.Fatal(err)
+	}
+	assertHTTPMediaFlows(ctx, t, source, <-sinks)
+
+	id := sessionID(t, handler)
+
+	if err := pc.recreate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := signal.Connect("whip", pc); err != nil {
+		t.Fatal(err)
+	}
+
+	// NOTE: THE OLD RESOURCE IS DELETED BEFORE THE NEW OFFER IS POSTED
+	if recreated := sessionID(t, handler); recreated == id {
+		t.Fatal("expected a new session resource for the recreated peer connection")
+	}
+
+	assertHTTPMediaFlows(ctx, t, source, <-sinks)
+}
+
+func TestHTTPSignalHandlerMapsPeerConnectionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status string
+	}{
+		{name: "unauthorized", err: fmt.Errorf("stream key revoked: %w", ErrHTTPSignalForbidden), status: "403"},
+		{name: "failure", err: errors.New("no free encoder"), status: "500"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			endpoint, handler := newHTTPSignalEndpoint(ctx, t, func(*PeerConnection, *http.Request) error {
+				return test.err
+			})
+
+			pc := newMediaPeerConnection(ctx, t)
+			if _, err := pc.CreateMediaSource("video", mediasource.WithVP8Track(90000)); err != nil {
+				t.Fatal(err)
+			}
+
+			err := CreateWHIPSignal(ctx, endpoint, httpSignalToken).Connect("whip", pc)
+			if err == nil || !strings.Contains(err.Error(), test.status) {
+				t.Fatalf("expected status %s, got %v", test.status, err)
+			}
+			if count := sessionCount(handler); count != 0 {
+				t.Fatalf("expected no session, got %d", count)
+			}
+		})
+	}
+}
+
+func TestHTTPSignalHandlerRollsBackFailedRestart(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	endpoint, handler := newHTTPSignalEndpoint(ctx, t, func(pc *PeerConnection, _ *http.Request) error {
+		_, err := pc.CreateMediaSink("video", mediasink.WithVP8Track(90000))
+		return err
+	})
+
+	pc := newMediaPeerConnection(ctx, t)
+	if _, err := pc.CreateMediaSource("video", mediasource.WithVP8Track(90000)); err != nil {
+		t.Fatal(err)
+	}
+
+	signal := CreateWHIPSignal(ctx, endpoint, httpSignalToken, WithTrickleICE())
+	defer signal.Close()
+
+	if err := signal.Connect("whip", pc); err != nil {
+		t.Fatal(err)
+	}
+
+	id := sessionID(t, handler)
+	frag := "a=ice-ufrag:restart\r\na=ice-pwd:restartrestartrestart00\r\na=mid:0\r\na=candidate:invalid\r\n"
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint+"/"+id, strings.NewReader(frag))
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(headerContentType, ContentTypeTrickleICEFrag)
+	request.Header.Set(headerAuthorization, "Bearer "+httpSignalToken)
+	request.Header.Set(headerIfMatch, "*")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected the invalid candidate to be rejected, got %s", response.Status)
+	}
+
+	handler.mux.Lock()
+	remote := handler.sessions[id].pc
+	handler.mux.Unlock()
+	if state := remote.GetPeerConnection().SignalingState(); state != webrtc.SignalingStateStable {
+		t.Fatalf("expected the failed restart to be rolled back, got signaling state %s", state)
+	}
+
+	// NOTE: THE NEXT RESTART WORKS ON THE ROLLED BACK RESOURCE
+	if err := signal.ConnectContext(ctx, "whip", pc); err != nil {
+		t.Fatal(err)
+	}
+}
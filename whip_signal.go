@@ -0,0 +1,541 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v4"
+
+	"github.com/harshabose/tools/pkg/multierr"
+)
+
+const (
+	ContentTypeSDP              = "application/sdp"
+	ContentTypeTrickleICEFrag   = "application/trickle-ice-sdpfrag"
+	headerLocation              = "Location"
+	headerETag                  = "ETag"
+	headerIfMatch               = "If-Match"
+	headerAuthorization         = "Authorization"
+	headerContentType           = "Content-Type"
+	sdpFragAttributeCandidate   = "a=candidate:"
+	sdpFragAttributeMid         = "a=mid:"
+	sdpFragAttributeICEUfrag    = "a=ice-ufrag:"
+	sdpFragAttributeICEPwd      = "a=ice-pwd:"
+	sdpFragAttributeMediaLine   = "m="
+	sdpAttributeEndOfCandidates = "a=end-of-candidates"
+)
+
+// httpSignal is the part shared by WHIPSignal and WHEPSignal: the offer is POSTed to the endpoint, the
+// answer comes back in the response together with a session resource in the Location header, trickled
+// candidates are PATCHed to that resource and it is DELETEd on Close. Connecting a peer connection again
+// while it has a session restarts ICE with a PATCH to the resource; only a recreated peer connection,
+// which has no remote description yet, POSTs a new offer.
+type httpSignal struct {
+	endpoint string
+	token    string
+	client   *http.Client
+	sessions map[string]*httpSession
+	mux      sync.Mutex
+	ctx      context.Context
+	signalConfig
+}
+
+func newHTTPSignal(ctx context.Context, endpoint, token string, options ...SignalOption) httpSignal {
+	return httpSignal{
+		endpoint:     endpoint,
+		token:        token,
+		client:       http.DefaultClient,
+		sessions:     make(map[string]*httpSession),
+		ctx:          ctx,
+		signalConfig: newSignalConfig(options...),
+	}
+}
+
+type httpSession struct {
+	signal   *httpSignal
+	pc       *PeerConnection
+	resource string
+	etag     string
+	pending  []webrtc.ICECandidateInit
+	// restarting queues local candidates while an ICE restart is PATCHed, as the server only knows the
+	// new ICE credentials afterwards
+	restarting bool
+	mux        sync.Mutex
+}
+
+// connect runs the exchange of pc within ctx; the session resource lives till it is replaced or the
+// signal is closed.
+func (signal *httpSignal) connect(ctx context.Context, category string, pc *PeerConnection) error {
+	ctx, cancel := exchangeContext(ctx, signal.ctx)
+	defer cancel()
+
+	if err := pc.lockNegotiation(ctx); err != nil {
+		return err
+	}
+	defer pc.unlockNegotiation()
+
+	key := category + "/" + pc.label
+
+	signal.mux.Lock()
+	old, exists := signal.sessions[key]
+	signal.mux.Unlock()
+
+	// NOTE: A RECREATED PEER CONNECTION HAS NO REMOTE DESCRIPTION; ITS DTLS TRANSPORT NEEDS A NEW RESOURCE
+	if exists && pc.GetPeerConnection().CurrentRemoteDescription() != nil {
+		return old.restart(ctx)
+	}
+
+	signal.mux.Lock()
+	delete(signal.sessions, key)
+	signal.mux.Unlock()
+
+	if exists {
+		if err := old.delete(ctx); err != nil {
+			pc.logger.Warn("error while deleting old session resource", "err", err)
+		}
+	}
+
+	session := &httpSession{signal: signal, pc: pc}
+
+	if signal.trickle {
+		pc.setLocalCandidateHandler(session.sendCandidate)
+	}
+
+	offer, err := pc.createOffer()
+	if err != nil {
+		return fmt.Errorf("error while creating offer: %w", err)
+	}
+
+	if err := pc.GetPeerConnection().SetLocalDescription(offer); err != nil {
+		return fmt.Errorf("error while setting local sdp: %w", err)
+	}
+
+	if err := pc.waitForICEGathering(ctx, signal.trickle); err != nil {
+		return err
+	}
+
+	answer, err := session.post(ctx, pc.GetPeerConnection().LocalDescription().SDP)
+	if err != nil {
+		return err
+	}
+
+	signal.mux.Lock()
+	signal.sessions[key] = session
+	signal.mux.Unlock()
+
+	if err := pc.setRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeAnswer,
+		SDP:  answer,
+	}); err != nil {
+		return err
+	}
+
+	session.flush()
+	return nil
+}
+
+// close deletes the session resources of the signal. The sessions are taken out under the lock, but
+// deleted after it is released, so that a slow server does not hold up the other sessions.
+func (signal *httpSignal) close() error {
+	signal.mux.Lock()
+	sessions := make([]*httpSession, 0, len(signal.sessions))
+	for key, session := range signal.sessions {
+		sessions = append(sessions, session)
+		delete(signal.sessions, key)
+	}
+	signal.mux.Unlock()
+
+	var merr error
+	for _, session := range sessions {
+		if err := session.delete(signal.ctx); err != nil {
+			merr = multierr.Append(merr, err)
+		}
+	}
+
+	return merr
+}
+
+func (s *httpSession) request(ctx context.Context, method, target, contentType, ifMatch string, body []byte) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		request.Header.Set(headerContentType, contentType)
+	}
+	if s.signal.token != "" {
+		request.Header.Set(headerAuthorization, "Bearer "+s.signal.token)
+	}
+	if ifMatch != "" {
+		request.Header.Set(headerIfMatch, ifMatch)
+	}
+
+	response, err := s.signal.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("error while sending %s request (url=%s); err: %w", method, target, err)
+	}
+
+	return response, nil
+}
+
+// post sends the offer and returns the answer. The session resource is resolved against the endpoint,
+// as the Location header is allowed to be relative.
+func (s *httpSession) post(ctx context.Context, offer string) (string, error) {
+	response, err := s.request(ctx, http.MethodPost, s.signal.endpoint, ContentTypeSDP, "", []byte(offer))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if response.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("unexpected response to offer (pc=%s; status=%s; body=%s)", s.pc.label, response.Status, strings.TrimSpace(string(body)))
+	}
+
+	location := response.Header.Get(headerLocation)
+	if location == "" {
+		return "", fmt.Errorf("response to offer has no session resource (pc=%s)", s.pc.label)
+	}
+
+	base, err := url.Parse(s.signal.endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	resource, err := base.Parse(location)
+	if err != nil {
+		return "", fmt.Errorf("invalid session resource '%s' (pc=%s); err: %w", location, s.pc.label, err)
+	}
+
+	s.mux.Lock()
+	s.resource = resource.String()
+	s.etag = response.Header.Get(headerETag)
+	s.mux.Unlock()
+
+	s.pc.logger.Info("answer received over http", "resource", s.resource)
+
+	return string(body), nil
+}
+
+// sendCandidate is the local candidate handler in trickle mode. Candidates gathered before the session
+// resource is known are queued and sent by flush.
+func (s *httpSession) sendCandidate(candidate webrtc.ICECandidateInit) {
+	s.mux.Lock()
+	if s.resource == "" || s.restarting {
+		s.pending = append(s.pending, candidate)
+		s.mux.Unlock()
+		return
+	}
+	s.mux.Unlock()
+
+	if err := s.patch(s.signal.ctx, []webrtc.ICECandidateInit{candidate}); err != nil {
+		s.pc.logger.Warn("error while sending local candidate", "err", err)
+	}
+}
+
+func (s *httpSession) flush() {
+	s.mux.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mux.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	if err := s.patch(s.signal.ctx, pending); err != nil {
+		s.pc.logger.Warn("error while sending local candidates", "err", err)
+	}
+}
+
+// patch sends candidates as a trickle-ice-sdpfrag. Candidates in the response body, if any, are
+// applied as remote candidates.
+func (s *httpSession) patch(ctx context.Context, candidates []webrtc.ICECandidateInit) error {
+	s.mux.Lock()
+	resource := s.resource
+	etag := s.etag
+	s.mux.Unlock()
+
+	frag := marshalTrickleICEFrag(s.pc.GetPeerConnection().LocalDescription(), candidates)
+
+	response, err := s.request(ctx, http.MethodPatch, resource, ContentTypeTrickleICEFrag, etag, frag)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected response to candidates (pc=%s; status=%s; body=%s)", s.pc.label, response.Status, strings.TrimSpace(string(body)))
+	}
+
+	var merr error
+	for _, candidate := range unmarshalTrickleICEFrag(body) {
+		if err := s.pc.addRemoteCandidate(candidate); err != nil {
+			merr = multierr.Append(merr, err)
+		}
+	}
+
+	return merr
+}
+
+// restart restarts ICE on the session resource (RFC 9725, section 4.4.1): the ICE credentials of a new
+// offer are PATCHed with If-Match "*" and the server responds with its new credentials and candidates,
+// which make up the answer together with the previous remote description. The caller holds the
+// negotiation lock of the peer connection.
+func (s *httpSession) restart(ctx context.Context) error {
+	s.mux.Lock()
+	s.restarting = true
+	resource := s.resource
+	s.mux.Unlock()
+
+	defer func() {
+		s.mux.Lock()
+		s.restarting = false
+		s.mux.Unlock()
+
+		s.flush()
+	}()
+
+	s.pc.iceRestart.Store(true)
+	offer, err := s.pc.createOffer()
+	if err != nil {
+		return fmt.Errorf("error while creating ice restart offer: %w", err)
+	}
+
+	if err := s.pc.GetPeerConnection().SetLocalDescription(offer); err != nil {
+		return fmt.Errorf("error while setting local sdp: %w", err)
+	}
+
+	if err := s.exchangeRestart(ctx, resource); err != nil {
+		s.pc.rollback()
+		return err
+	}
+
+	s.pc.logger.Info("ice restarted over http", "resource", resource)
+	return nil
+}
+
+func (s *httpSession) exchangeRestart(ctx context.Context, resource string) error {
+	if err := s.pc.waitForICEGathering(ctx, s.signal.trickle); err != nil {
+		return err
+	}
+
+	local := s.pc.GetPeerConnection().LocalDescription()
+	frag := marshalTrickleICEFrag(local, unmarshalTrickleICEFrag([]byte(local.SDP)))
+
+	response, err := s.request(ctx, http.MethodPatch, resource, ContentTypeTrickleICEFrag, "*", frag)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response to ice restart (pc=%s; status=%s; body=%s)", s.pc.label, response.Status, strings.TrimSpace(string(body)))
+	}
+
+	ufrag, pwd := iceCredentials(body)
+	if ufrag == "" || pwd == "" {
+		return fmt.Errorf("response to ice restart has no ice credentials (pc=%s)", s.pc.label)
+	}
+
+	remote := s.pc.GetPeerConnection().CurrentRemoteDescription()
+	if err := s.pc.setRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeAnswer,
+		SDP:  restartedDescription(remote.SDP, ufrag, pwd),
+	}); err != nil {
+		return err
+	}
+
+	s.mux.Lock()
+	if etag := response.Header.Get(headerETag); etag != "" {
+		s.etag = etag
+	}
+	s.mux.Unlock()
+
+	var merr error
+	for _, candidate := range unmarshalTrickleICEFrag(body) {
+		if err := s.pc.addRemoteCandidate(candidate); err != nil {
+			merr = multierr.Append(merr, err)
+		}
+	}
+
+	return merr
+}
+
+func (s *httpSession) delete(ctx context.Context) error {
+	s.mux.Lock()
+	resource := s.resource
+	s.mux.Unlock()
+
+	if resource == "" {
+		return nil
+	}
+
+	response, err := s.request(ctx, http.MethodDelete, resource, "", "", nil)
+	if err != nil {
+		return err
+	}
+	_ = response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected response to session delete (pc=%s; status=%s)", s.pc.label, response.Status)
+	}
+
+	return nil
+}
+
+// marshalTrickleICEFrag builds a trickle-ice-sdpfrag (RFC 8840) carrying the given candidates. The ICE
+// credentials and media lines are taken from the local description.
+func marshalTrickleICEFrag(local *webrtc.SessionDescription, candidates []webrtc.ICECandidateInit) []byte {
+	var (
+		ufrag, pwd string
+		mediaLines = make(map[string]string)
+		mediaLine  string
+		order      []string
+	)
+
+	if local != nil {
+		for _, line := range strings.Split(local.SDP, "\n") {
+			line = strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(line, sdpFragAttributeICEUfrag) && ufrag == "":
+				ufrag = strings.TrimPrefix(line, sdpFragAttributeICEUfrag)
+			case strings.HasPrefix(line, sdpFragAttributeICEPwd) && pwd == "":
+				pwd = strings.TrimPrefix(line, sdpFragAttributeICEPwd)
+			case strings.HasPrefix(line, sdpFragAttributeMediaLine):
+				mediaLine = line
+			case strings.HasPrefix(line, sdpFragAttributeMid):
+				mediaLines[strings.TrimPrefix(line, sdpFragAttributeMid)] = mediaLine
+			}
+		}
+	}
+
+	grouped := make(map[string][]string)
+	for _, candidate := range candidates {
+		mid := ""
+		if candidate.SDPMid != nil {
+			mid = *candidate.SDPMid
+		}
+		if _, exists := grouped[mid]; !exists {
+			order = append(order, mid)
+		}
+		grouped[mid] = append(grouped[mid], "a="+strings.TrimPrefix(candidate.Candidate, "a="))
+	}
+
+	var buf bytes.Buffer
+	if ufrag != "" {
+		buf.WriteString(sdpFragAttributeICEUfrag + ufrag + "\r\n")
+	}
+	if pwd != "" {
+		buf.WriteString(sdpFragAttributeICEPwd + pwd + "\r\n")
+	}
+
+	for _, mid := range order {
+		line, exists := mediaLines[mid]
+		if !exists {
+			line = "m=application 9 UDP/DTLS/SCTP webrtc-datachannel"
+		}
+		buf.WriteString(line + "\r\n")
+		buf.WriteString(sdpFragAttributeMid + mid + "\r\n")
+		for _, candidate := range grouped[mid] {
+			buf.WriteString(candidate + "\r\n")
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// iceCredentials returns the first ICE username fragment and password of a trickle-ice-sdpfrag or SDP.
+func iceCredentials(frag []byte) (ufrag, pwd string) {
+	for _, line := range strings.Split(string(frag), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, sdpFragAttributeICEUfrag) && ufrag == "":
+			ufrag = strings.TrimPrefix(line, sdpFragAttributeICEUfrag)
+		case strings.HasPrefix(line, sdpFragAttributeICEPwd) && pwd == "":
+			pwd = strings.TrimPrefix(line, sdpFragAttributeICEPwd)
+		}
+	}
+
+	return ufrag, pwd
+}
+
+// restartedDescription returns sdp with the given ICE credentials and without candidates, which is what
+// the description of the remote looks like after an ICE restart done over a trickle-ice-sdpfrag; the
+// candidates of the remote are added separately.
+func restartedDescription(sdp, ufrag, pwd string) string {
+	var lines []string
+	for _, line := range strings.Split(sdp, "\r\n") {
+		switch {
+		case strings.HasPrefix(line, sdpFragAttributeICEUfrag):
+			line = sdpFragAttributeICEUfrag + ufrag
+		case strings.HasPrefix(line, sdpFragAttributeICEPwd):
+			line = sdpFragAttributeICEPwd + pwd
+		case strings.HasPrefix(line, sdpFragAttributeCandidate), line == sdpAttributeEndOfCandidates:
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\r\n")
+}
+
+// unmarshalTrickleICEFrag returns the candidates of a trickle-ice-sdpfrag, each tagged with the mid and
+// username fragment it was listed under.
+func unmarshalTrickleICEFrag(frag []byte) []webrtc.ICECandidateInit {
+	var (
+		candidates []webrtc.ICECandidateInit
+		ufrag      string
+		mid        *string
+	)
+
+	for _, line := range strings.Split(string(frag), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, sdpFragAttributeICEUfrag):
+			ufrag = strings.TrimPrefix(line, sdpFragAttributeICEUfrag)
+		case strings.HasPrefix(line, sdpFragAttributeMid):
+			value := strings.TrimPrefix(line, sdpFragAttributeMid)
+			mid = &value
+		case strings.HasPrefix(line, sdpFragAttributeCandidate):
+			candidate := webrtc.ICECandidateInit{
+				Candidate: strings.TrimPrefix(line, "a="),
+				SDPMid:    mid,
+			}
+			if ufrag != "" {
+				value := ufrag
+				candidate.UsernameFragment = &value
+			}
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates
+}